@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"theskyinflames/graphql-challenge/cmd/service"
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/api"
 	"theskyinflames/graphql-challenge/internal/infra/persistence"
 	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
 )
@@ -30,5 +35,189 @@ func main() {
 	}
 	fmt.Printf("db migration run finished\n")
 
-	service.Run(context.Background(), srvPort, postgresql.NewProductsRepository(db))
+	pr, err := productsRepository(ctx, db, os.Getenv("PRODUCTS_STORE"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	reservationTTL, err := durationFromEnv("RESERVATION_TTL", app.DefaultReservationTTL)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	policy, err := authorizationPolicy(os.Getenv("AUTHZ_POLICY_FILE"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	keys, err := service.LoadKeySet(os.Getenv("AUTH_KEYS_FILE"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	limiter, err := rateLimiter(db, os.Getenv("RATE_LIMIT_STORE"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	limits, err := rateLimits()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	queryLimits, err := queryLimits()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	service.Run(
+		context.Background(),
+		srvPort,
+		pr,
+		postgresql.NewOrdersRepository(db),
+		postgresql.NewAPIKeysRepository(db),
+		postgresql.NewTransactor(db),
+		postgresql.NewIdempotencyStore(db),
+		postgresql.NewOutbox(db),
+		reservationTTL,
+		policy,
+		keys,
+		os.Getenv("AUTH_ISSUER"),
+		os.Getenv("AUTH_AUDIENCE"),
+		limiter,
+		limits,
+		queryLimits,
+	)
+}
+
+// durationFromEnv returns the duration set in the env var, like "15m", or the default one when it's not set
+func durationFromEnv(name string, defaultDuration time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultDuration, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: it must be a positive duration like 15m", name, value)
+	}
+	return d, nil
+}
+
+// intFromEnv returns the positive number set in the env var, or the default one when it's not set
+func intFromEnv(name string, defaultInt int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultInt, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: it must be a positive number", name, value)
+	}
+	return n, nil
+}
+
+// queryLimits returns the limits of the GraphQL queries set in the GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_FIELDS,
+// GRAPHQL_MAX_ALIASES and GRAPHQL_MAX_COST env vars, or the default ones when they're not set
+func queryLimits() (api.QueryLimits, error) {
+	limits := api.DefaultQueryLimits()
+	for _, l := range []struct {
+		env   string
+		limit *int
+	}{
+		{env: "GRAPHQL_MAX_DEPTH", limit: &limits.MaxDepth},
+		{env: "GRAPHQL_MAX_FIELDS", limit: &limits.MaxFields},
+		{env: "GRAPHQL_MAX_ALIASES", limit: &limits.MaxAliases},
+		{env: "GRAPHQL_MAX_COST", limit: &limits.MaxCost},
+	} {
+		n, err := intFromEnv(l.env, *l.limit)
+		if err != nil {
+			return api.QueryLimits{}, err
+		}
+		*l.limit = n
+	}
+	return limits, nil
+}
+
+// authorizationPolicy returns the policy read from the JSON file, or the default one when no file is set
+func authorizationPolicy(path string) (app.Policy, error) {
+	if path == "" {
+		return app.DefaultPolicy(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read authorization policy: %w", err)
+	}
+	return app.ParsePolicy(data)
+}
+
+// productsRepository returns the products repository selected by config:
+//   - state: the products are stored with their current state. It's the default one
+//   - events: the products are stored as a history of domain events. The histories of the products
+//     stored without their events go on from a snapshot of their current state
+func productsRepository(ctx context.Context, db *sql.DB, store string) (app.ProductsRepository, error) {
+	switch store {
+	case "", "state":
+		return postgresql.NewProductsRepository(db), nil
+	case "events":
+		pr := postgresql.NewEventSourcedProductsRepository(db)
+		synced, err := pr.SyncHistories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("%d product histories synced\n", synced)
+		return pr, nil
+	default:
+		return nil, fmt.Errorf("unknown products store %q", store)
+	}
+}
+
+// Default budgets of each client for the GraphQL operations
+const (
+	defaultQueriesRateLimit   = "600/1m"
+	defaultMutationsRateLimit = "60/1m"
+)
+
+// rateLimits returns the budgets of each client set in the RATE_LIMIT_QUERIES and RATE_LIMIT_MUTATIONS env vars,
+// like "60/1m", or the default ones when they're not set
+func rateLimits() (api.RateLimits, error) {
+	var limits api.RateLimits
+	for _, l := range []struct {
+		env, defaultLimit string
+		limit             *app.RateLimit
+	}{
+		{env: "RATE_LIMIT_QUERIES", defaultLimit: defaultQueriesRateLimit, limit: &limits.Queries},
+		{env: "RATE_LIMIT_MUTATIONS", defaultLimit: defaultMutationsRateLimit, limit: &limits.Mutations},
+	} {
+		value := os.Getenv(l.env)
+		if value == "" {
+			value = l.defaultLimit
+		}
+		limit, err := app.ParseRateLimit(value)
+		if err != nil {
+			return api.RateLimits{}, fmt.Errorf("%s: %w", l.env, err)
+		}
+		*l.limit = limit
+	}
+	return limits, nil
+}
+
+// rateLimiter returns the rate limiter selected by config:
+//   - memory: the buckets are kept by each replica of the service. It's the default one
+//   - postgres: the buckets are shared by all the replicas of the service
+func rateLimiter(db *sql.DB, store string) (app.RateLimiter, error) {
+	switch store {
+	case "", "memory":
+		return app.NewMemoryRateLimiter(), nil
+	case "postgres":
+		return postgresql.NewRateLimiter(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", store)
+	}
 }
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// Signing algorithms of the bearer tokens
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+const (
+	// minHMACKeySize is the min size in bytes of the HS256 keys. It's the size of the hash, as RFC 7518 requires
+	minHMACKeySize = 32
+	// minRSAKeySize is the min size in bits of the RS256 keys, as RFC 7518 requires
+	minRSAKeySize = 2048
+	// clockSkew is the leeway given to the time claims of the tokens
+	clockSkew = 30 * time.Second
+)
+
+// ErrInvalidKeySet is self-described
+var ErrInvalidKeySet = errors.New("invalid key set")
+
+// KeySet is the set of keys the bearer tokens are verified with, by their key ID.
+// It's loaded from a local JSON Web Key Set (RFC 7517) file, so the tokens are verified without calling the identity provider.
+type KeySet struct {
+	keys map[string]verificationKey
+}
+
+type verificationKey struct {
+	alg string
+	// key is a []byte for HS256 and a *rsa.PublicKey for RS256
+	key interface{}
+}
+
+// jwk is a JSON Web Key. Only the symmetric (oct) and RSA keys are supported
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadKeySet reads the key set from a JSON Web Key Set file. An empty path returns an empty key set,
+// so all the bearer tokens are rejected.
+func LoadKeySet(path string) (KeySet, error) {
+	if path == "" {
+		return KeySet{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("read key set: %w", err)
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet parses a JSON Web Key Set. Each key must have a unique ID
+func ParseKeySet(data []byte) (KeySet, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return KeySet{}, fmt.Errorf("%w: %s", ErrInvalidKeySet, err.Error())
+	}
+
+	ks := KeySet{keys: make(map[string]verificationKey, len(set.Keys))}
+	for _, k := range set.Keys {
+		if _, ok := ks.keys[k.Kid]; ok {
+			return KeySet{}, fmt.Errorf("%w: key ID %q is repeated", ErrInvalidKeySet, k.Kid)
+		}
+		vk, err := k.verificationKey()
+		if err != nil {
+			return KeySet{}, fmt.Errorf("%w: key %q: %s", ErrInvalidKeySet, k.Kid, err.Error())
+		}
+		ks.keys[k.Kid] = vk
+	}
+	return ks, nil
+}
+
+func (k jwk) verificationKey() (verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		if k.Alg != "" && k.Alg != algHS256 {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %q for a symmetric key", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("decode k: %w", err)
+		}
+		if len(secret) < minHMACKeySize {
+			return verificationKey{}, fmt.Errorf("it's shorter than %d bytes", minHMACKeySize)
+		}
+		return verificationKey{alg: algHS256, key: secret}, nil
+	case "RSA":
+		if k.Alg != "" && k.Alg != algRS256 {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %q for an RSA key", k.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("decode e: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return verificationKey{}, errors.New("invalid exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if key.N.BitLen() < minRSAKeySize {
+			return verificationKey{}, fmt.Errorf("it's shorter than %d bits", minRSAKeySize)
+		}
+		return verificationKey{alg: algRS256, key: key}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// ErrInvalidToken is self-described
+var ErrInvalidToken = errors.New("invalid token")
+
+// claims are the claims of the bearer tokens. The subject is the ID of the principal
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// APIKeyAuthenticator resolves an API key to the principal of its client
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (app.Principal, error)
+}
+
+// apiKeyHeader is the header the machine clients send their API key in
+const apiKeyHeader = "X-API-Key"
+
+// Authenticator authenticates the requests with a JWT bearer token, or with an API key for the machine clients
+type Authenticator struct {
+	log     cqrs.Logger
+	keys    KeySet
+	apiKeys APIKeyAuthenticator
+	parser  *jwt.Parser
+}
+
+// NewAuthenticator is a constructor. When the issuer or the audience are not empty, the tokens must have them.
+func NewAuthenticator(log cqrs.Logger, keys KeySet, apiKeys APIKeyAuthenticator, issuer, audience string) Authenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algHS256, algRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	return Authenticator{log: log, keys: keys, apiKeys: apiKeys, parser: jwt.NewParser(opts...)}
+}
+
+// Authenticate verifies the token and returns the principal it's been issued for
+func (a Authenticator) Authenticate(token string) (app.Principal, error) {
+	var c claims
+	if _, err := a.parser.ParseWithClaims(token, &c, a.verificationKey); err != nil {
+		return app.Principal{}, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+	if c.Subject == "" {
+		return app.Principal{}, fmt.Errorf("%w: it has no subject", ErrInvalidToken)
+	}
+	return app.CallerPrincipal(c.Subject, c.Roles), nil
+}
+
+// verificationKey returns the key the token is verified with. It's the one with the key ID of the token,
+// or the only key of the set when the token has no key ID. The key must be of the algorithm the token is signed with,
+// so an RSA public key can't be used as an HMAC secret.
+func (a Authenticator) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys.keys) == 1 {
+		for id := range a.keys.keys {
+			kid = id
+		}
+	}
+	k, ok := a.keys.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if k.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q is not a %s key", kid, token.Method.Alg())
+	}
+	return k.key, nil
+}
+
+// Middleware puts the principal of the bearer token or the API key of the request in its context.
+// The requests without credentials go on as anonymous, and the ones with invalid credentials are rejected.
+func (a Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, key := r.Header.Get("Authorization"), r.Header.Get(apiKeyHeader)
+		switch {
+		case header == "" && key == "":
+			next.ServeHTTP(w, r)
+		case header != "" && key != "":
+			writeError(w, http.StatusUnauthorized, api.UnauthenticatedErrorCode, "only one of the Authorization and X-API-Key headers can be sent")
+		case key != "":
+			a.serveWithAPIKey(w, r, key, next)
+		default:
+			a.serveWithBearerToken(w, r, header, next)
+		}
+	})
+}
+
+func (a Authenticator) serveWithBearerToken(w http.ResponseWriter, r *http.Request, header string, next http.Handler) {
+	scheme, token, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		invalidToken(w, "the Authorization header is not a bearer token")
+		return
+	}
+
+	principal, err := a.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		a.log.Printf("authentication failed: %s\n", err.Error())
+		invalidToken(w, ErrInvalidToken.Error())
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(app.ContextWithPrincipal(r.Context(), principal)))
+}
+
+func (a Authenticator) serveWithAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	principal, err := a.apiKeys.Authenticate(r.Context(), key)
+	if err != nil {
+		a.log.Printf("authentication failed: %s\n", err.Error())
+		if errors.Is(err, app.ErrInvalidAPIKey) {
+			writeError(w, http.StatusUnauthorized, api.UnauthenticatedErrorCode, app.ErrInvalidAPIKey.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "internal error")
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(app.ContextWithPrincipal(r.Context(), principal)))
+}
+
+// invalidToken rejects a request with an invalid bearer token
+func invalidToken(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeError(w, http.StatusUnauthorized, api.UnauthenticatedErrorCode, msg)
+}
+
+// writeError answers the request with a GraphQL error, so the GraphQL clients can handle it as the rest of errors
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{
+			{"message": msg, "extensions": map[string]interface{}{"code": code}},
+		},
+	})
+}
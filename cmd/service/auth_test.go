@@ -0,0 +1,270 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/cmd/service"
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type loggerMock struct{}
+
+func (loggerMock) Printf(string, ...interface{}) {}
+
+type apiKeysMock struct{}
+
+func (apiKeysMock) Authenticate(_ context.Context, key string) (app.Principal, error) {
+	switch key {
+	case "valid":
+		return app.Principal{ID: "apikey:erp", Roles: []string{app.RoleMerchandiser}}, nil
+	case "broken":
+		return app.Principal{}, errors.New("")
+	default:
+		return app.Principal{}, app.ErrInvalidAPIKey
+	}
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	secret := []byte("a-secret-of-at-least-thirty-two-bytes")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keySet, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "hmac", "kty": "oct", "alg": "HS256", "k": base64.RawURLEncoding.EncodeToString(secret)},
+			{
+				"kid": "rsa",
+				"kty": "RSA",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+		},
+	})
+	require.NoError(t, err)
+	keys, err := service.ParseKeySet(keySet)
+	require.NoError(t, err)
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "buyer",
+			"roles": []string{"buyer"},
+			"iss":   "issuer",
+			"aud":   "graphql-challenge",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	sign := func(method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		c := validClaims()
+		if value == nil {
+			delete(c, name)
+			return c
+		}
+		c[name] = value
+		return c
+	}
+	rsaModulus := rsaKey.N.Bytes()
+
+	testCases := []struct {
+		name              string
+		authorization     string
+		apiKey            string
+		expectedStatus    int
+		expectedPrincipal *app.Principal
+	}{
+		{
+			name:           `Given a request without Authorization header, when it's served, then it goes on as anonymous`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              `Given a valid HS256 token, when it's served, then the principal is in the request context`,
+			authorization:     "Bearer " + sign(jwt.SigningMethodHS256, "hmac", validClaims(), secret),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: &app.Principal{ID: "buyer", Roles: []string{"buyer"}},
+		},
+		{
+			name:              `Given a valid RS256 token, when it's served, then the principal is in the request context`,
+			authorization:     "Bearer " + sign(jwt.SigningMethodRS256, "rsa", validClaims(), rsaKey),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: &app.Principal{ID: "buyer", Roles: []string{"buyer"}},
+		},
+		{
+			name: `Given a valid token that claims the system role, when it's served, then the principal has the other roles only`,
+			authorization: "Bearer " + sign(jwt.SigningMethodHS256, "hmac", func() jwt.MapClaims {
+				claims := validClaims()
+				claims["roles"] = []string{app.RoleSystem, "buyer"}
+				return claims
+			}(), secret),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: &app.Principal{ID: "buyer", Roles: []string{"buyer"}},
+		},
+		{
+			name:           `Given a non bearer Authorization header, when it's served, then it's unauthorized`,
+			authorization:  "Basic YnV5ZXI6cGFzcw==",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given an expired token, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", withClaim("exp", time.Now().Add(-time.Hour).Unix()), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token without expiration, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", withClaim("exp", nil), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token without subject, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", withClaim("sub", nil), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token of another issuer, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", withClaim("iss", "another"), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token for another audience, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", withClaim("aud", "another"), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token signed with another key, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", validClaims(), []byte("another-secret-of-at-least-thirty-two-bytes")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a token of an unknown key, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "unknown", validClaims(), secret),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given a HS256 token signed with the RSA public key, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "rsa", validClaims(), rsaModulus),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:              `Given a valid API key, when it's served, then the principal of its client is in the request context`,
+			apiKey:            "valid",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: &app.Principal{ID: "apikey:erp", Roles: []string{app.RoleMerchandiser}},
+		},
+		{
+			name:           `Given an invalid API key, when it's served, then it's unauthorized`,
+			apiKey:         "invalid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given an API key that can't be checked, when it's served, then it fails`,
+			apiKey:         "broken",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           `Given a bearer token and an API key, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodHS256, "hmac", validClaims(), secret),
+			apiKey:         "valid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           `Given an unsigned token, when it's served, then it's unauthorized`,
+			authorization:  "Bearer " + sign(jwt.SigningMethodNone, "hmac", validClaims(), jwt.UnsafeAllowNoneSignatureType),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				principal app.Principal
+				ok        bool
+			)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok = app.PrincipalFromContext(r.Context())
+			})
+			mw := service.NewAuthenticator(loggerMock{}, keys, apiKeysMock{}, "issuer", "graphql-challenge").Middleware(next)
+
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.apiKey != "" {
+				req.Header.Set("X-API-Key", tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus == http.StatusUnauthorized && tc.apiKey == "" {
+				require.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+			}
+			require.Equal(t, tc.expectedPrincipal != nil, ok)
+			if tc.expectedPrincipal != nil {
+				require.Equal(t, *tc.expectedPrincipal, principal)
+			}
+		})
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	testCases := []struct {
+		name        string
+		keySet      string
+		expectedErr error
+	}{
+		{
+			name:        `Given a malformed key set, when it's parsed, then an error is returned`,
+			keySet:      `{"keys":`,
+			expectedErr: service.ErrInvalidKeySet,
+		},
+		{
+			name:        `Given a HS256 key shorter than 32 bytes, when it's parsed, then an error is returned`,
+			keySet:      `{"keys":[{"kid":"hmac","kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString([]byte("short")) + `"}]}`,
+			expectedErr: service.ErrInvalidKeySet,
+		},
+		{
+			name:        `Given an unsupported key type, when it's parsed, then an error is returned`,
+			keySet:      `{"keys":[{"kid":"ec","kty":"EC"}]}`,
+			expectedErr: service.ErrInvalidKeySet,
+		},
+		{
+			name: `Given a repeated key ID, when it's parsed, then an error is returned`,
+			keySet: `{"keys":[` +
+				`{"kid":"hmac","kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"},` +
+				`{"kid":"hmac","kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`,
+			expectedErr: service.ErrInvalidKeySet,
+		},
+		{
+			name:   `Given a valid key set, when it's parsed, then no error is returned`,
+			keySet: `{"keys":[{"kid":"hmac","kty":"oct","alg":"HS256","k":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.ParseKeySet([]byte(tc.keySet))
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
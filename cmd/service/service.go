@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/infra/api"
@@ -17,7 +18,25 @@ import (
 )
 
 // Run Starts the API server
-func Run(ctx context.Context, srvPort string, pr app.ProductsRepository) {
+func Run(
+	ctx context.Context,
+	srvPort string,
+	pr app.ProductsRepository,
+	or app.OrdersRepository,
+	kr app.APIKeysRepository,
+	tx app.Transactor,
+	is app.IdempotencyStore,
+	ob app.Outbox,
+	reservationTTL time.Duration,
+	policy app.Policy,
+	keys KeySet,
+	tokenIssuer, tokenAudience string,
+	limiter app.RateLimiter,
+	limits api.RateLimits,
+	queryLimits api.QueryLimits,
+) {
+	log := log.New(os.Stdout, "graphql-challenge: ", os.O_APPEND)
+
 	r := chi.NewRouter()
 
 	cors := cors.New(cors.Options{
@@ -27,15 +46,26 @@ func Run(ctx context.Context, srvPort string, pr app.ProductsRepository) {
 	})
 	r.Use(cors.Handler)
 	r.Use(middleware.Logger)
+	r.Use(NewAuthenticator(log, keys, app.NewAPIKeyAuthenticator(log, kr), tokenIssuer, tokenAudience).Middleware)
 
 	r.Get("/status", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	log := log.New(os.Stdout, "graphql-challenge: ", os.O_APPEND)
+	// The events stored in the outbox by the commands are published by the relay, which runs in every replica,
+	// so the GraphQL subscriptions of each replica receive all of them through its hub.
+	// The hub reads the outbox to resume the event streams
+	hub := app.NewOutboxEventsHub(log, ob)
+	go app.NewOutboxRelay(log, ob, tx, app.BuildEventsBus(hub)).Run(ctx)
+
+	bus := app.BuildCommandQueryBus(log, pr, or, kr, tx, is, ob, reservationTTL, policy)
+	go app.NewReservationsSweeper(log, pr, bus).Run(ctx)
 
-	bus := app.BuildCommandQueryBus(log, app.BuildEventsBus(), pr)
-	r.Post("/graphql", api.GraphqlHandler(log, bus))
+	// The operations are rate limited whether they're sent over HTTP or over WebSocket
+	rateLimiter := api.NewOperationRateLimiter(log, limiter, limits)
+	r.Post("/graphql", api.GraphqlHandler(log, bus, hub, queryLimits, rateLimiter))
+	r.Get("/graphql", api.SubscriptionsHandler(log, bus, hub, queryLimits, rateLimiter))
+	r.Get("/events", api.EventsStreamHandler(log, hub))
 
 	fmt.Printf("serving at port %s\n", srvPort)
 	if err := http.ListenAndServe(srvPort, r); err != nil {
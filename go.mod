@@ -4,8 +4,10 @@ go 1.19
 
 require (
 	github.com/go-chi/chi v1.5.4
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/golang-migrate/migrate/v4 v4.15.2
 	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/graphql-go/graphql v0.8.0
 	github.com/lib/pq v1.10.0
 	github.com/ory/dockertest/v3 v3.9.1
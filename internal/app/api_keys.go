@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// apiKeyPrefix is the prefix of the API keys. It makes them easy to find by the secret scanners
+const apiKeyPrefix = "gqc_"
+
+// APIKeyPrincipalPrefix is the prefix of the IDs of the principals authenticated with an API key
+const APIKeyPrincipalPrefix = "apikey:"
+
+// APIKeyScopes are the roles that can be granted to an API key
+var APIKeyScopes = []string{RoleBuyer, RoleMerchandiser, RoleAdmin}
+
+// ErrInvalidAPIKey is self-described
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// NewAPIKeySecret returns a random key to be given to a client
+func NewAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate API key: %w", err)
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hash the key is stored with.
+// The keys are random, so they don't need a slow hash to not be guessed from it.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKey is a DTO. It has no key nor hash, so it can be listed
+type APIKey struct {
+	ID         uuid.UUID
+	Name       string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// NewAPIKeyDTO builds an API key DTO from an API key entity
+func NewAPIKeyDTO(k domain.APIKey) APIKey {
+	return APIKey{
+		ID:         k.ID(),
+		Name:       k.Name(),
+		Scopes:     k.Scopes(),
+		ExpiresAt:  k.ExpiresAt(),
+		LastUsedAt: k.LastUsedAt(),
+		RevokedAt:  k.RevokedAt(),
+		CreatedAt:  k.CreatedAt(),
+	}
+}
+
+// APIKeysQuery is a query
+type APIKeysQuery struct{}
+
+// APIKeysName is self-described
+var APIKeysName = "apiKeys"
+
+// Name implements Query interface
+func (q APIKeysQuery) Name() string {
+	return APIKeysName
+}
+
+// APIKeys is a query handler
+type APIKeys struct {
+	kr APIKeysRepository
+}
+
+// NewAPIKeys is a constructor
+func NewAPIKeys(kr APIKeysRepository) APIKeys {
+	return APIKeys{kr: kr}
+}
+
+// Handle implements the QueryHandler interface
+func (qh APIKeys) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	_, ok := query.(APIKeysQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(APIKeysName, query.Name())
+	}
+
+	keys, err := qh.kr.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]APIKey, 0, len(keys))
+	for _, k := range keys {
+		response = append(response, NewAPIKeyDTO(k))
+	}
+	return response, nil
+}
+
+// APIKeyAuthenticator resolves the API keys to the principals of their clients
+type APIKeyAuthenticator struct {
+	log cqrs.Logger
+	kr  APIKeysRepository
+}
+
+// NewAPIKeyAuthenticator is a constructor
+func NewAPIKeyAuthenticator(log cqrs.Logger, kr APIKeysRepository) APIKeyAuthenticator {
+	return APIKeyAuthenticator{log: log, kr: kr}
+}
+
+// Authenticate returns the principal of the key, with its scopes as roles.
+// It returns ErrInvalidAPIKey when the key does not exist, or it's been revoked or it's expired.
+func (a APIKeyAuthenticator) Authenticate(ctx context.Context, key string) (Principal, error) {
+	k, err := a.kr.FindByHash(ctx, HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Principal{}, ErrInvalidAPIKey
+		}
+		return Principal{}, err
+	}
+
+	now := time.Now().UTC()
+	if !k.IsActive(now) {
+		return Principal{}, fmt.Errorf("%w: %s is revoked or expired", ErrInvalidAPIKey, k.ID().String())
+	}
+
+	// Failing to record the use must not deny the request
+	if err := a.kr.TouchLastUsed(ctx, k.ID(), now); err != nil {
+		a.log.Printf("record the use of the API key %s: %s\n", k.ID().String(), err.Error())
+	}
+	return CallerPrincipal(APIKeyPrincipalPrefix+k.ID().String(), k.Scopes()), nil
+}
@@ -0,0 +1,274 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func newAPIKey(t *testing.T, key string, expiresAt *time.Time) domain.APIKey {
+	k, err := domain.NewAPIKey(uuid.New(), "erp", app.HashAPIKey(key), []string{app.RoleMerchandiser}, expiresAt, time.Now())
+	require.NoError(t, err)
+	return k
+}
+
+func TestNewAPIKeySecret(t *testing.T) {
+	t.Run(`Given two new keys, when they're compared, then they're different and their hashes don't disclose them`, func(t *testing.T) {
+		first, err := app.NewAPIKeySecret()
+		require.NoError(t, err)
+		second, err := app.NewAPIKeySecret()
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+		require.True(t, strings.HasPrefix(first, "gqc_"))
+		require.Len(t, app.HashAPIKey(first), 64)
+		require.NotContains(t, app.HashAPIKey(first), first)
+	})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	randomErr := errors.New("")
+	testCases := []struct {
+		name            string
+		cmd             cqrs.Command
+		kr              *APIKeysRepositoryMock
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			kr:   &APIKeysRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a command without key, when it's called, then an error is returned`,
+			cmd:  app.CreateAPIKeyCmd{ID: uuid.New(), KeyName: "erp", Scopes: []string{app.RoleAdmin}},
+			kr:   &APIKeysRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: `Given a key with an invalid name and an unknown scope,
+				when it's created,
+				then a validation error with both of them is returned`,
+			cmd: app.CreateAPIKeyCmd{ID: uuid.New(), Scopes: []string{app.RoleSystem}, Key: "key"},
+			kr:  &APIKeysRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				var verr domain.ValidationError
+				require.ErrorAs(t, err, &verr)
+				require.Len(t, verr.Violations, 2)
+				require.ErrorIs(t, err, domain.ErrInvalidAPIKeyName)
+				require.ErrorIs(t, err, domain.ErrInvalidAPIKeyScope)
+			},
+		},
+		{
+			name: `Given an API keys repository that returns an error on Insert,
+				when it's called,
+				then an error is returned`,
+			cmd: app.CreateAPIKeyCmd{ID: uuid.New(), KeyName: "erp", Scopes: []string{app.RoleAdmin}, Key: "key"},
+			kr: &APIKeysRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.APIKey) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a valid key,
+				when it's created,
+				then only the hash of the key is stored`,
+			cmd: app.CreateAPIKeyCmd{ID: uuid.New(), KeyName: "erp", Scopes: []string{app.RoleAdmin}, Key: "key"},
+			kr:  &APIKeysRepositoryMock{},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := app.NewCreateAPIKey(tc.kr).Handle(context.Background(), tc.cmd)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+
+		cmd := tc.cmd.(app.CreateAPIKeyCmd)
+		inserted := tc.kr.InsertCalls()[0].K
+		require.Equal(t, cmd.ID, inserted.ID(), tc.name)
+		require.Equal(t, app.HashAPIKey(cmd.Key), inserted.Hash(), tc.name)
+		require.Equal(t, cmd.Scopes, inserted.Scopes(), tc.name)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	testCases := []struct {
+		name            string
+		key             func() domain.APIKey
+		findErr         error
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name:    `Given a not found key, when it's revoked, then an error is returned`,
+			findErr: app.ErrNotFound,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrNotFound)
+			},
+		},
+		{
+			name: `Given a revoked key, when it's revoked, then an error is returned`,
+			key: func() domain.APIKey {
+				k := newAPIKey(t, "key", nil)
+				require.NoError(t, k.Revoke(time.Now()))
+				return k
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAPIKeyRevoked)
+			},
+		},
+		{
+			name: `Given an active key, when it's revoked, then it's updated as revoked`,
+			key: func() domain.APIKey {
+				return newAPIKey(t, "key", nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		kr := &APIKeysRepositoryMock{
+			FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.APIKey, error) {
+				if tc.findErr != nil {
+					return domain.APIKey{}, tc.findErr
+				}
+				return tc.key(), nil
+			},
+		}
+		_, err := app.NewRevokeAPIKey(kr).Handle(context.Background(), app.RevokeAPIKeyCmd{ID: uuid.New()})
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+		require.NotNil(t, kr.UpdateCalls()[0].K.RevokedAt(), tc.name)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Run(`Given stored keys, when they're listed, then they're returned without their hashes`, func(t *testing.T) {
+		k := newAPIKey(t, "key", nil)
+		kr := &APIKeysRepositoryMock{
+			FindAllFunc: func(_ context.Context) ([]domain.APIKey, error) {
+				return []domain.APIKey{k}, nil
+			},
+		}
+		response, err := app.NewAPIKeys(kr).Handle(context.Background(), app.APIKeysQuery{})
+		require.NoError(t, err)
+		require.Equal(t, []app.APIKey{app.NewAPIKeyDTO(k)}, response)
+	})
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		soon      = time.Now().Add(time.Millisecond)
+		active    = newAPIKey(t, "active", nil)
+		expired   = newAPIKey(t, "expired", &soon)
+		revoked   = newAPIKey(t, "revoked", nil)
+	)
+	require.NoError(t, revoked.Revoke(time.Now()))
+	time.Sleep(2 * time.Millisecond)
+
+	testCases := []struct {
+		name              string
+		key               string
+		touchErr          error
+		expectedPrincipal app.Principal
+		expectedLogCalls  int
+		expectedErrFunc   func(*testing.T, error)
+	}{
+		{
+			name: `Given an unknown key, when it's authenticated, then an error is returned`,
+			key:  "unknown",
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: `Given an expired key, when it's authenticated, then an error is returned`,
+			key:  "expired",
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: `Given a revoked key, when it's authenticated, then an error is returned`,
+			key:  "revoked",
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name:              `Given an active key, when it's authenticated, then its principal is returned with its scopes as roles`,
+			key:               "active",
+			expectedPrincipal: app.Principal{ID: "apikey:" + active.ID().String(), Roles: []string{app.RoleMerchandiser}},
+		},
+		{
+			name: `Given an active key whose use can't be recorded,
+				when it's authenticated,
+				then the error is logged and its principal is returned`,
+			key:               "active",
+			touchErr:          randomErr,
+			expectedPrincipal: app.Principal{ID: "apikey:" + active.ID().String(), Roles: []string{app.RoleMerchandiser}},
+			expectedLogCalls:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			kr = &APIKeysRepositoryMock{
+				FindByHashFunc: func(_ context.Context, hash string) (domain.APIKey, error) {
+					for _, k := range []domain.APIKey{active, expired, revoked} {
+						if k.Hash() == hash {
+							return k, nil
+						}
+					}
+					return domain.APIKey{}, app.ErrNotFound
+				},
+				TouchLastUsedFunc: func(_ context.Context, _ uuid.UUID, _ time.Time) error {
+					return tc.touchErr
+				},
+			}
+			lm = &loggerMock{}
+		)
+		principal, err := app.NewAPIKeyAuthenticator(lm, kr).Authenticate(context.Background(), tc.key)
+		require.Equal(t, tc.expectedLogCalls, lm.calls, tc.name)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			require.Empty(t, kr.TouchLastUsedCalls(), tc.name)
+			continue
+		}
+		require.Equal(t, tc.expectedPrincipal, principal, tc.name)
+		require.Equal(t, active.ID(), kr.TouchLastUsedCalls()[0].ID, tc.name)
+	}
+
+	t.Run(`Given an API keys repository that returns an error, when a key is authenticated, then the error is returned`, func(t *testing.T) {
+		kr := &APIKeysRepositoryMock{
+			FindByHashFunc: func(_ context.Context, _ string) (domain.APIKey, error) {
+				return domain.APIKey{}, randomErr
+			},
+		}
+		_, err := app.NewAPIKeyAuthenticator(&loggerMock{}, kr).Authenticate(context.Background(), "key")
+		require.ErrorIs(t, err, randomErr)
+	})
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// ArchiveProductCmd is a command
+type ArchiveProductCmd struct {
+	ID uuid.UUID
+}
+
+// ArchiveProductName is self-described
+var ArchiveProductName = "archive.product"
+
+// Name implements the Command interface
+func (cmd ArchiveProductCmd) Name() string {
+	return ArchiveProductName
+}
+
+// ArchiveProduct is a command handler
+type ArchiveProduct struct {
+	pr ProductsRepository
+}
+
+// NewArchiveProduct is a constructor
+func NewArchiveProduct(pr ProductsRepository) ArchiveProduct {
+	return ArchiveProduct{pr: pr}
+}
+
+// Handle implements CommandHandler interface.
+// If the product is modified concurrently, the archive is tried again.
+func (ch ArchiveProduct) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(ArchiveProductCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(ArchiveProductName, cmd.Name())
+	}
+
+	var evs []events.Event
+	err := retryOnConflict(func() error {
+		p, err := ch.pr.FindByID(ctx, co.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := p.Archive(); err != nil {
+			return err
+		}
+
+		if err := ch.pr.Update(ctx, p); err != nil {
+			return err
+		}
+
+		evs = p.Events()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
@@ -0,0 +1,113 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestArchiveProduct(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		available = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		archived  = fixtures.Product{Archived: true}.Build()
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		cmd             cqrs.Command
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a products repository that returns not found on FindByID, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.ArchiveProductCmd{},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return domain.Product{}, app.ErrNotFound
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrNotFound)
+			},
+		},
+		{
+			name: `Given an archived product, 
+				when it's archived, 
+				then an error is returned`,
+			cmd: app.ArchiveProductCmd{},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return archived, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductArchived)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Update, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.ArchiveProductCmd{},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+				UpdateFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given an available product, 
+				when it's archived, 
+				then it's withdrawn from sale and archived`,
+			cmd: app.ArchiveProductCmd{ID: available.ID()},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		ch := app.NewArchiveProduct(testCase.pr)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		require.Equal(t, testCase.cmd.(app.ArchiveProductCmd).ID, testCase.pr.FindByIDCalls()[0].ID)
+		archived := testCase.pr.UpdateCalls()[0].P
+		require.True(t, archived.Archived())
+		require.False(t, archived.IsAvailable())
+		require.Equal(t, domain.StatusArchived, archived.Status())
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductArchivedEventName, evs[0].Name())
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// Roles of the principals
+const (
+	RoleBuyer        = "buyer"
+	RoleMerchandiser = "merchandiser"
+	RoleAdmin        = "admin"
+	// RoleSystem is the role of the jobs of the service itself, like the reservations sweeper
+	RoleSystem = "system"
+	// RoleAnyone is given in a policy to allow a command or query to any caller, even the anonymous ones
+	RoleAnyone = "*"
+)
+
+// SystemPrincipal is the principal the jobs of the service itself run as
+var SystemPrincipal = Principal{ID: "system", Roles: []string{RoleSystem}}
+
+// ForbiddenError is returned when the caller has none of the roles allowed to run a command or query
+type ForbiddenError struct {
+	// Name is the name of the command or query
+	Name string
+	// Anonymous is true when the caller has not been authenticated
+	Anonymous bool
+}
+
+// Error implements the error interface
+func (e ForbiddenError) Error() string {
+	if e.Anonymous {
+		return fmt.Sprintf("forbidden: %s requires an authenticated caller", e.Name)
+	}
+	return fmt.Sprintf("forbidden: the caller is not allowed to run %s", e.Name)
+}
+
+// ErrInvalidPolicy is self-described
+var ErrInvalidPolicy = errors.New("invalid authorization policy")
+
+// Policy maps the names of the commands and queries to the roles allowed to run them.
+// The commands and queries that are not in the policy are denied to everyone.
+type Policy map[string][]string
+
+// DefaultPolicy returns the policy used when none is configured. The catalog can be browsed
+// and purchased from by anyone, the catalog is managed by the merchandisers, and the orders, refunds and API keys by the admins.
+func DefaultPolicy() Policy {
+	return Policy{
+		ProductsName:           {RoleAnyone},
+		ProductsConnectionName: {RoleAnyone},
+		ProductName:            {RoleAnyone},
+		SearchProductsName:     {RoleAnyone},
+		PurchaseProductName:    {RoleAnyone},
+		CheckoutName:           {RoleAnyone},
+		ReserveProductName:     {RoleAnyone},
+		CreateProductName:      {RoleMerchandiser, RoleAdmin},
+		UpdateProductName:      {RoleMerchandiser, RoleAdmin},
+		ArchiveProductName:     {RoleMerchandiser, RoleAdmin},
+		RefundPurchaseName:     {RoleAdmin},
+		OrdersName:             {RoleAdmin},
+		OrderName:              {RoleAdmin},
+		CreateAPIKeyName:       {RoleAdmin},
+		RevokeAPIKeyName:       {RoleAdmin},
+		APIKeysName:            {RoleAdmin},
+		ExpireReservationName:  {RoleSystem},
+	}
+}
+
+// ParsePolicy parses a JSON policy, like {"purchase.product": ["buyer"]}.
+// It fails when it has a name that is not a command nor a query, so a misspelled name doesn't go unnoticed.
+// The system role is kept for the commands the default policy allows it to run, so the jobs of the service itself
+// go on running whatever the policy says.
+func ParsePolicy(data []byte) (Policy, error) {
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err.Error())
+	}
+
+	known := DefaultPolicy()
+	var unknown []string
+	for name := range policy {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: unknown commands or queries %v", ErrInvalidPolicy, unknown)
+	}
+
+	if policy == nil {
+		policy = Policy{}
+	}
+	for name, roles := range known {
+		if hasRole(roles, RoleSystem) && !hasRole(policy[name], RoleSystem) {
+			policy[name] = append(policy[name], RoleSystem)
+		}
+	}
+	return policy, nil
+}
+
+// Authorize returns a ForbiddenError when the principal of the context has none of the roles allowed to run the command or query
+func (p Policy) Authorize(ctx context.Context, name string) error {
+	principal, authenticated := PrincipalFromContext(ctx)
+	for _, role := range p[name] {
+		if role == RoleAnyone || (authenticated && principal.HasRole(role)) {
+			return nil
+		}
+	}
+	return ForbiddenError{Name: name, Anonymous: !authenticated}
+}
+
+// ChAuthorizationMw is a command handler middleware that runs the command only when the policy allows the caller to
+func ChAuthorizationMw(policy Policy) cqrs.CommandHandlerMiddleware {
+	return func(ch cqrs.CommandHandler) cqrs.CommandHandler {
+		return cqrs.CommandHandlerFunc(func(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+			if err := policy.Authorize(ctx, cmd.Name()); err != nil {
+				return nil, err
+			}
+			return ch.Handle(ctx, cmd)
+		})
+	}
+}
+
+// QhAuthorizationMw is a query handler middleware that runs the query only when the policy allows the caller to
+func QhAuthorizationMw(policy Policy) cqrs.QueryHandlerMiddleware {
+	return func(qh cqrs.QueryHandler) cqrs.QueryHandler {
+		return cqrs.QueryHandlerFunc(func(ctx context.Context, q cqrs.Query) (cqrs.QueryResult, error) {
+			if err := policy.Authorize(ctx, q.Name()); err != nil {
+				return nil, err
+			}
+			return qh.Handle(ctx, q)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+func TestAuthorizationMw(t *testing.T) {
+	var (
+		policy = app.Policy{
+			app.CreateProductName: {app.RoleMerchandiser, app.RoleAdmin},
+			app.ProductsName:      {app.RoleAnyone},
+		}
+		anonymous    = context.Background()
+		buyer        = app.ContextWithPrincipal(context.Background(), app.Principal{ID: "buyer", Roles: []string{app.RoleBuyer}})
+		merchandiser = app.ContextWithPrincipal(context.Background(), app.Principal{ID: "merch", Roles: []string{app.RoleMerchandiser}})
+	)
+	testCases := []struct {
+		name            string
+		ctx             context.Context
+		cmdOrQueryName  string
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given a caller with one of the allowed roles,
+				when it's handled,
+				then it's run`,
+			ctx:            merchandiser,
+			cmdOrQueryName: app.CreateProductName,
+		},
+		{
+			name: `Given a caller without any of the allowed roles,
+				when it's handled,
+				then a ForbiddenError is returned`,
+			ctx:            buyer,
+			cmdOrQueryName: app.CreateProductName,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.Equal(t, app.ForbiddenError{Name: app.CreateProductName}, err)
+			},
+		},
+		{
+			name: `Given an anonymous caller and a name that requires a role,
+				when it's handled,
+				then an anonymous ForbiddenError is returned`,
+			ctx:            anonymous,
+			cmdOrQueryName: app.CreateProductName,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.Equal(t, app.ForbiddenError{Name: app.CreateProductName, Anonymous: true}, err)
+			},
+		},
+		{
+			name: `Given an anonymous caller and a name allowed to anyone,
+				when it's handled,
+				then it's run`,
+			ctx:            anonymous,
+			cmdOrQueryName: app.ProductsName,
+		},
+		{
+			name: `Given a name that is not in the policy,
+				when it's handled,
+				then it's denied to everyone`,
+			ctx:            merchandiser,
+			cmdOrQueryName: app.RefundPurchaseName,
+			expectedErrFunc: func(t *testing.T, err error) {
+				var ferr app.ForbiddenError
+				require.True(t, errors.As(err, &ferr))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			ch = &CommandHandlerMock{
+				HandleFunc: func(_ context.Context, _ cqrs.Command) ([]events.Event, error) {
+					return nil, nil
+				},
+			}
+			qh = &QueryHandlerMock{
+				HandleFunc: func(_ context.Context, _ cqrs.Query) (cqrs.QueryResult, error) {
+					return nil, nil
+				},
+			}
+			cmd = &CommandMock{NameFunc: func() string { return tc.cmdOrQueryName }}
+			q   = &QueryMock{NameFunc: func() string { return tc.cmdOrQueryName }}
+		)
+		_, chErr := app.ChAuthorizationMw(policy)(ch).Handle(tc.ctx, cmd)
+		_, qhErr := app.QhAuthorizationMw(policy)(qh).Handle(tc.ctx, q)
+
+		expectedCalls := 0
+		if tc.expectedErrFunc == nil {
+			expectedCalls = 1
+		}
+		require.Len(t, ch.HandleCalls(), expectedCalls, tc.name)
+		require.Len(t, qh.HandleCalls(), expectedCalls, tc.name)
+		for _, err := range []error{chErr, qhErr} {
+			require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+			if err != nil {
+				tc.expectedErrFunc(t, err)
+			}
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		policy         string
+		expectedPolicy app.Policy
+		expectedErr    error
+	}{
+		{
+			name:        `Given a malformed policy, when it's parsed, then an error is returned`,
+			policy:      `{"purchase.product": "buyer"}`,
+			expectedErr: app.ErrInvalidPolicy,
+		},
+		{
+			name:        `Given a policy with an unknown name, when it's parsed, then an error is returned`,
+			policy:      `{"purchase.products": ["buyer"]}`,
+			expectedErr: app.ErrInvalidPolicy,
+		},
+		{
+			name:   `Given a valid policy, when it's parsed, then it's returned`,
+			policy: `{"purchase.product": ["buyer"], "orders": ["admin", "merchandiser"]}`,
+			expectedPolicy: app.Policy{
+				app.PurchaseProductName:   {app.RoleBuyer},
+				app.OrdersName:            {app.RoleAdmin, app.RoleMerchandiser},
+				app.ExpireReservationName: {app.RoleSystem},
+			},
+		},
+		{
+			name:           `Given a policy that takes the system role off a command of the service jobs, when it's parsed, then the role is kept`,
+			policy:         `{"expire.reservation": ["admin"]}`,
+			expectedPolicy: app.Policy{app.ExpireReservationName: {app.RoleAdmin, app.RoleSystem}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := app.ParsePolicy([]byte(tc.policy))
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedPolicy, policy)
+		})
+	}
+}
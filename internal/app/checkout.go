@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// CheckoutItem is an item of a checkout
+type CheckoutItem struct {
+	ProductID uuid.UUID
+	// Quantity is the number of units to purchase. If it's not provided, one unit is purchased
+	Quantity int
+	// OrderID is the ID of the order to be created. If it's not provided, a new one is generated
+	OrderID uuid.UUID
+}
+
+// CheckoutCmd is a command
+type CheckoutCmd struct {
+	Items []CheckoutItem
+	// Holder is the session token the reserved items are held for. It's optional,
+	// and it's ignored when the buyer is authenticated
+	Holder string
+	// BuyerID is the ID of the authenticated buyer. It's empty for the anonymous purchases
+	BuyerID string
+}
+
+// CheckoutName is self-described
+var CheckoutName = "checkout"
+
+// Name implements the Command interface
+func (cmd CheckoutCmd) Name() string {
+	return CheckoutName
+}
+
+// holder returns the holder of the reservations the checkout is done for
+func (cmd CheckoutCmd) holder() string {
+	return reservationHolder(cmd.BuyerID, cmd.Holder)
+}
+
+// ErrEmptyCheckout is self-described
+var ErrEmptyCheckout = errors.New("empty checkout")
+
+// CheckoutItemFailure is the reason why an item of a checkout can't be purchased
+type CheckoutItemFailure struct {
+	ProductID uuid.UUID
+	Err       error
+}
+
+// CheckoutError is returned when some of the items of a checkout can't be purchased
+type CheckoutError struct {
+	Failures []CheckoutItemFailure
+}
+
+// Error implements the error.Error interface
+func (e CheckoutError) Error() string {
+	reasons := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", f.ProductID, f.Err))
+	}
+	return "checkout failed: " + strings.Join(reasons, ", ")
+}
+
+// Checkout is a command handler
+type Checkout struct {
+	pr ProductsRepository
+	or OrdersRepository
+	tx Transactor
+}
+
+// NewCheckout is a constructor
+func NewCheckout(pr ProductsRepository, or OrdersRepository, tx Transactor) Checkout {
+	return Checkout{pr: pr, or: or, tx: tx}
+}
+
+// Handle implements CommandHandler interface.
+// All the items are purchased in the same transaction, so either all of them are purchased or none.
+// Their products are locked meanwhile, in the order of their IDs so the concurrent checkouts don't deadlock.
+// When some of them can't be purchased, a CheckoutError with the reason of each one is returned.
+func (ch Checkout) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(CheckoutCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(CheckoutName, cmd.Name())
+	}
+	if len(co.Items) == 0 {
+		return nil, ErrEmptyCheckout
+	}
+
+	var evs []events.Event
+	err := retryOnConflict(func() error {
+		evs = nil
+		return ch.tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := ch.lock(ctx, co.Items); err != nil {
+				return err
+			}
+
+			var failures []CheckoutItemFailure
+			for _, item := range co.Items {
+				itemEvs, err := ch.purchase(ctx, co, item)
+				if err != nil {
+					if !isCheckoutItemFailure(err) {
+						return err
+					}
+					failures = append(failures, CheckoutItemFailure{ProductID: item.ProductID, Err: err})
+					continue
+				}
+				evs = append(evs, itemEvs...)
+			}
+			if len(failures) > 0 {
+				return CheckoutError{Failures: failures}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
+
+// lock locks the products of the items. The products not found are left to be reported by their purchase
+func (ch Checkout) lock(ctx context.Context, items []CheckoutItem) error {
+	IDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		IDs = append(IDs, item.ProductID)
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i].String() < IDs[j].String() })
+
+	for _, ID := range IDs {
+		if _, err := ch.pr.FindByIDForUpdate(ctx, ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ch Checkout) purchase(ctx context.Context, co CheckoutCmd, item CheckoutItem) ([]events.Event, error) {
+	quantity := item.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	orderID := item.OrderID
+	if orderID == uuid.Nil {
+		orderID = uuid.New()
+	}
+
+	p, err := ch.pr.FindByID(ctx, item.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := releaseExpiredReservation(&p, now); err != nil {
+		return nil, err
+	}
+
+	if err := p.PurchaseBy(co.holder(), quantity); err != nil {
+		return nil, err
+	}
+
+	if err := ch.pr.UpdateStock(ctx, p); err != nil {
+		return nil, err
+	}
+
+	price, _ := p.Price() // a product without price can't be purchased
+	if err := ch.or.Insert(ctx, domain.NewOrder(orderID, p.ID(), co.BuyerID, price, quantity, now)); err != nil {
+		return nil, err
+	}
+
+	return p.Events(), nil
+}
+
+// isCheckoutItemFailure returns true when the error is due to the item itself, so the rest of items can still be checked
+func isCheckoutItemFailure(err error) bool {
+	for _, target := range []error{
+		ErrNotFound,
+		domain.ErrProductPurchased,
+		domain.ErrProductReserved,
+		domain.ErrProductWithoutPrice,
+		domain.ErrInsufficientStock,
+		domain.ErrInvalidQuantity,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
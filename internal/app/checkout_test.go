@@ -0,0 +1,137 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestCheckout(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		available = []domain.Product{
+			fixtures.Product{Available: helpers.BoolPtr(true)}.Build(),
+			fixtures.Product{Available: helpers.BoolPtr(true)}.Build(),
+		}
+		purchased = fixtures.Product{Available: helpers.BoolPtr(false)}.Build()
+		products  = map[uuid.UUID]domain.Product{
+			available[0].ID(): available[0],
+			available[1].ID(): available[1],
+			purchased.ID():    purchased,
+		}
+		findByID = func(_ context.Context, ID uuid.UUID) (domain.Product, error) {
+			p, ok := products[ID]
+			if !ok {
+				return domain.Product{}, app.ErrNotFound
+			}
+			return p, nil
+		}
+		missingID = uuid.New()
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		or              *OrdersRepositoryMock
+		cmd             cqrs.Command
+		expectedEvents  int
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a checkout without items, when it's called, then an error is returned`,
+			cmd:  app.CheckoutCmd{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrEmptyCheckout)
+			},
+		},
+		{
+			name: `Given a checkout with some items that can't be purchased, 
+				when it's called, 
+				then an error with the reason of each failed item is returned`,
+			cmd: app.CheckoutCmd{Items: []app.CheckoutItem{
+				{ProductID: available[0].ID()},
+				{ProductID: purchased.ID()},
+				{ProductID: missingID},
+			}},
+			pr: &ProductsRepositoryMock{FindByIDFunc: findByID},
+			or: &OrdersRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				var checkoutErr app.CheckoutError
+				require.ErrorAs(t, err, &checkoutErr)
+				require.Len(t, checkoutErr.Failures, 2)
+				require.Equal(t, purchased.ID(), checkoutErr.Failures[0].ProductID)
+				require.ErrorIs(t, checkoutErr.Failures[0].Err, domain.ErrProductPurchased)
+				require.Equal(t, missingID, checkoutErr.Failures[1].ProductID)
+				require.ErrorIs(t, checkoutErr.Failures[1].Err, app.ErrNotFound)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Update, 
+				when it's called, 
+				then the checkout is aborted and the error is returned`,
+			cmd: app.CheckoutCmd{Items: []app.CheckoutItem{
+				{ProductID: available[0].ID()},
+				{ProductID: available[1].ID()},
+			}},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: findByID,
+				UpdateStockFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			or: &OrdersRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a checkout with available items, 
+				when it's called, 
+				then all of them are purchased`,
+			cmd: app.CheckoutCmd{Items: []app.CheckoutItem{
+				{ProductID: available[0].ID()},
+				{ProductID: available[1].ID()},
+			}},
+			pr:             &ProductsRepositoryMock{FindByIDFunc: findByID},
+			or:             &OrdersRepositoryMock{},
+			expectedEvents: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tx := &TransactorMock{
+			WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+				return fn(ctx)
+			},
+		}
+		ch := app.NewCheckout(testCase.pr, testCase.or, tx)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		require.Len(t, tx.WithinTxCalls(), 1)
+		require.Len(t, evs, testCase.expectedEvents)
+		for _, ev := range evs {
+			require.Equal(t, domain.ProductPurchasedEventName, ev.Name())
+		}
+		require.Len(t, testCase.or.InsertCalls(), len(testCase.cmd.(app.CheckoutCmd).Items))
+	}
+}
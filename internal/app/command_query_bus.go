@@ -1,23 +1,72 @@
 package app
 
 import (
+	"time"
+
 	"github.com/theskyinflames/cqrs-eda/pkg/bus"
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
 	"github.com/theskyinflames/cqrs-eda/pkg/helpers"
 )
 
-// BuildCommandQueryBus returns the command/query bus
-func BuildCommandQueryBus(log cqrs.Logger, eventsBus bus.Bus, pr ProductsRepository) bus.Bus {
+// BuildCommandQueryBus returns the command/query bus. The commands and queries are only run for the callers the policy allows to
+func BuildCommandQueryBus(
+	log cqrs.Logger,
+	pr ProductsRepository,
+	or OrdersRepository,
+	kr APIKeysRepository,
+	tx Transactor,
+	is IdempotencyStore,
+	ob Outbox,
+	reservationTTL time.Duration,
+	policy Policy,
+) bus.Bus {
+	// The last middleware is the outermost one, so the callers are authorized before anything else is done
 	chMw := cqrs.CommandHandlerMultiMiddleware(
-		cqrs.ChEventMw(eventsBus),
+		ChOutboxMw(ob, tx),
+		ChIdempotencyMw(is, tx),
 		cqrs.ChErrMw(log),
+		ChAuthorizationMw(policy),
+	)
+	qhMw := cqrs.QueryHandlerMultiMiddleware(
+		cqrs.QhErrMw(log),
+		QhAuthorizationMw(policy),
 	)
 
-	purchaseProduct := chMw(NewPurchaseProduct(pr))
-	productsQh := cqrs.QhErrMw(log)(NewProducts(pr))
+	purchaseProduct := chMw(NewPurchaseProduct(pr, or, tx))
+	refundPurchase := chMw(NewRefundPurchase(pr, or, tx))
+	checkout := chMw(NewCheckout(pr, or, tx))
+	createProduct := chMw(NewCreateProduct(pr))
+	updateProduct := chMw(NewUpdateProduct(pr))
+	archiveProduct := chMw(NewArchiveProduct(pr))
+	reserveProduct := chMw(NewReserveProduct(pr, reservationTTL))
+	expireReservation := chMw(NewExpireReservation(pr))
+	createAPIKey := chMw(NewCreateAPIKey(kr))
+	revokeAPIKey := chMw(NewRevokeAPIKey(kr))
+	productsQh := qhMw(NewProducts(pr))
+	productsConnectionQh := qhMw(NewProductsConnection(pr))
+	productQh := qhMw(NewProductByID(pr))
+	searchProductsQh := qhMw(NewSearchProducts(pr))
+	ordersQh := qhMw(NewOrders(or))
+	orderQh := qhMw(NewOrderByID(or))
+	apiKeysQh := qhMw(NewAPIKeys(kr))
 
 	bus := bus.New()
 	bus.Register(PurchaseProductName, helpers.BusChHandler(purchaseProduct))
+	bus.Register(RefundPurchaseName, helpers.BusChHandler(refundPurchase))
+	bus.Register(CheckoutName, helpers.BusChHandler(checkout))
+	bus.Register(CreateProductName, helpers.BusChHandler(createProduct))
+	bus.Register(UpdateProductName, helpers.BusChHandler(updateProduct))
+	bus.Register(ArchiveProductName, helpers.BusChHandler(archiveProduct))
+	bus.Register(ReserveProductName, helpers.BusChHandler(reserveProduct))
+	bus.Register(ExpireReservationName, helpers.BusChHandler(expireReservation))
+	bus.Register(CreateAPIKeyName, helpers.BusChHandler(createAPIKey))
+	bus.Register(RevokeAPIKeyName, helpers.BusChHandler(revokeAPIKey))
 	bus.Register(ProductsName, helpers.BusQhHandler(productsQh))
+	bus.Register(ProductsConnectionName, helpers.BusQhHandler(productsConnectionQh))
+	bus.Register(ProductName, helpers.BusQhHandler(productQh))
+	bus.Register(SearchProductsName, helpers.BusQhHandler(searchProductsQh))
+	bus.Register(OrdersName, helpers.BusQhHandler(ordersQh))
+	bus.Register(OrderName, helpers.BusQhHandler(orderQh))
+	bus.Register(APIKeysName, helpers.BusQhHandler(apiKeysQh))
 	return bus
 }
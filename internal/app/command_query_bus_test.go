@@ -0,0 +1,39 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandQueryBusAuthorization(t *testing.T) {
+	t.Run(`Given a policy that denies a command to the caller,
+		when it's dispatched with an idempotency key,
+		then a ForbiddenError is returned before the command is logged or the idempotency store and the outbox are touched`, func(t *testing.T) {
+		var (
+			lm = &loggerMock{}
+			is = &IdempotencyStoreMock{}
+			ob = &OutboxMock{}
+			tx = &TransactorMock{
+				WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				},
+			}
+			policy = app.Policy{app.PurchaseProductName: {app.RoleBuyer}}
+			cmd    = app.PurchaseProductCmd{ID: uuid.New(), Quantity: 1, Key: "key", Client: "ip:127.0.0.1"}
+		)
+		bus := app.BuildCommandQueryBus(lm, &ProductsRepositoryMock{}, &OrdersRepositoryMock{}, &APIKeysRepositoryMock{}, tx, is, ob, app.DefaultReservationTTL, policy)
+
+		_, err := bus.Dispatch(context.Background(), cmd)
+		require.Equal(t, app.ForbiddenError{Name: app.PurchaseProductName, Anonymous: true}, err)
+		require.Zero(t, lm.calls)
+		require.Empty(t, is.FindByKeyCalls())
+		require.Empty(t, is.ReserveCalls())
+		require.Empty(t, ob.SaveCalls())
+		require.Empty(t, tx.WithinTxCalls())
+	})
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// CreateAPIKeyCmd is a command
+type CreateAPIKeyCmd struct {
+	ID        uuid.UUID
+	KeyName   string
+	Scopes    []string
+	ExpiresAt *time.Time
+	// Key is the plaintext key given to the client. Only its hash is stored,
+	// and it's not marshaled, so it's not written to the logs.
+	Key string `json:"-"`
+}
+
+// CreateAPIKeyName is self-described
+var CreateAPIKeyName = "create.api_key"
+
+// Name implements the Command interface
+func (cmd CreateAPIKeyCmd) Name() string {
+	return CreateAPIKeyName
+}
+
+// CreateAPIKey is a command handler
+type CreateAPIKey struct {
+	kr APIKeysRepository
+}
+
+// NewCreateAPIKey is a constructor
+func NewCreateAPIKey(kr APIKeysRepository) CreateAPIKey {
+	return CreateAPIKey{kr: kr}
+}
+
+// Handle implements CommandHandler interface.
+// When the key is not valid, a domain.ValidationError with all the broken rules is returned.
+func (ch CreateAPIKey) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(CreateAPIKeyCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(CreateAPIKeyName, cmd.Name())
+	}
+	if co.Key == "" {
+		return nil, fmt.Errorf("%w: it's empty", ErrInvalidAPIKey)
+	}
+
+	k, err := domain.NewAPIKey(co.ID, co.KeyName, HashAPIKey(co.Key), co.Scopes, co.ExpiresAt, time.Now())
+	var verr domain.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		return nil, err
+	}
+	// The scopes are roles, which are not known by the domain
+	for _, s := range co.Scopes {
+		if !isAPIKeyScope(s) {
+			verr.Violations = append(verr.Violations, domain.FieldViolation{
+				Field:  "scopes",
+				Reason: fmt.Sprintf("unknown scope %q", s),
+				Err:    domain.ErrInvalidAPIKeyScope,
+			})
+		}
+	}
+	if len(verr.Violations) > 0 {
+		return nil, domain.ValidationError{Entity: domain.APIKeyEntity, Violations: verr.Violations}
+	}
+
+	return nil, ch.kr.Insert(ctx, k)
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// CreateProductCmd is a command
+type CreateProductCmd struct {
+	ID          uuid.UUID
+	ProductName string
+	// Price is nil when the product has not been priced yet
+	Price *domain.Money
+	// Stock is the number of units for sale. The product is available when it has any
+	Stock int
+}
+
+// CreateProductName is self-described
+var CreateProductName = "create.product"
+
+// Name implements the Command interface
+func (cmd CreateProductCmd) Name() string {
+	return CreateProductName
+}
+
+// CreateProduct is a command handler
+type CreateProduct struct {
+	pr ProductsRepository
+}
+
+// NewCreateProduct is a constructor
+func NewCreateProduct(pr ProductsRepository) CreateProduct {
+	return CreateProduct{pr: pr}
+}
+
+// Handle implements CommandHandler interface
+func (ch CreateProduct) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(CreateProductCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(CreateProductName, cmd.Name())
+	}
+
+	p, err := domain.NewProduct(co.ID, co.ProductName, co.Price, co.Stock)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.pr.Insert(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return p.Events(), nil
+}
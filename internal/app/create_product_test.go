@@ -0,0 +1,90 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestCreateProduct(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		price     = fixtures.Money("1.1")
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		cmd             cqrs.Command
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a command with an empty name, when it's called, then an error is returned`,
+			cmd:  app.CreateProductCmd{ID: uuid.New(), Price: &price, Stock: 1},
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidProductName)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Insert, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.CreateProductCmd{ID: uuid.New(), ProductName: "product1", Price: &price, Stock: 1},
+			pr: &ProductsRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a valid command, 
+				when it's called, 
+				then the product is inserted and a product created event is returned`,
+			cmd: app.CreateProductCmd{ID: uuid.New(), ProductName: "product1", Price: &price, Stock: 2},
+			pr: &ProductsRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Product) error {
+					return nil
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		ch := app.NewCreateProduct(testCase.pr)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		cmd := testCase.cmd.(app.CreateProductCmd)
+		require.Len(t, testCase.pr.InsertCalls(), 1)
+		inserted := testCase.pr.InsertCalls()[0].P
+		require.Equal(t, cmd.ID, inserted.ID())
+		require.Equal(t, cmd.ProductName, inserted.Name())
+		require.Equal(t, cmd.Stock, inserted.Stock())
+		require.True(t, inserted.IsAvailable())
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductCreatedEventName, evs[0].Name())
+	}
+}
@@ -42,3 +42,10 @@ func NewInvalidQueryError(expected, had string) InvalidQueryError {
 
 // ErrNotFound is an entity not found error
 var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned when an entity is created with the ID of another one
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrVersionConflict is returned when an entity has been modified by someone else since it was read.
+// The operation can be retried.
+var ErrVersionConflict = errors.New("version conflict")
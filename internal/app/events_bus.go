@@ -11,10 +11,21 @@ import (
 	"github.com/theskyinflames/cqrs-eda/pkg/events"
 )
 
-// BuildEventsBus returns a generic events bus
-func BuildEventsBus() bus.Bus {
+// BuildEventsBus returns a generic events bus. The events are also published to the hub for its subscribers
+func BuildEventsBus(hub EventsHub) bus.Bus {
+	evh := eventHandlers(eventHandler(), hub.Publish)
+
 	eventsBus := bus.New()
-	eventsBus.Register(domain.ProductPurchasedEventName, busHandler(eventHandler()))
+	eventsBus.Register(domain.ProductCreatedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductPurchasedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductRefundedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductPriceChangedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductRenamedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductPublishedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductUnpublishedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductArchivedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductReservedEventName, busHandler(evh))
+	eventsBus.Register(domain.ProductReservationExpiredEventName, busHandler(evh))
 	return eventsBus
 }
 
@@ -24,6 +35,15 @@ func eventHandler() events.Handler {
 	})
 }
 
+// eventHandlers returns a handler that calls all the given handlers in order
+func eventHandlers(handlers ...events.Handler) events.Handler {
+	return events.Handler(func(ev events.Event) {
+		for _, h := range handlers {
+			h(ev)
+		}
+	})
+}
+
 func busHandler(evh events.Handler) bus.Handler {
 	return bus.Handler(func(_ context.Context, d bus.Dispatchable) (interface{}, error) {
 		ev, ok := d.(events.Event)
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// eventPayload has the fields of the domain events that are not kept by events.EventBasic
+type eventPayload struct {
+	ID          uuid.UUID
+	ProductName string        `json:",omitempty"`
+	Price       *domain.Money `json:",omitempty"`
+	Stock       int           `json:",omitempty"`
+	Quantity    int           `json:",omitempty"`
+	Sold        int           `json:",omitempty"`
+	From        domain.Status `json:",omitempty"`
+	Status      domain.Status `json:",omitempty"`
+	Holder      string        `json:",omitempty"`
+	ExpiresAt   time.Time
+}
+
+// EncodeEvent returns the payload of a domain event to be stored
+func EncodeEvent(ev events.Event) ([]byte, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("encode event %s: %w", ev.Name(), err)
+	}
+	return payload, nil
+}
+
+// DecodeEvent rebuilds a domain event from its name, the ID of its aggregate and its payload
+func DecodeEvent(name string, aggregateID uuid.UUID, payload []byte) (events.Event, error) {
+	var body eventPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("decode event %s: %w", name, err)
+	}
+
+	switch name {
+	case domain.ProductCreatedEventName:
+		var ev domain.ProductCreatedEvent
+		ev.Hydrate(body.ID, aggregateID, body.ProductName, body.Price, body.Stock)
+		return ev, nil
+	case domain.ProductPurchasedEventName:
+		var ev domain.ProductPurchasedEvent
+		ev.Hydrate(body.ID, aggregateID, body.Quantity, body.Stock)
+		return ev, nil
+	case domain.ProductRefundedEventName:
+		var ev domain.ProductRefundedEvent
+		ev.Hydrate(body.ID, aggregateID, body.Quantity, body.Stock, body.Status)
+		return ev, nil
+	case domain.ProductPriceChangedEventName:
+		if body.Price == nil {
+			return nil, fmt.Errorf("decode event %s: missing price", name)
+		}
+		var ev domain.ProductPriceChangedEvent
+		ev.Hydrate(body.ID, aggregateID, *body.Price)
+		return ev, nil
+	case domain.ProductRenamedEventName:
+		var ev domain.ProductRenamedEvent
+		ev.Hydrate(body.ID, aggregateID, body.ProductName)
+		return ev, nil
+	case domain.ProductPublishedEventName:
+		var ev domain.ProductPublishedEvent
+		ev.Hydrate(body.ID, aggregateID, body.Stock)
+		return ev, nil
+	case domain.ProductUnpublishedEventName:
+		var ev domain.ProductUnpublishedEvent
+		ev.Hydrate(body.ID, aggregateID, body.Stock)
+		return ev, nil
+	case domain.ProductArchivedEventName:
+		var ev domain.ProductArchivedEvent
+		ev.Hydrate(body.ID, aggregateID, body.From, body.Stock)
+		return ev, nil
+	case domain.ProductReservedEventName:
+		var ev domain.ProductReservedEvent
+		ev.Hydrate(body.ID, aggregateID, body.Holder, body.ExpiresAt, body.Stock)
+		return ev, nil
+	case domain.ProductReservationExpiredEventName:
+		var ev domain.ProductReservationExpiredEvent
+		ev.Hydrate(body.ID, aggregateID, body.Holder, body.Stock)
+		return ev, nil
+	case domain.ProductSnapshotTakenEventName:
+		var ev domain.ProductSnapshotTakenEvent
+		ev.Hydrate(body.ID, aggregateID, body.ProductName, body.Price, body.Status, body.Holder, body.ExpiresAt, body.Stock, body.Sold)
+		return ev, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnknownEvent, name)
+	}
+}
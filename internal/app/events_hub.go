@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+const (
+	// subscriptionBufferSize is the number of events kept for a subscriber that is not receiving them as fast as they're published
+	subscriptionBufferSize = 16
+	// historySize is the number of the last published events kept to be sent again to the subscribers that resume
+	historySize = 1000
+	// replayBatchSize is the number of events read from the outbox at once when a subscriber resumes from it
+	replayBatchSize = 100
+)
+
+// PublishedEvent is an event published by the hub
+type PublishedEvent struct {
+	// ID identifies the event. It's used to resume a subscription.
+	// For the events relayed from the outbox, it's the position of the event in the stream of the delivered ones
+	ID    string
+	Event events.Event
+	// OccurredAt is when the event was stored in the outbox, so it's the time of the command that recorded it
+	OccurredAt time.Time
+}
+
+// EventsHub fans out the events published on the events bus to the subscribers that are listening to them.
+// It keeps the last published events, so a subscriber can resume from the last event it received.
+// When the hub reads the outbox, a subscriber that resumes from an event that is not kept anymore
+// receives the events delivered by the outbox after that one.
+type EventsHub struct {
+	*hub
+}
+
+type hub struct {
+	log    cqrs.Logger
+	outbox Outbox
+
+	mux     sync.Mutex
+	seq     int64
+	history []PublishedEvent
+	subs    map[*subscription]struct{}
+}
+
+type subscription struct {
+	names map[string]bool
+	ch    chan PublishedEvent
+}
+
+func (s *subscription) wants(ev events.Event) bool {
+	return len(s.names) == 0 || s.names[ev.Name()]
+}
+
+// NewEventsHub is a constructor of a hub that only keeps the last published events
+func NewEventsHub() EventsHub {
+	return EventsHub{&hub{subs: make(map[*subscription]struct{})}}
+}
+
+// NewOutboxEventsHub is a constructor of a hub that also reads the outbox the events are relayed from,
+// so the subscribers can resume from the events that are not kept anymore
+func NewOutboxEventsHub(log cqrs.Logger, ob Outbox) EventsHub {
+	return EventsHub{&hub{log: log, outbox: ob, subs: make(map[*subscription]struct{})}}
+}
+
+// Subscribe returns a channel that receives the published events with one of the given names,
+// or all of them if no name is given. The channel is closed when the context is done.
+// If the ID of the last event received by the subscriber is given, the channel receives first the events published
+// after that one. If that event is not kept anymore, they're read from the stream of the outbox, or all the kept events are received
+// when the hub doesn't read the outbox.
+func (h EventsHub) Subscribe(ctx context.Context, lastEventID string, names ...string) <-chan PublishedEvent {
+	sub := &subscription{names: make(map[string]bool, len(names))}
+	for _, n := range names {
+		sub.names[n] = true
+	}
+
+	h.mux.Lock()
+	missed, kept := h.historyAfter(lastEventID)
+	seq, err := strconv.ParseInt(lastEventID, 10, 64)
+	replay := lastEventID != "" && !kept && h.outbox != nil && err == nil
+	if replay {
+		missed = nil
+	}
+	var wanted []PublishedEvent
+	for _, pe := range missed {
+		if sub.wants(pe.Event) {
+			wanted = append(wanted, pe)
+		}
+	}
+	sub.ch = make(chan PublishedEvent, len(wanted)+subscriptionBufferSize)
+	for _, pe := range wanted {
+		sub.ch <- pe
+	}
+	h.subs[sub] = struct{}{}
+	h.mux.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mux.Lock()
+		defer h.mux.Unlock()
+		delete(h.subs, sub)
+		close(sub.ch)
+	}()
+
+	if !replay {
+		return sub.ch
+	}
+	out := make(chan PublishedEvent, subscriptionBufferSize)
+	go h.replay(ctx, sub, seq, out)
+	return out
+}
+
+// replay sends to out the events delivered by the outbox after the given position, and then the events published
+// to the subscription meanwhile and afterwards. The events published meanwhile that have been replayed are skipped
+func (h EventsHub) replay(ctx context.Context, sub *subscription, seq int64, out chan<- PublishedEvent) {
+	defer close(out)
+
+	replayed := make(map[string]bool)
+	for {
+		msgs, err := h.outbox.FindAfter(ctx, seq, replayBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.log.Printf("replaying events after %d: %s\n", seq, err.Error())
+			}
+			break
+		}
+		for _, m := range msgs {
+			seq = m.Seq
+			oe, err := m.outboxEvent()
+			if err != nil {
+				h.log.Printf("replaying event %d: %s\n", m.Seq, err.Error())
+				continue
+			}
+			pe := oe.published()
+			replayed[pe.ID] = true
+			if !sub.wants(pe.Event) {
+				continue
+			}
+			select {
+			case out <- pe:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(msgs) < replayBatchSize {
+			break
+		}
+	}
+
+	for pe := range sub.ch {
+		if replayed[pe.ID] {
+			continue
+		}
+		select {
+		case out <- pe:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Publish sends the event to its subscribers.
+// A subscriber whose buffer is full loses the event, so a slow subscriber can't block the publisher.
+// The events that are not relayed from the outbox are identified by the hub, after the last one it has published.
+func (h EventsHub) Publish(ev events.Event) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	oe, ok := ev.(OutboxEvent)
+	if !ok {
+		oe = OutboxEvent{Event: ev, Seq: h.seq + 1, CreatedAt: time.Now()}
+	}
+	if oe.Seq > h.seq {
+		h.seq = oe.Seq
+	}
+	pe := oe.published()
+	h.history = append(h.history, pe)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	for sub := range h.subs {
+		if !sub.wants(pe.Event) {
+			continue
+		}
+		select {
+		case sub.ch <- pe:
+		default:
+		}
+	}
+}
+
+// historyAfter returns the kept events published after the one with the given ID, and whether that one is kept.
+// When it's not kept, all the kept events are returned
+func (h *hub) historyAfter(ID string) ([]PublishedEvent, bool) {
+	if ID == "" {
+		return nil, false
+	}
+	for i := len(h.history) - 1; i >= 0; i-- {
+		if h.history[i].ID == ID {
+			return h.history[i+1:], true
+		}
+	}
+	return h.history, false
+}
+
+// published returns the event as it's published by the hub
+func (e OutboxEvent) published() PublishedEvent {
+	return PublishedEvent{ID: strconv.FormatInt(e.Seq, 10), Event: e.Event, OccurredAt: e.CreatedAt.UTC()}
+}
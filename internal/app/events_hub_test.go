@@ -0,0 +1,184 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+func TestEventsHub(t *testing.T) {
+	p := fixtures.Product{}.Build()
+
+	t.Run(`Given subscribers to some events, 
+		when an event is published, 
+		then only the subscribers to its name receive it`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		hub := app.NewEventsHub()
+		purchased := hub.Subscribe(ctx, "", domain.ProductPurchasedEventName)
+		refunded := hub.Subscribe(ctx, "", domain.ProductRefundedEventName)
+		all := hub.Subscribe(ctx, "")
+
+		ev := domain.NewProductPurchasedEvent(p, 1)
+		hub.Publish(ev)
+
+		require.Equal(t, ev, (<-purchased).Event)
+		require.Equal(t, ev, (<-all).Event)
+		require.Len(t, refunded, 0)
+	})
+
+	t.Run(`Given a subscriber, 
+		when its context is done, 
+		then its channel is closed`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		hub := app.NewEventsHub()
+		evs := hub.Subscribe(ctx, "")
+		cancel()
+
+		select {
+		case _, ok := <-evs:
+			require.False(t, ok)
+		case <-time.After(time.Second):
+			require.Fail(t, "the channel has not been closed")
+		}
+		hub.Publish(domain.NewProductPurchasedEvent(p, 1))
+	})
+
+	t.Run(`Given a subscriber that doesn't receive the events, 
+		when more events than its buffer are published, 
+		then the publisher is not blocked`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		hub := app.NewEventsHub()
+		evs := hub.Subscribe(ctx, "")
+		for i := 0; i < 100; i++ {
+			hub.Publish(domain.NewProductPurchasedEvent(p, 1))
+		}
+		require.NotEmpty(t, evs)
+	})
+
+	t.Run(`Given some published events, 
+		when a subscriber resumes from one of them, 
+		then it receives first the events published after that one`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub       = app.NewEventsHub()
+			first     = hub.Subscribe(ctx, "")
+			published = []events.Event{
+				domain.NewProductPurchasedEvent(p, 1),
+				domain.NewProductRefundedEvent(p, 1),
+				domain.NewProductPurchasedEvent(p, 2),
+			}
+		)
+		for _, ev := range published {
+			hub.Publish(ev)
+		}
+		lastReceived := <-first
+
+		resumed := hub.Subscribe(ctx, lastReceived.ID, domain.ProductPurchasedEventName)
+		require.Len(t, resumed, 1)
+		require.Equal(t, published[2], (<-resumed).Event)
+
+		resumed = hub.Subscribe(ctx, "unknown-1")
+		require.Len(t, resumed, len(published))
+
+		<-first
+		last := <-first
+		resumed = hub.Subscribe(ctx, last.ID)
+		require.Len(t, resumed, 0)
+	})
+
+	t.Run(`Given an event relayed from the outbox, 
+		when it's published, 
+		then it's identified by its position in the outbox and it occurred when it was stored`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub       = app.NewEventsHub()
+			evs       = hub.Subscribe(ctx, "")
+			createdAt = time.Now().Add(-time.Minute)
+			ev        = domain.NewProductPurchasedEvent(p, 1)
+		)
+		hub.Publish(app.OutboxEvent{Event: ev, Seq: 42, CreatedAt: createdAt})
+
+		pe := <-evs
+		require.Equal(t, "42", pe.ID)
+		require.Equal(t, ev, pe.Event)
+		require.True(t, createdAt.Equal(pe.OccurredAt))
+	})
+
+	t.Run(`Given a hub that reads the outbox, 
+		when a subscriber resumes from an event that is not kept, 
+		then it receives first the events stored in the outbox after that one, and then the published ones only once`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var msgs []app.OutboxMessage
+		for i, ev := range []events.Event{
+			domain.NewProductPurchasedEvent(p, 1),
+			domain.NewProductRefundedEvent(p, 1),
+			domain.NewProductPurchasedEvent(p, 2),
+		} {
+			m, err := app.NewOutboxMessage(ev)
+			require.NoError(t, err)
+			m.Seq, m.CreatedAt = int64(i+11), time.Now()
+			msgs = append(msgs, m)
+		}
+		var (
+			ob = &OutboxMock{
+				FindAfterFunc: func(_ context.Context, seq int64, _ int) ([]app.OutboxMessage, error) {
+					var after []app.OutboxMessage
+					for _, m := range msgs {
+						if m.Seq > seq {
+							after = append(after, m)
+						}
+					}
+					return after, nil
+				},
+			}
+			hub = app.NewOutboxEventsHub(&loggerMock{}, ob)
+		)
+
+		resumed := hub.Subscribe(ctx, "10", domain.ProductPurchasedEventName)
+		require.Equal(t, "11", (<-resumed).ID)
+		require.Equal(t, "13", (<-resumed).ID)
+
+		// An event already replayed is not received again
+		last, err := msgs[2].Event()
+		require.NoError(t, err)
+		hub.Publish(app.OutboxEvent{Event: last, Seq: 13, CreatedAt: msgs[2].CreatedAt})
+		hub.Publish(app.OutboxEvent{Event: domain.NewProductPurchasedEvent(p, 1), Seq: 14, CreatedAt: time.Now()})
+		require.Equal(t, "14", (<-resumed).ID)
+		require.Equal(t, int64(10), ob.FindAfterCalls()[0].Seq)
+	})
+
+	t.Run(`Given a hub that reads the outbox, 
+		when a subscriber resumes from a kept event, 
+		then the outbox is not read`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			ob  = &OutboxMock{}
+			hub = app.NewOutboxEventsHub(&loggerMock{}, ob)
+		)
+		hub.Publish(app.OutboxEvent{Event: domain.NewProductPurchasedEvent(p, 1), Seq: 1, CreatedAt: time.Now()})
+		hub.Publish(app.OutboxEvent{Event: domain.NewProductRefundedEvent(p, 1), Seq: 2, CreatedAt: time.Now()})
+
+		resumed := hub.Subscribe(ctx, "1")
+		require.Equal(t, "2", (<-resumed).ID)
+		require.Empty(t, ob.FindAfterCalls())
+	})
+}
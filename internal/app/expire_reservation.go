@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// ExpireReservationCmd is a command
+type ExpireReservationCmd struct {
+	ID uuid.UUID
+}
+
+// ExpireReservationName is self-described
+var ExpireReservationName = "expire.reservation"
+
+// Name implements the Command interface
+func (cmd ExpireReservationCmd) Name() string {
+	return ExpireReservationName
+}
+
+// ExpireReservation is a command handler
+type ExpireReservation struct {
+	pr ProductsRepository
+}
+
+// NewExpireReservation is a constructor
+func NewExpireReservation(pr ProductsRepository) ExpireReservation {
+	return ExpireReservation{pr: pr}
+}
+
+// Handle implements CommandHandler interface.
+// Nothing is done when the hold of the product has not expired, because it may have been
+// purchased or renewed since it was found expired.
+// If the product is modified concurrently, the expiration is tried again.
+func (ch ExpireReservation) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(ExpireReservationCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(ExpireReservationName, cmd.Name())
+	}
+
+	var evs []events.Event
+	err := retryOnConflict(func() error {
+		evs = nil
+		p, err := ch.pr.FindByID(ctx, co.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := releaseExpiredReservation(&p, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		evs = p.Events()
+		if len(evs) == 0 {
+			return nil
+		}
+		return ch.pr.Update(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
@@ -0,0 +1,113 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestExpireReservation(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		overdue   = fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(-time.Minute))}.Build()
+		held      = fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		sold      = fixtures.Product{Available: helpers.BoolPtr(false)}.Build()
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		cmd             cqrs.Command
+		expectedExpired bool
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Update, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.ExpireReservationCmd{ID: overdue.ID()},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return overdue, nil
+				},
+				UpdateFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a product purchased since its hold was found expired, 
+				when it's called, 
+				then nothing is done`,
+			cmd: app.ExpireReservationCmd{ID: sold.ID()},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return sold, nil
+				},
+			},
+		},
+		{
+			name: `Given a product whose hold has been renewed since it was found expired, 
+				when it's called, 
+				then nothing is done`,
+			cmd: app.ExpireReservationCmd{ID: held.ID()},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return held, nil
+				},
+			},
+		},
+		{
+			name: `Given a product whose hold has expired, 
+				when it's called, 
+				then it's put back on sale`,
+			cmd: app.ExpireReservationCmd{ID: overdue.ID()},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return overdue, nil
+				},
+			},
+			expectedExpired: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ch := app.NewExpireReservation(testCase.pr)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		if !testCase.expectedExpired {
+			require.Len(t, evs, 0, testCase.name)
+			require.Len(t, testCase.pr.UpdateCalls(), 0, testCase.name)
+			continue
+		}
+		require.Len(t, evs, 1, testCase.name)
+		require.Equal(t, domain.ProductReservationExpiredEventName, evs[0].Name(), testCase.name)
+		released := testCase.pr.UpdateCalls()[0].P
+		require.Equal(t, domain.StatusPublished, released.Status(), testCase.name)
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// IdempotentCommand is a command that is run only once for the same idempotency key
+type IdempotentCommand interface {
+	cqrs.Command
+	// IdempotencyKey returns the key given by the client. If it's empty, the command is not deduplicated
+	IdempotencyKey() string
+	// IdempotencyScope returns the client the key belongs to, so the clients can't see or block the keys of others
+	IdempotencyScope() string
+	// Fingerprint identifies the input of the command, so the same key can't be used with another input
+	Fingerprint() string
+}
+
+// IdempotencyRecord is an idempotency key already used by a command
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	CommandName string
+	Fingerprint string
+}
+
+// ErrIdempotencyKeyReused is self-described
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with another input")
+
+// MaxIdempotencyKeyLength is the max number of characters of an idempotency key
+const MaxIdempotencyKeyLength = 255
+
+// idempotencyNamespace is used to derive IDs from idempotency keys
+var idempotencyNamespace = uuid.MustParse("8f4b1c0e-6a7d-4c55-9b1e-3f2a0d9c7e61")
+
+// IDFromIdempotencyKey returns always the same ID for the same idempotency key of the same scope.
+// It's used to know the ID of the entities created by a command before it's run, even when it's a replay.
+func IDFromIdempotencyKey(scope, key string) uuid.UUID {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(scope+"\x00"+key))
+}
+
+// ChIdempotencyMw is a command handler middleware that runs an IdempotentCommand only once for the same key.
+// The keys are scoped by client, so the same key sent by two clients is two different keys.
+// The key is reserved in the same transaction the command is run, so it's only kept when the command succeeds.
+// A failed command doesn't change anything, so it can be retried with the same key.
+// A replay of a succeeded command returns no error and no events.
+func ChIdempotencyMw(store IdempotencyStore, tx Transactor) cqrs.CommandHandlerMiddleware {
+	return func(ch cqrs.CommandHandler) cqrs.CommandHandler {
+		return cqrs.CommandHandlerFunc(func(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+			ic, ok := cmd.(IdempotentCommand)
+			if !ok || ic.IdempotencyKey() == "" {
+				return ch.Handle(ctx, cmd)
+			}
+
+			record := IdempotencyRecord{
+				Scope:       ic.IdempotencyScope(),
+				Key:         ic.IdempotencyKey(),
+				CommandName: ic.Name(),
+				Fingerprint: ic.Fingerprint(),
+			}
+			var evs []events.Event
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				reserved, err := store.Reserve(ctx, record)
+				if err != nil {
+					return err
+				}
+				if !reserved {
+					return checkReplay(ctx, store, record)
+				}
+
+				evs, err = ch.Handle(ctx, cmd)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return evs, nil
+		})
+	}
+}
+
+func checkReplay(ctx context.Context, store IdempotencyStore, record IdempotencyRecord) error {
+	stored, err := store.FindByKey(ctx, record.Scope, record.Key)
+	if err != nil {
+		return err
+	}
+	if stored.CommandName != record.CommandName || stored.Fingerprint != record.Fingerprint {
+		return ErrIdempotencyKeyReused
+	}
+	return nil
+}
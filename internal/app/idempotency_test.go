@@ -0,0 +1,139 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+func TestChIdempotencyMw(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		cmd       = app.PurchaseProductCmd{ID: uuid.New(), Quantity: 1, Key: "key", Client: "principal:buyer"}
+		record    = app.IdempotencyRecord{Scope: cmd.Client, Key: cmd.Key, CommandName: cmd.Name(), Fingerprint: cmd.Fingerprint()}
+		ev        = events.NewEventBasic(uuid.New(), "event", nil)
+	)
+	testCases := []struct {
+		name            string
+		cmd             cqrs.Command
+		store           *IdempotencyStoreMock
+		chErr           error
+		expectedChCalls int
+		expectedEvents  int
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given a command without idempotency key, 
+				when it's handled, 
+				then it's run without reserving any key`,
+			cmd:             app.PurchaseProductCmd{ID: uuid.New()},
+			store:           &IdempotencyStoreMock{},
+			expectedChCalls: 1,
+			expectedEvents:  1,
+		},
+		{
+			name: `Given a command with a not used idempotency key, 
+				when it's handled, 
+				then the key is reserved and the command is run`,
+			cmd: cmd,
+			store: &IdempotencyStoreMock{
+				ReserveFunc: func(_ context.Context, _ app.IdempotencyRecord) (bool, error) {
+					return true, nil
+				},
+			},
+			expectedChCalls: 1,
+			expectedEvents:  1,
+		},
+		{
+			name: `Given a command with a not used idempotency key which fails, 
+				when it's handled, 
+				then the error is returned`,
+			cmd: cmd,
+			store: &IdempotencyStoreMock{
+				ReserveFunc: func(_ context.Context, _ app.IdempotencyRecord) (bool, error) {
+					return true, nil
+				},
+			},
+			chErr:           randomErr,
+			expectedChCalls: 1,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a command with an idempotency key already used with the same input, 
+				when it's handled, 
+				then it's not run again and no error is returned`,
+			cmd: cmd,
+			store: &IdempotencyStoreMock{
+				FindByKeyFunc: func(_ context.Context, _, _ string) (app.IdempotencyRecord, error) {
+					return record, nil
+				},
+			},
+		},
+		{
+			name: `Given a command with an idempotency key already used with another input, 
+				when it's handled, 
+				then it's not run and an error is returned`,
+			cmd: app.PurchaseProductCmd{ID: uuid.New(), Key: cmd.Key, Client: cmd.Client},
+			store: &IdempotencyStoreMock{
+				FindByKeyFunc: func(_ context.Context, _, _ string) (app.IdempotencyRecord, error) {
+					return record, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrIdempotencyKeyReused)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			ch = &CommandHandlerMock{
+				HandleFunc: func(_ context.Context, _ cqrs.Command) ([]events.Event, error) {
+					if tc.chErr != nil {
+						return nil, tc.chErr
+					}
+					return []events.Event{ev}, nil
+				},
+			}
+			tx = &TransactorMock{
+				WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				},
+			}
+		)
+		evs, err := app.ChIdempotencyMw(tc.store, tx)(ch).Handle(context.Background(), tc.cmd)
+		require.Len(t, ch.HandleCalls(), tc.expectedChCalls, tc.name)
+		for _, call := range tc.store.ReserveCalls() {
+			require.Equal(t, cmd.Client, call.R.Scope, tc.name)
+		}
+		for _, call := range tc.store.FindByKeyCalls() {
+			require.Equal(t, cmd.Client, call.Scope, tc.name)
+		}
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+		require.Len(t, evs, tc.expectedEvents, tc.name)
+	}
+}
+
+func TestIDFromIdempotencyKey(t *testing.T) {
+	t.Run(`Given an idempotency key, when an ID is derived from it twice, then the same ID is returned`, func(t *testing.T) {
+		require.Equal(t, app.IDFromIdempotencyKey("scope", "key"), app.IDFromIdempotencyKey("scope", "key"))
+		require.NotEqual(t, app.IDFromIdempotencyKey("scope", "key"), app.IDFromIdempotencyKey("scope", "another key"))
+	})
+
+	t.Run(`Given an idempotency key, when an ID is derived from it in two scopes, then different IDs are returned`, func(t *testing.T) {
+		require.NotEqual(t, app.IDFromIdempotencyKey("principal:a", "key"), app.IDFromIdempotencyKey("principal:b", "key"))
+	})
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// Order is a DTO
+type Order struct {
+	ID          uuid.UUID
+	ProductID   uuid.UUID
+	BuyerID     string
+	Price       domain.Money
+	Quantity    int
+	PurchasedAt time.Time
+	Refunded    int
+	RefundedAt  *time.Time
+}
+
+// NewOrderDTO builds an order DTO from an order entity
+func NewOrderDTO(o domain.Order) Order {
+	return Order{
+		ID:          o.ID(),
+		ProductID:   o.ProductID(),
+		BuyerID:     o.BuyerID(),
+		Price:       o.Price(),
+		Quantity:    o.Quantity(),
+		PurchasedAt: o.PurchasedAt(),
+		Refunded:    o.Refunded(),
+		RefundedAt:  o.RefundedAt(),
+	}
+}
+
+// OrdersResponse is a DTO
+type OrdersResponse []Order
+
+// OrdersQuery is a query
+type OrdersQuery struct{}
+
+// OrdersName is self-described
+var OrdersName = "orders"
+
+// Name implements Query interface
+func (q OrdersQuery) Name() string {
+	return OrdersName
+}
+
+// Orders is a query handler
+type Orders struct {
+	or OrdersRepository
+}
+
+// NewOrders is a constructor
+func NewOrders(or OrdersRepository) Orders {
+	return Orders{or: or}
+}
+
+// Handle implements the QueryHandler interface
+func (qh Orders) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	_, ok := query.(OrdersQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(OrdersName, query.Name())
+	}
+
+	o, err := qh.or.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []Order
+	for _, item := range o {
+		response = append(response, NewOrderDTO(item))
+	}
+
+	return response, nil
+}
+
+// OrderQuery is a query
+type OrderQuery struct {
+	ID uuid.UUID
+}
+
+// OrderName is self-described
+var OrderName = "order"
+
+// Name implements Query interface
+func (q OrderQuery) Name() string {
+	return OrderName
+}
+
+// OrderByID is a query handler
+type OrderByID struct {
+	or OrdersRepository
+}
+
+// NewOrderByID is a constructor
+func NewOrderByID(or OrdersRepository) OrderByID {
+	return OrderByID{or: or}
+}
+
+// Handle implements the QueryHandler interface
+func (qh OrderByID) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	q, ok := query.(OrderQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(OrderName, query.Name())
+	}
+
+	o, err := qh.or.FindByID(ctx, q.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOrderDTO(o), nil
+}
@@ -0,0 +1,88 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrders(t *testing.T) {
+	var (
+		randomErr   = errors.New("")
+		purchasedAt = time.Now()
+		order       = domain.NewOrder(uuid.New(), uuid.New(), "buyer", fixtures.Money("1.1"), 1, purchasedAt)
+	)
+
+	t.Run(`Given an invalid query, when it's called, then an error is returned`, func(t *testing.T) {
+		_, err := app.NewOrders(&OrdersRepositoryMock{}).Handle(context.Background(), newInvalidQuery())
+		require.ErrorAs(t, err, &app.InvalidQueryError{})
+	})
+
+	t.Run(`Given an orders repository that returns an error on FindAll, 
+			when it's called, 
+			then an error is returned`, func(t *testing.T) {
+		or := &OrdersRepositoryMock{
+			FindAllFunc: func(_ context.Context) ([]domain.Order, error) {
+				return nil, randomErr
+			},
+		}
+		_, err := app.NewOrders(or).Handle(context.Background(), app.OrdersQuery{})
+		require.ErrorIs(t, err, randomErr)
+	})
+
+	t.Run(`Given an orders repository that returns a list of orders on FindAll, 
+			when it's called, 
+			then the list of orders is returned`, func(t *testing.T) {
+		or := &OrdersRepositoryMock{
+			FindAllFunc: func(_ context.Context) ([]domain.Order, error) {
+				return []domain.Order{order}, nil
+			},
+		}
+		result, err := app.NewOrders(or).Handle(context.Background(), app.OrdersQuery{})
+		require.NoError(t, err)
+		require.Equal(t, []app.Order{app.NewOrderDTO(order)}, result)
+	})
+}
+
+func TestOrderByID(t *testing.T) {
+	order := domain.NewOrder(uuid.New(), uuid.New(), "buyer", fixtures.Money("1.1"), 1, time.Now())
+
+	t.Run(`Given an invalid query, when it's called, then an error is returned`, func(t *testing.T) {
+		_, err := app.NewOrderByID(&OrdersRepositoryMock{}).Handle(context.Background(), newInvalidQuery())
+		require.ErrorAs(t, err, &app.InvalidQueryError{})
+	})
+
+	t.Run(`Given an orders repository that returns not found on FindByID, 
+			when it's called, 
+			then an error is returned`, func(t *testing.T) {
+		or := &OrdersRepositoryMock{
+			FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Order, error) {
+				return domain.Order{}, app.ErrNotFound
+			},
+		}
+		_, err := app.NewOrderByID(or).Handle(context.Background(), app.OrderQuery{ID: order.ID()})
+		require.ErrorIs(t, err, app.ErrNotFound)
+	})
+
+	t.Run(`Given an orders repository that returns an order on FindByID, 
+			when it's called, 
+			then the order is returned`, func(t *testing.T) {
+		or := &OrdersRepositoryMock{
+			FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Order, error) {
+				return order, nil
+			},
+		}
+		result, err := app.NewOrderByID(or).Handle(context.Background(), app.OrderQuery{ID: order.ID()})
+		require.NoError(t, err)
+		require.Equal(t, app.NewOrderDTO(order), result)
+		require.Equal(t, order.ID(), or.FindByIDCalls()[0].ID)
+	})
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// OutboxMessage is a domain event stored in the outbox to be published
+type OutboxMessage struct {
+	ID          uuid.UUID
+	AggregateID uuid.UUID
+	EventName   string
+	Payload     []byte
+	// Seq is the position of the message in the stream of the delivered messages, and it's set when the message is delivered.
+	// CreatedAt is when the message was stored, and it's set when it's stored
+	Seq       int64
+	CreatedAt time.Time
+}
+
+// NewOutboxMessage is a constructor
+func NewOutboxMessage(ev events.Event) (OutboxMessage, error) {
+	payload, err := EncodeEvent(ev)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return OutboxMessage{
+		ID:          uuid.New(),
+		AggregateID: ev.AggregateID(),
+		EventName:   ev.Name(),
+		Payload:     payload,
+	}, nil
+}
+
+// Event rebuilds the domain event stored in the message
+func (m OutboxMessage) Event() (events.Event, error) {
+	return DecodeEvent(m.EventName, m.AggregateID, m.Payload)
+}
+
+// OutboxEvent is a domain event relayed from the outbox. It keeps the position of its message in the stream
+// and when it was stored, so its subscribers can identify it and know when it happened
+type OutboxEvent struct {
+	events.Event
+	Seq       int64
+	CreatedAt time.Time
+}
+
+// outboxEvent rebuilds the domain event stored in the message, with its position in the stream
+func (m OutboxMessage) outboxEvent() (OutboxEvent, error) {
+	ev, err := m.Event()
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{Event: ev, Seq: m.Seq, CreatedAt: m.CreatedAt}, nil
+}
+
+// ChOutboxMw is a command handler middleware that stores the events returned by the command handler in the outbox.
+// They're stored in the same transaction the command is run, so they're stored only if the command succeeds,
+// and they're not lost if the service stops before publishing them. The OutboxRelay publishes them.
+func ChOutboxMw(ob Outbox, tx Transactor) cqrs.CommandHandlerMiddleware {
+	return func(ch cqrs.CommandHandler) cqrs.CommandHandler {
+		return cqrs.CommandHandlerFunc(func(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+			var evs []events.Event
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				var err error
+				evs, err = ch.Handle(ctx, cmd)
+				if err != nil || len(evs) == 0 {
+					return err
+				}
+
+				msgs := make([]OutboxMessage, len(evs))
+				for i, ev := range evs {
+					if msgs[i], err = NewOutboxMessage(ev); err != nil {
+						return err
+					}
+				}
+				return ob.Save(ctx, msgs)
+			})
+			if err != nil {
+				return nil, err
+			}
+			return evs, nil
+		})
+	}
+}
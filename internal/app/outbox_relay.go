@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+const (
+	outboxRelayInterval  = time.Second
+	outboxRelayBatchSize = 100
+)
+
+// OutboxRelay publishes the events stored in the outbox to the events bus of the replica of the service it runs in.
+// It runs in every replica: the pending events are delivered from the outbox to a stream by one replica at a time,
+// and each replica publishes the events of the stream, so its subscribers receive the events whichever replica stored them.
+// The stream is read from the position of the last event published, and the positions are committed in order,
+// so an event committed after another one with a higher position is not missed.
+type OutboxRelay struct {
+	log       cqrs.Logger
+	ob        Outbox
+	tx        Transactor
+	eventsBus bus.Bus
+}
+
+// NewOutboxRelay is a constructor
+func NewOutboxRelay(log cqrs.Logger, ob Outbox, tx Transactor, eventsBus bus.Bus) OutboxRelay {
+	return OutboxRelay{log: log, ob: ob, tx: tx, eventsBus: eventsBus}
+}
+
+// Run delivers the pending events and publishes the events delivered after it started, periodically until the context is done
+func (r OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+
+	var (
+		seq     int64
+		started bool
+	)
+	for {
+		if !started {
+			var err error
+			if seq, err = r.ob.LastSeq(ctx); err != nil {
+				r.log.Printf("outbox relay: %s", err.Error())
+			}
+			started = err == nil
+		}
+
+		for {
+			n, err := r.Deliver(ctx)
+			if err != nil {
+				r.log.Printf("outbox relay: %s", err.Error())
+			}
+			if err != nil || n < outboxRelayBatchSize {
+				break
+			}
+		}
+
+		for started {
+			var (
+				n   int
+				err error
+			)
+			seq, n, err = r.Publish(ctx, seq)
+			if err != nil {
+				r.log.Printf("outbox relay: %s", err.Error())
+			}
+			if err != nil || n < outboxRelayBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver delivers a batch of pending events to the stream, and returns how many of them have been delivered.
+// None is delivered while another replica is delivering them.
+func (r OutboxRelay) Deliver(ctx context.Context) (int, error) {
+	var delivered int
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		delivered, err = r.ob.Deliver(ctx, outboxRelayBatchSize)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
+
+// Publish publishes a batch of the events delivered after the given position of the stream.
+// It returns the position of the last event of the batch, and how many events the batch has.
+// An event that fails to be published is logged and skipped, so it doesn't stop the stream.
+func (r OutboxRelay) Publish(ctx context.Context, seq int64) (int64, int, error) {
+	msgs, err := r.ob.FindAfter(ctx, seq, outboxRelayBatchSize)
+	if err != nil {
+		return seq, 0, err
+	}
+
+	for _, m := range msgs {
+		seq = m.Seq
+		if err := r.publish(ctx, m); err != nil {
+			r.log.Printf("outbox relay: message %s: %s", m.ID.String(), err.Error())
+		}
+	}
+	return seq, len(msgs), nil
+}
+
+func (r OutboxRelay) publish(ctx context.Context, m OutboxMessage) error {
+	ev, err := m.outboxEvent()
+	if err != nil {
+		return err
+	}
+	_, err = r.eventsBus.Dispatch(ctx, ev)
+	return err
+}
@@ -0,0 +1,151 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+)
+
+type loggerMock struct {
+	calls int
+}
+
+func (lm *loggerMock) Printf(string, ...interface{}) {
+	lm.calls++
+}
+
+func TestOutboxRelayDeliver(t *testing.T) {
+	randomErr := errors.New("")
+
+	testCases := []struct {
+		name              string
+		delivered         int
+		deliverErr        error
+		expectedDelivered int
+		expectedErr       error
+	}{
+		{
+			name: `Given an outbox that returns an error on Deliver, 
+				when the relay delivers the pending events, 
+				then the error is returned`,
+			deliverErr:  randomErr,
+			expectedErr: randomErr,
+		},
+		{
+			name: `Given an outbox with pending events, 
+				when the relay delivers them, 
+				then they're delivered in a transaction and how many of them is returned`,
+			delivered:         2,
+			expectedDelivered: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				ob = &OutboxMock{
+					DeliverFunc: func(_ context.Context, _ int) (int, error) {
+						return tc.delivered, tc.deliverErr
+					},
+				}
+				tx = &TransactorMock{
+					WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+						return fn(ctx)
+					},
+				}
+			)
+
+			delivered, err := app.NewOutboxRelay(&loggerMock{}, ob, tx, bus.New()).Deliver(context.Background())
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedDelivered, delivered)
+			require.Len(t, tx.WithinTxCalls(), 1)
+		})
+	}
+}
+
+func TestOutboxRelayPublish(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		p         = fixtures.Product{}.Build()
+	)
+	purchased, err := app.NewOutboxMessage(domain.NewProductPurchasedEvent(p, 1))
+	require.NoError(t, err)
+	purchased.Seq = 11
+	refunded, err := app.NewOutboxMessage(domain.NewProductRefundedEvent(p, 1))
+	require.NoError(t, err)
+	refunded.Seq = 13
+	unknown := app.OutboxMessage{ID: uuid.New(), EventName: "unknown", Payload: []byte("{}"), Seq: 12}
+
+	testCases := []struct {
+		name               string
+		msgs               []app.OutboxMessage
+		findErr            error
+		expectedSeq        int64
+		expectedRead       int
+		expectedDispatched []string
+		expectedLogCalls   int
+		expectedErr        error
+	}{
+		{
+			name: `Given an outbox that returns an error on FindAfter, 
+				when the relay publishes the delivered events, 
+				then the error is returned and the position is kept`,
+			findErr:     randomErr,
+			expectedSeq: 10,
+			expectedErr: randomErr,
+		},
+		{
+			name: `Given an outbox without events delivered after the position, 
+				when the relay publishes the delivered events, 
+				then nothing is published and the position is kept`,
+			expectedSeq: 10,
+		},
+		{
+			name: `Given an outbox with a delivered event that can't be published, 
+				when the relay publishes the delivered events, 
+				then the error is logged and the rest of events are published in order`,
+			msgs:               []app.OutboxMessage{purchased, unknown, refunded},
+			expectedSeq:        13,
+			expectedRead:       3,
+			expectedDispatched: []string{domain.ProductPurchasedEventName, domain.ProductRefundedEventName},
+			expectedLogCalls:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				dispatched []string
+				eventsBus  = bus.New()
+				handler    = func(_ context.Context, d bus.Dispatchable) (interface{}, error) {
+					dispatched = append(dispatched, d.Name())
+					return nil, nil
+				}
+				ob = &OutboxMock{
+					FindAfterFunc: func(_ context.Context, _ int64, _ int) ([]app.OutboxMessage, error) {
+						return tc.msgs, tc.findErr
+					},
+				}
+				lm = &loggerMock{}
+			)
+			eventsBus.Register(domain.ProductPurchasedEventName, handler)
+			eventsBus.Register(domain.ProductRefundedEventName, handler)
+
+			seq, read, err := app.NewOutboxRelay(lm, ob, &TransactorMock{}, eventsBus).Publish(context.Background(), 10)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedSeq, seq)
+			require.Equal(t, tc.expectedRead, read)
+			require.Equal(t, tc.expectedDispatched, dispatched)
+			require.Equal(t, tc.expectedLogCalls, lm.calls)
+			require.Equal(t, int64(10), ob.FindAfterCalls()[0].Seq)
+		})
+	}
+}
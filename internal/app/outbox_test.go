@@ -0,0 +1,136 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+func TestChOutboxMw(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		ev        = domain.NewProductPurchasedEvent(fixtures.Product{}.Build(), 2)
+	)
+	testCases := []struct {
+		name              string
+		ob                *OutboxMock
+		chEvents          []events.Event
+		chErr             error
+		expectedSaveCalls int
+		expectedErrFunc   func(*testing.T, error)
+	}{
+		{
+			name: `Given a command handler that returns an error, 
+				when it's handled, 
+				then no event is stored and the error is returned`,
+			ob:    &OutboxMock{},
+			chErr: randomErr,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a command handler that returns no events, 
+				when it's handled, 
+				then nothing is stored in the outbox`,
+			ob: &OutboxMock{},
+		},
+		{
+			name: `Given an outbox that returns an error, 
+				when it's handled, 
+				then the error is returned`,
+			ob: &OutboxMock{
+				SaveFunc: func(_ context.Context, _ []app.OutboxMessage) error {
+					return randomErr
+				},
+			},
+			chEvents:          []events.Event{ev},
+			expectedSaveCalls: 1,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a command handler that returns events, 
+				when it's handled, 
+				then the events are stored in the outbox`,
+			ob:                &OutboxMock{},
+			chEvents:          []events.Event{ev},
+			expectedSaveCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			ch = &CommandHandlerMock{
+				HandleFunc: func(_ context.Context, _ cqrs.Command) ([]events.Event, error) {
+					return tc.chEvents, tc.chErr
+				},
+			}
+			tx = &TransactorMock{
+				WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				},
+			}
+		)
+		evs, err := app.ChOutboxMw(tc.ob, tx)(ch).Handle(context.Background(), app.PurchaseProductCmd{})
+		require.Len(t, tx.WithinTxCalls(), 1, tc.name)
+		require.Len(t, tc.ob.SaveCalls(), tc.expectedSaveCalls, tc.name)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+		require.Equal(t, tc.chEvents, evs, tc.name)
+		if tc.expectedSaveCalls > 0 {
+			msgs := tc.ob.SaveCalls()[0].Msgs
+			require.Len(t, msgs, len(tc.chEvents), tc.name)
+			require.Equal(t, ev.Name(), msgs[0].EventName, tc.name)
+			require.Equal(t, ev.AggregateID(), msgs[0].AggregateID, tc.name)
+		}
+	}
+}
+
+func TestOutboxMessageEvent(t *testing.T) {
+	p := fixtures.Product{}.Build()
+	reservation := fixtures.Reservation("buyer", time.Now().Add(time.Hour))
+	reserved := fixtures.Product{Reservation: reservation}.Build()
+	history := []events.Event{
+		domain.NewProductCreatedEvent(p),
+		domain.NewProductPurchasedEvent(p, 2),
+		domain.NewProductRefundedEvent(p, 3),
+		domain.NewProductPriceChangedEvent(p, fixtures.Money("2.2")),
+		domain.NewProductRenamedEvent(p, "product2"),
+		domain.NewProductPublishedEvent(p),
+		domain.NewProductUnpublishedEvent(p),
+		domain.NewProductArchivedEvent(p),
+		domain.NewProductReservedEvent(p, *reservation),
+		domain.NewProductReservationExpiredEvent(reserved),
+	}
+	for _, ev := range history {
+		t.Run(`Given an outbox message built from a domain event, when the event is rebuilt, then it's the same event`, func(t *testing.T) {
+			m, err := app.NewOutboxMessage(ev)
+			require.NoError(t, err)
+
+			got, err := m.Event()
+			require.NoError(t, err)
+			require.Equal(t, ev, got)
+		})
+	}
+
+	t.Run(`Given an outbox message of an unknown event, when the event is rebuilt, then an error is returned`, func(t *testing.T) {
+		m := app.OutboxMessage{ID: uuid.New(), EventName: "unknown", Payload: []byte("{}")}
+		_, err := m.Event()
+		require.ErrorIs(t, err, domain.ErrUnknownEvent)
+	})
+}
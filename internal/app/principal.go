@@ -0,0 +1,56 @@
+package app
+
+import "context"
+
+// Principal is the authenticated caller of the service
+type Principal struct {
+	// ID identifies the caller. For the buyers, it's their buyer ID
+	ID    string
+	Roles []string
+}
+
+// HasRole is self-described
+func (p Principal) HasRole(role string) bool {
+	return hasRole(p.Roles, role)
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedRoles are the roles that are only given by the service itself
+var reservedRoles = []string{RoleSystem}
+
+// CallerPrincipal returns the principal of a caller authenticated with the given roles.
+// The reserved roles are stripped, so a token or an API key can't claim them.
+func CallerPrincipal(ID string, roles []string) Principal {
+	p := Principal{ID: ID}
+	for _, r := range roles {
+		if !hasRole(reservedRoles, r) {
+			p.Roles = append(p.Roles, r)
+		}
+	}
+	return p
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of the context that carries the principal
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal carried by the context.
+// The second returned value is false when the caller has not been authenticated.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if ctx == nil {
+		return Principal{}, false
+	}
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
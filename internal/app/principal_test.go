@@ -0,0 +1,36 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	t.Run(`Given a context without principal, when it's read, then there is no principal`, func(t *testing.T) {
+		_, ok := app.PrincipalFromContext(context.Background())
+		require.False(t, ok)
+		_, ok = app.PrincipalFromContext(nil)
+		require.False(t, ok)
+	})
+
+	t.Run(`Given a context with a principal, when it's read, then the principal is returned`, func(t *testing.T) {
+		principal := app.Principal{ID: "buyer", Roles: []string{"buyer"}}
+		got, ok := app.PrincipalFromContext(app.ContextWithPrincipal(context.Background(), principal))
+		require.True(t, ok)
+		require.Equal(t, principal, got)
+		require.True(t, got.HasRole("buyer"))
+		require.False(t, got.HasRole("admin"))
+	})
+}
+
+func TestCallerPrincipal(t *testing.T) {
+	t.Run(`Given the roles of a caller with a reserved one, when its principal is built, then the reserved role is stripped`, func(t *testing.T) {
+		principal := app.CallerPrincipal("buyer", []string{app.RoleSystem, app.RoleBuyer})
+		require.Equal(t, app.Principal{ID: "buyer", Roles: []string{app.RoleBuyer}}, principal)
+		require.False(t, principal.HasRole(app.RoleSystem))
+	})
+}
@@ -2,6 +2,9 @@ package app
 
 import (
 	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
 
 	"github.com/google/uuid"
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
@@ -12,14 +15,24 @@ type Product struct {
 	ID        uuid.UUID
 	Name      string
 	Available bool
-	Price     float64
+	// Price is nil when the product has no price
+	Price *domain.Money
+	// Quantity is the number of units left
+	Quantity int
+	Version  int
+	Status   domain.Status
+	// ReservedUntil is the time the hold of the product expires. It's nil when the product is not reserved
+	ReservedUntil *time.Time
 }
 
 // ProductsResponse is a DTO
 type ProductsResponse []Product
 
-// ProductsQuery is a query
-type ProductsQuery struct{}
+// ProductsQuery is a query. The zero value returns all the products sorted by ID
+type ProductsQuery struct {
+	Filter ProductFilter
+	Order  ProductOrder
+}
 
 // ProductsName is self-described
 var ProductsName = "products"
@@ -41,25 +54,84 @@ func NewProducts(pr ProductsRepository) Products {
 
 // Handle implements the QueryHandler interface
 func (qh Products) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
-	_, ok := query.(ProductsQuery)
+	q, ok := query.(ProductsQuery)
 	if !ok {
 		return nil, NewInvalidQueryError(ProductsName, query.Name())
 	}
+	if err := q.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	if err := q.Order.Validate(); err != nil {
+		return nil, err
+	}
 
-	p, err := qh.pr.FindAll(ctx)
+	p, err := qh.pr.FindMatching(ctx, q.Filter, q.Order)
 	if err != nil {
 		return nil, err
 	}
 
 	var response []Product
 	for _, item := range p {
-		response = append(response, Product{
-			ID:        item.ID(),
-			Name:      item.Name(),
-			Available: item.Available(),
-			Price:     item.Price(),
-		})
+		response = append(response, NewProductDTO(item))
 	}
 
 	return response, nil
 }
+
+// ProductQuery is a query
+type ProductQuery struct {
+	ID uuid.UUID
+}
+
+// ProductName is self-described
+var ProductName = "product"
+
+// Name implements Query interface
+func (q ProductQuery) Name() string {
+	return ProductName
+}
+
+// ProductByID is a query handler
+type ProductByID struct {
+	pr ProductsRepository
+}
+
+// NewProductByID is a constructor
+func NewProductByID(pr ProductsRepository) ProductByID {
+	return ProductByID{pr: pr}
+}
+
+// Handle implements the QueryHandler interface
+func (qh ProductByID) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	q, ok := query.(ProductQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(ProductName, query.Name())
+	}
+
+	p, err := qh.pr.FindByID(ctx, q.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProductDTO(p), nil
+}
+
+// NewProductDTO builds a product DTO from a product entity
+func NewProductDTO(p domain.Product) Product {
+	dto := Product{
+		ID:        p.ID(),
+		Name:      p.Name(),
+		Available: p.Available(),
+		Quantity:  p.Stock(),
+		Version:   p.Version(),
+		Status:    p.Status(),
+	}
+	if price, ok := p.Price(); ok {
+		dto.Price = &price
+	}
+	if r, ok := p.Reservation(); ok {
+		expiresAt := r.ExpiresAt()
+		dto.ReservedUntil = &expiresAt
+	}
+	return dto
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+const (
+	// DefaultPageSize is the number of products of a page when its size is not given
+	DefaultPageSize = 20
+	// MaxPageSize is the max number of products of a page
+	MaxPageSize = 100
+)
+
+// ErrInvalidCursor is self-described
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidPageSize is self-described
+var ErrInvalidPageSize = errors.New("invalid page size")
+
+// ProductsPageRequest selects a page of the products that match Filter, sorted by Order.
+// The page has the first Limit products after After, or the last Limit products before Before when FromEnd is true.
+// The products After and Before are not included in the page.
+type ProductsPageRequest struct {
+	Filter  ProductFilter
+	Order   ProductOrder
+	After   *ProductCursor
+	Before  *ProductCursor
+	Limit   int
+	FromEnd bool
+}
+
+// ProductCursor is the position of a product in the products sorted by Field.
+// Key is the value of the field for the product, or nil when the product has no value for it,
+// and ID is the tiebreaker. When the products are sorted by ID, Field is empty and Key is nil.
+type ProductCursor struct {
+	ID    uuid.UUID
+	Field ProductOrderField
+	Key   *string
+}
+
+// NewProductCursor returns the position of the product in the products sorted by the field
+func NewProductCursor(p domain.Product, field ProductOrderField) ProductCursor {
+	c := ProductCursor{ID: p.ID(), Field: field}
+	switch field {
+	case ProductOrderByName:
+		name := p.Name()
+		c.Key = &name
+	case ProductOrderByPrice:
+		if price, ok := p.Price(); ok {
+			amount := price.Amount()
+			c.Key = &amount
+		}
+	case ProductOrderByQuantity:
+		stock := strconv.Itoa(p.Stock())
+		c.Key = &stock
+	default:
+		c.Field = ""
+	}
+	return c
+}
+
+const productCursorPrefix = "product:"
+
+// EncodeProductCursor returns the opaque cursor of a product in the products sorted by ID
+func EncodeProductCursor(ID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(productCursorPrefix + ID.String()))
+}
+
+// Encode returns the opaque cursor. The cursors of the products sorted by ID only have their IDs
+func (c ProductCursor) Encode() string {
+	if c.Field == "" {
+		return EncodeProductCursor(c.ID)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(append([]byte(productCursorPrefix), b...))
+}
+
+// DecodeProductCursor returns the position of the product of the cursor
+func DecodeProductCursor(cursor string) (ProductCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), productCursorPrefix) {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+	b = b[len(productCursorPrefix):]
+
+	if ID, err := uuid.Parse(string(b)); err == nil {
+		return ProductCursor{ID: ID}, nil
+	}
+	var c ProductCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+	if c.Field == "" || c.Field == ProductOrderByID || (ProductOrder{Field: c.Field}).Validate() != nil {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ProductEdge is a DTO
+type ProductEdge struct {
+	Cursor string
+	Node   Product
+}
+
+// PageInfo is a DTO
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     string
+	EndCursor       string
+}
+
+// ProductsConnection is a DTO. It's a page of products as Relay connections are
+type ProductsConnection struct {
+	Edges    []ProductEdge
+	PageInfo PageInfo
+}
+
+// ProductsConnectionQuery is a query. It follows the Relay cursor connections specification:
+// First and After select the products after a cursor, and Last and Before the ones before a cursor.
+// The products are filtered by Filter and sorted by Order, and a cursor is only valid for the field it was sorted by.
+type ProductsConnectionQuery struct {
+	Filter ProductFilter
+	Order  ProductOrder
+	First  *int
+	After  string
+	Last   *int
+	Before string
+}
+
+// ProductsConnectionName is self-described
+var ProductsConnectionName = "productsConnection"
+
+// Name implements Query interface
+func (q ProductsConnectionQuery) Name() string {
+	return ProductsConnectionName
+}
+
+// pageRequest validates the query and returns the page request it's asking for
+func (q ProductsConnectionQuery) pageRequest() (ProductsPageRequest, error) {
+	if q.First != nil && q.Last != nil {
+		return ProductsPageRequest{}, fmt.Errorf("%w: first and last can't be used together", ErrInvalidPageSize)
+	}
+
+	if err := q.Filter.Validate(); err != nil {
+		return ProductsPageRequest{}, err
+	}
+	if err := q.Order.Validate(); err != nil {
+		return ProductsPageRequest{}, err
+	}
+
+	var (
+		req = ProductsPageRequest{Filter: q.Filter, Order: q.Order, Limit: DefaultPageSize}
+		err error
+	)
+	switch {
+	case q.First != nil:
+		req.Limit = *q.First
+	case q.Last != nil:
+		req.Limit = *q.Last
+		req.FromEnd = true
+	}
+	if req.Limit < 0 || req.Limit > MaxPageSize {
+		return ProductsPageRequest{}, fmt.Errorf("%w: it must be between 0 and %d", ErrInvalidPageSize, MaxPageSize)
+	}
+
+	if req.After, err = q.optionalCursor(q.After); err != nil {
+		return ProductsPageRequest{}, err
+	}
+	if req.Before, err = q.optionalCursor(q.Before); err != nil {
+		return ProductsPageRequest{}, err
+	}
+	return req, nil
+}
+
+// optionalCursor decodes the cursor, which must have been returned for the same order field
+func (q ProductsConnectionQuery) optionalCursor(cursor string) (*ProductCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	c, err := DecodeProductCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if c.Field != q.sortField() {
+		return nil, fmt.Errorf("%w: it was not returned for the products sorted by %s", ErrInvalidCursor, q.sortField())
+	}
+	return &c, nil
+}
+
+// sortField is the field the products are sorted by, as it's kept in their cursors
+func (q ProductsConnectionQuery) sortField() ProductOrderField {
+	if q.Order.Field == ProductOrderByID {
+		return ""
+	}
+	return q.Order.Field
+}
+
+// ProductsConnectionHandler is a query handler
+type ProductsConnectionHandler struct {
+	pr ProductsRepository
+}
+
+// NewProductsConnection is a constructor
+func NewProductsConnection(pr ProductsRepository) ProductsConnectionHandler {
+	return ProductsConnectionHandler{pr: pr}
+}
+
+// Handle implements the QueryHandler interface
+func (qh ProductsConnectionHandler) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	q, ok := query.(ProductsConnectionQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(ProductsConnectionName, query.Name())
+	}
+
+	req, err := q.pageRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// One more product is asked for to know if there are more products after the page
+	limit := req.Limit
+	req.Limit++
+	products, err := qh.pr.FindPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(products) > limit
+	if hasMore {
+		if req.FromEnd {
+			products = products[1:]
+		} else {
+			products = products[:limit]
+		}
+	}
+
+	var response ProductsConnection
+	for _, p := range products {
+		cursor := NewProductCursor(p, q.sortField()).Encode()
+		response.Edges = append(response.Edges, ProductEdge{Cursor: cursor, Node: NewProductDTO(p)})
+	}
+	if len(response.Edges) > 0 {
+		response.PageInfo.StartCursor = response.Edges[0].Cursor
+		response.PageInfo.EndCursor = response.Edges[len(response.Edges)-1].Cursor
+	}
+	// When paginating forward, there are products before the page if it starts after a cursor, and the other way around
+	if req.FromEnd {
+		response.PageInfo.HasPreviousPage = hasMore
+		response.PageInfo.HasNextPage = req.Before != nil
+	} else {
+		response.PageInfo.HasNextPage = hasMore
+		response.PageInfo.HasPreviousPage = req.After != nil
+	}
+
+	return response, nil
+}
@@ -0,0 +1,231 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestProductCursor(t *testing.T) {
+	t.Run(`Given a product ID, when its cursor is decoded, then the same ID is returned`, func(t *testing.T) {
+		ID := uuid.New()
+		decoded, err := app.DecodeProductCursor(app.EncodeProductCursor(ID))
+		require.NoError(t, err)
+		require.Equal(t, app.ProductCursor{ID: ID}, decoded)
+	})
+
+	t.Run(`Given the positions of products sorted by a field, 
+		when their cursors are decoded, 
+		then the same positions are returned`, func(t *testing.T) {
+		var (
+			priced   = fixtures.Product{}.Build()
+			unpriced = fixtures.Product{NoPrice: true}.Build()
+		)
+		for _, c := range []app.ProductCursor{
+			app.NewProductCursor(priced, app.ProductOrderByPrice),
+			app.NewProductCursor(unpriced, app.ProductOrderByPrice),
+			app.NewProductCursor(priced, app.ProductOrderByName),
+			app.NewProductCursor(priced, app.ProductOrderByQuantity),
+			app.NewProductCursor(priced, app.ProductOrderByID),
+		} {
+			decoded, err := app.DecodeProductCursor(c.Encode())
+			require.NoError(t, err)
+			require.Equal(t, c, decoded)
+		}
+		require.Equal(t, "1.10", *app.NewProductCursor(priced, app.ProductOrderByPrice).Key)
+		require.Nil(t, app.NewProductCursor(unpriced, app.ProductOrderByPrice).Key)
+	})
+
+	t.Run(`Given an invalid cursor, when it's decoded, then an error is returned`, func(t *testing.T) {
+		unknownField := app.ProductCursor{ID: uuid.New(), Field: "UNKNOWN"}.Encode()
+		for _, cursor := range []string{"!", "b3JkZXI6MQ", app.EncodeProductCursor(uuid.Nil)[:10], unknownField} {
+			_, err := app.DecodeProductCursor(cursor)
+			require.ErrorIs(t, err, app.ErrInvalidCursor, cursor)
+		}
+	})
+}
+
+func TestProductsConnection(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		products  = []domain.Product{
+			fixtures.Product{}.Build(),
+			fixtures.Product{}.Build(),
+			fixtures.Product{}.Build(),
+		}
+		cursor      = app.EncodeProductCursor(products[0].ID())
+		priceCursor = app.NewProductCursor(products[0], app.ProductOrderByPrice)
+		byPrice     = app.ProductOrder{Field: app.ProductOrderByPrice, Direction: app.OrderDesc}
+		available   = app.ProductFilter{Available: helpers.BoolPtr(true)}
+		prices      = []domain.Money{fixtures.Money("1"), fixtures.Money("2")}
+	)
+	testCases := []struct {
+		name             string
+		query            cqrs.Query
+		found            []domain.Product
+		expectedRequest  app.ProductsPageRequest
+		expectedNodes    []domain.Product
+		expectedPageInfo app.PageInfo
+		expectedErrFunc  func(*testing.T, error)
+	}{
+		{
+			name:  `Given an invalid query, when it's called, then an error is returned`,
+			query: newInvalidQuery(),
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidQueryError{})
+			},
+		},
+		{
+			name:  `Given a query with first and last, when it's called, then an error is returned`,
+			query: app.ProductsConnectionQuery{First: helpers.IntPtr(1), Last: helpers.IntPtr(1)},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidPageSize)
+			},
+		},
+		{
+			name:  `Given a query with a too big page size, when it's called, then an error is returned`,
+			query: app.ProductsConnectionQuery{First: helpers.IntPtr(app.MaxPageSize + 1)},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidPageSize)
+			},
+		},
+		{
+			name:  `Given a query with an invalid cursor, when it's called, then an error is returned`,
+			query: app.ProductsConnectionQuery{After: "invalid"},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidCursor)
+			},
+		},
+		{
+			name:  `Given a query with an invalid filter, when it's called, then an error is returned`,
+			query: app.ProductsConnectionQuery{Filter: app.ProductFilter{MinPrice: &prices[1], MaxPrice: &prices[0]}},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductFilter)
+			},
+		},
+		{
+			name:  `Given a query with an invalid order, when it's called, then an error is returned`,
+			query: app.ProductsConnectionQuery{Order: app.ProductOrder{Field: "UNKNOWN"}},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductOrder)
+			},
+		},
+		{
+			name: `Given a query with a cursor returned for another order, 
+				when it's called, 
+				then an error is returned`,
+			query: app.ProductsConnectionQuery{Order: byPrice, After: cursor},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidCursor)
+			},
+		},
+		{
+			name: `Given a query of the products that match a filter sorted by price after a cursor, 
+				when it's called, 
+				then the filter, the order and the position of the cursor are asked for, and the cursors of the page have their prices`,
+			query:           app.ProductsConnectionQuery{Filter: available, Order: byPrice, First: helpers.IntPtr(2), After: priceCursor.Encode()},
+			found:           products[1:],
+			expectedRequest: app.ProductsPageRequest{Filter: available, Order: byPrice, After: &priceCursor, Limit: 3},
+			expectedNodes:   products[1:],
+			expectedPageInfo: app.PageInfo{
+				HasPreviousPage: true,
+				StartCursor:     app.NewProductCursor(products[1], app.ProductOrderByPrice).Encode(),
+				EndCursor:       app.NewProductCursor(products[2], app.ProductOrderByPrice).Encode(),
+			},
+		},
+		{
+			name:            `Given a products repository that returns an error, when it's called, then an error is returned`,
+			query:           app.ProductsConnectionQuery{},
+			expectedRequest: app.ProductsPageRequest{Limit: app.DefaultPageSize + 1},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a query of the first products, 
+				when there are more products than the asked ones, 
+				then the first ones are returned and there is a next page`,
+			query:           app.ProductsConnectionQuery{First: helpers.IntPtr(2)},
+			found:           products,
+			expectedRequest: app.ProductsPageRequest{Limit: 3},
+			expectedNodes:   products[:2],
+			expectedPageInfo: app.PageInfo{
+				HasNextPage: true,
+				StartCursor: app.EncodeProductCursor(products[0].ID()),
+				EndCursor:   app.EncodeProductCursor(products[1].ID()),
+			},
+		},
+		{
+			name: `Given a query of the products after a cursor, 
+				when there are not more products than the asked ones, 
+				then they're returned and there is a previous page`,
+			query:           app.ProductsConnectionQuery{First: helpers.IntPtr(2), After: cursor},
+			found:           products[1:],
+			expectedRequest: app.ProductsPageRequest{After: &app.ProductCursor{ID: products[0].ID()}, Limit: 3},
+			expectedNodes:   products[1:],
+			expectedPageInfo: app.PageInfo{
+				HasPreviousPage: true,
+				StartCursor:     app.EncodeProductCursor(products[1].ID()),
+				EndCursor:       app.EncodeProductCursor(products[2].ID()),
+			},
+		},
+		{
+			name: `Given a query of the last products before a cursor, 
+				when there are more products than the asked ones, 
+				then the last ones are returned and there are a previous and a next page`,
+			query:           app.ProductsConnectionQuery{Last: helpers.IntPtr(2), Before: cursor},
+			found:           products,
+			expectedRequest: app.ProductsPageRequest{Before: &app.ProductCursor{ID: products[0].ID()}, Limit: 3, FromEnd: true},
+			expectedNodes:   products[1:],
+			expectedPageInfo: app.PageInfo{
+				HasPreviousPage: true,
+				HasNextPage:     true,
+				StartCursor:     app.EncodeProductCursor(products[1].ID()),
+				EndCursor:       app.EncodeProductCursor(products[2].ID()),
+			},
+		},
+		{
+			name:             `Given a query, when there are no products, then an empty page is returned`,
+			query:            app.ProductsConnectionQuery{},
+			expectedRequest:  app.ProductsPageRequest{Limit: app.DefaultPageSize + 1},
+			expectedPageInfo: app.PageInfo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		pr := &ProductsRepositoryMock{
+			FindPageFunc: func(_ context.Context, _ app.ProductsPageRequest) ([]domain.Product, error) {
+				if tc.expectedErrFunc != nil {
+					return nil, randomErr
+				}
+				return tc.found, nil
+			},
+		}
+		result, err := app.NewProductsConnection(pr).Handle(context.Background(), tc.query)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+
+		require.Len(t, pr.FindPageCalls(), 1, tc.name)
+		require.Equal(t, tc.expectedRequest, pr.FindPageCalls()[0].Req, tc.name)
+		page := result.(app.ProductsConnection)
+		require.Len(t, page.Edges, len(tc.expectedNodes), tc.name)
+		for i, p := range tc.expectedNodes {
+			order := tc.query.(app.ProductsConnectionQuery).Order
+			require.Equal(t, app.NewProductCursor(p, order.Field).Encode(), page.Edges[i].Cursor, tc.name)
+			require.Equal(t, app.NewProductDTO(p), page.Edges[i].Node, tc.name)
+		}
+		require.Equal(t, tc.expectedPageInfo, page.PageInfo, tc.name)
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidProductFilter is self-described
+var ErrInvalidProductFilter = errors.New("invalid product filter")
+
+// ErrInvalidProductOrder is self-described
+var ErrInvalidProductOrder = errors.New("invalid product order")
+
+// ProductFilter selects the products which match all of its criteria. The zero value selects all the products.
+type ProductFilter struct {
+	Available *bool
+	// MinPrice and MaxPrice are inclusive. The products without price, or priced in another currency, don't match them.
+	MinPrice *domain.Money
+	MaxPrice *domain.Money
+	// NameContains matches the products whose name contains it, ignoring the case
+	NameContains string
+	// IDs matches the products with any of these IDs. When it's nil, the products are not filtered by ID.
+	IDs []uuid.UUID
+}
+
+// Validate is self-described
+func (f ProductFilter) Validate() error {
+	if f.MinPrice == nil || f.MaxPrice == nil {
+		return nil
+	}
+	if f.MinPrice.Currency() != f.MaxPrice.Currency() {
+		return fmt.Errorf("%w: min and max prices are in different currencies", ErrInvalidProductFilter)
+	}
+	if f.MaxPrice.LessThan(*f.MinPrice) {
+		return fmt.Errorf("%w: min price is greater than max price", ErrInvalidProductFilter)
+	}
+	return nil
+}
+
+// ProductOrderField is a field the products can be sorted by
+type ProductOrderField string
+
+const (
+	// ProductOrderByID is the default order
+	ProductOrderByID ProductOrderField = "ID"
+	// ProductOrderByName is self-described
+	ProductOrderByName ProductOrderField = "NAME"
+	// ProductOrderByPrice sorts the products without price last
+	ProductOrderByPrice ProductOrderField = "PRICE"
+	// ProductOrderByQuantity sorts by the number of units left
+	ProductOrderByQuantity ProductOrderField = "QUANTITY"
+)
+
+// OrderDirection is self-described
+type OrderDirection string
+
+const (
+	// OrderAsc is self-described
+	OrderAsc OrderDirection = "ASC"
+	// OrderDesc is self-described
+	OrderDesc OrderDirection = "DESC"
+)
+
+// ProductOrder sorts the products. The zero value sorts them by ID in ascending order.
+type ProductOrder struct {
+	Field     ProductOrderField
+	Direction OrderDirection
+}
+
+// Validate is self-described
+func (o ProductOrder) Validate() error {
+	switch o.Field {
+	case "", ProductOrderByID, ProductOrderByName, ProductOrderByPrice, ProductOrderByQuantity:
+	default:
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidProductOrder, o.Field)
+	}
+	switch o.Direction {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return fmt.Errorf("%w: unknown direction %q", ErrInvalidProductOrder, o.Direction)
+	}
+	return nil
+}
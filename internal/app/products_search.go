@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// ErrInvalidSearchText is self-described
+var ErrInvalidSearchText = errors.New("invalid search text")
+
+// MaxSearchResults is the max number of hits of a search that can be walked through. The DB ranks and skips all the hits
+// before a page, so the pages after them are not served.
+const MaxSearchResults = 1000
+
+// ProductsSearchRequest asks for Limit products matching the text, skipping the first Offset ones
+type ProductsSearchRequest struct {
+	Text   string
+	Offset int
+	Limit  int
+}
+
+// ProductSearchHit is a product that matches a search.
+// The hits are sorted by their rank, the most relevant first.
+type ProductSearchHit struct {
+	Product domain.Product
+	Rank    float64
+	// Snippet is the matching text of the product, HTML-escaped, with the matching words wrapped in <mark> tags.
+	// So it's safe to be inserted as HTML
+	Snippet string
+}
+
+const searchCursorPrefix = "search:"
+
+// The cursors of a search are positions in its results, since the ranking of the products has no stable key.
+// So a page after a cursor may skip or repeat products when the catalog changes in the meantime.
+func encodeSearchCursor(position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(searchCursorPrefix + strconv.Itoa(position)))
+}
+
+func decodeSearchCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), searchCursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	position, err := strconv.Atoi(strings.TrimPrefix(string(b), searchCursorPrefix))
+	if err != nil || position < 0 {
+		return 0, ErrInvalidCursor
+	}
+	if position >= MaxSearchResults-1 {
+		return 0, fmt.Errorf("%w: only the first %d results can be walked through", ErrInvalidCursor, MaxSearchResults)
+	}
+	return position, nil
+}
+
+// ProductSearchEdge is a DTO
+type ProductSearchEdge struct {
+	Cursor  string
+	Node    Product
+	Rank    float64
+	Snippet string
+}
+
+// ProductsSearchConnection is a DTO
+type ProductsSearchConnection struct {
+	Edges    []ProductSearchEdge
+	PageInfo PageInfo
+}
+
+// SearchProductsQuery is a query. It returns the First products that match the text after the After cursor,
+// ranked by relevance. The text can use the web search syntax: quoted phrases, "or" and "-" to exclude words.
+type SearchProductsQuery struct {
+	Text  string
+	First *int
+	After string
+}
+
+// SearchProductsName is self-described
+var SearchProductsName = "searchProducts"
+
+// Name implements Query interface
+func (q SearchProductsQuery) Name() string {
+	return SearchProductsName
+}
+
+// searchRequest validates the query and returns the search request it's asking for
+func (q SearchProductsQuery) searchRequest() (ProductsSearchRequest, error) {
+	req := ProductsSearchRequest{Text: strings.TrimSpace(q.Text), Limit: DefaultPageSize}
+	if req.Text == "" {
+		return ProductsSearchRequest{}, fmt.Errorf("%w: it's empty", ErrInvalidSearchText)
+	}
+	if q.First != nil {
+		req.Limit = *q.First
+	}
+	if req.Limit < 0 || req.Limit > MaxPageSize {
+		return ProductsSearchRequest{}, fmt.Errorf("%w: it must be between 0 and %d", ErrInvalidPageSize, MaxPageSize)
+	}
+	if q.After != "" {
+		position, err := decodeSearchCursor(q.After)
+		if err != nil {
+			return ProductsSearchRequest{}, err
+		}
+		req.Offset = position + 1
+	}
+	if req.Offset+req.Limit > MaxSearchResults {
+		req.Limit = MaxSearchResults - req.Offset
+	}
+	return req, nil
+}
+
+// SearchProducts is a query handler
+type SearchProducts struct {
+	pr ProductsRepository
+}
+
+// NewSearchProducts is a constructor
+func NewSearchProducts(pr ProductsRepository) SearchProducts {
+	return SearchProducts{pr: pr}
+}
+
+// Handle implements the QueryHandler interface
+func (qh SearchProducts) Handle(ctx context.Context, query cqrs.Query) (cqrs.QueryResult, error) {
+	q, ok := query.(SearchProductsQuery)
+	if !ok {
+		return nil, NewInvalidQueryError(SearchProductsName, query.Name())
+	}
+
+	req, err := q.searchRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// One more product is asked for to know if there are more products after the page
+	limit := req.Limit
+	req.Limit++
+	hits, err := qh.pr.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+
+	var response ProductsSearchConnection
+	for i, hit := range hits {
+		response.Edges = append(response.Edges, ProductSearchEdge{
+			Cursor:  encodeSearchCursor(req.Offset + i),
+			Node:    NewProductDTO(hit.Product),
+			Rank:    hit.Rank,
+			Snippet: hit.Snippet,
+		})
+	}
+	if len(response.Edges) > 0 {
+		response.PageInfo.StartCursor = response.Edges[0].Cursor
+		response.PageInfo.EndCursor = response.Edges[len(response.Edges)-1].Cursor
+	}
+	response.PageInfo.HasNextPage = hasMore && req.Offset+limit < MaxSearchResults
+	response.PageInfo.HasPreviousPage = req.Offset > 0
+
+	return response, nil
+}
@@ -0,0 +1,184 @@
+package app_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestSearchProducts(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		hits      = []app.ProductSearchHit{
+			{Product: fixtures.Product{}.Build(), Rank: 0.9, Snippet: "<mark>red</mark> shoes"},
+			{Product: fixtures.Product{}.Build(), Rank: 0.5, Snippet: "<mark>red</mark> hat"},
+			{Product: fixtures.Product{}.Build(), Rank: 0.1, Snippet: "<mark>red</mark> scarf"},
+		}
+	)
+
+	// The cursors are got from a first search, since they're opaque
+	pr := &ProductsRepositoryMock{
+		SearchFunc: func(_ context.Context, _ app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+			return hits, nil
+		},
+	}
+	result, err := app.NewSearchProducts(pr).Handle(context.Background(), app.SearchProductsQuery{Text: "red", First: helpers.IntPtr(2)})
+	require.NoError(t, err)
+	firstPage := result.(app.ProductsSearchConnection)
+
+	testCases := []struct {
+		name             string
+		query            cqrs.Query
+		found            []app.ProductSearchHit
+		expectedRequest  app.ProductsSearchRequest
+		expectedHits     []app.ProductSearchHit
+		expectedPageInfo app.PageInfo
+		expectedErrFunc  func(*testing.T, error)
+	}{
+		{
+			name:  `Given an invalid query, when it's called, then an error is returned`,
+			query: newInvalidQuery(),
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidQueryError{})
+			},
+		},
+		{
+			name:  `Given a query with a blank text, when it's called, then an error is returned`,
+			query: app.SearchProductsQuery{Text: "  "},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidSearchText)
+			},
+		},
+		{
+			name:  `Given a query with a too big page size, when it's called, then an error is returned`,
+			query: app.SearchProductsQuery{Text: "red", First: helpers.IntPtr(app.MaxPageSize + 1)},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidPageSize)
+			},
+		},
+		{
+			name:  `Given a query with a cursor of another connection, when it's called, then an error is returned`,
+			query: app.SearchProductsQuery{Text: "red", After: app.EncodeProductCursor(hits[0].Product.ID())},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidCursor)
+			},
+		},
+		{
+			name:            `Given a products repository that returns an error, when it's called, then an error is returned`,
+			query:           app.SearchProductsQuery{Text: "red"},
+			expectedRequest: app.ProductsSearchRequest{Text: "red", Limit: app.DefaultPageSize + 1},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a search, 
+				when there are more hits than the asked ones, 
+				then the first ones are returned and there is a next page`,
+			query:           app.SearchProductsQuery{Text: " red ", First: helpers.IntPtr(2)},
+			found:           hits,
+			expectedRequest: app.ProductsSearchRequest{Text: "red", Limit: 3},
+			expectedHits:    hits[:2],
+			expectedPageInfo: app.PageInfo{
+				HasNextPage: true,
+				StartCursor: firstPage.Edges[0].Cursor,
+				EndCursor:   firstPage.Edges[1].Cursor,
+			},
+		},
+		{
+			name: `Given a search after a cursor, 
+				when there are not more hits than the asked ones, 
+				then the hits after the cursor are returned and there is a previous page`,
+			query:           app.SearchProductsQuery{Text: "red", First: helpers.IntPtr(2), After: firstPage.PageInfo.EndCursor},
+			found:           hits[2:],
+			expectedRequest: app.ProductsSearchRequest{Text: "red", Offset: 2, Limit: 3},
+			expectedHits:    hits[2:],
+			expectedPageInfo: app.PageInfo{
+				HasPreviousPage: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		pr := &ProductsRepositoryMock{
+			SearchFunc: func(_ context.Context, _ app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+				if tc.expectedErrFunc != nil {
+					return nil, randomErr
+				}
+				return tc.found, nil
+			},
+		}
+		result, err := app.NewSearchProducts(pr).Handle(context.Background(), tc.query)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+
+		require.Len(t, pr.SearchCalls(), 1, tc.name)
+		require.Equal(t, tc.expectedRequest, pr.SearchCalls()[0].Req, tc.name)
+		page := result.(app.ProductsSearchConnection)
+		require.Len(t, page.Edges, len(tc.expectedHits), tc.name)
+		for i, hit := range tc.expectedHits {
+			require.Equal(t, app.NewProductDTO(hit.Product), page.Edges[i].Node, tc.name)
+			require.Equal(t, hit.Rank, page.Edges[i].Rank, tc.name)
+			require.Equal(t, hit.Snippet, page.Edges[i].Snippet, tc.name)
+		}
+		require.Equal(t, tc.expectedPageInfo.HasNextPage, page.PageInfo.HasNextPage, tc.name)
+		require.Equal(t, tc.expectedPageInfo.HasPreviousPage, page.PageInfo.HasPreviousPage, tc.name)
+		if tc.expectedPageInfo.StartCursor != "" {
+			require.Equal(t, tc.expectedPageInfo.StartCursor, page.PageInfo.StartCursor, tc.name)
+			require.Equal(t, tc.expectedPageInfo.EndCursor, page.PageInfo.EndCursor, tc.name)
+		}
+	}
+
+	t.Run(`Given a page after a cursor, 
+			when the next page is asked for after its end cursor, 
+			then the search goes on after the last hit of that page`, func(t *testing.T) {
+		pr := &ProductsRepositoryMock{
+			SearchFunc: func(_ context.Context, _ app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+				return hits[2:], nil
+			},
+		}
+		sh := app.NewSearchProducts(pr)
+		result, err := sh.Handle(context.Background(), app.SearchProductsQuery{Text: "red", After: firstPage.PageInfo.EndCursor})
+		require.NoError(t, err)
+		secondPage := result.(app.ProductsSearchConnection)
+		require.NotEqual(t, firstPage.PageInfo.EndCursor, secondPage.PageInfo.EndCursor)
+
+		_, err = sh.Handle(context.Background(), app.SearchProductsQuery{Text: "red", After: secondPage.PageInfo.EndCursor})
+		require.NoError(t, err)
+		require.Equal(t, 3, pr.SearchCalls()[1].Req.Offset)
+	})
+
+	t.Run(`Given a cursor at the last results that can be walked through, 
+			when the next page is asked for, 
+			then only the results up to the max are asked for and there is no next page`, func(t *testing.T) {
+		pr := &ProductsRepositoryMock{
+			SearchFunc: func(_ context.Context, _ app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+				return hits, nil
+			},
+		}
+		cursor := base64.RawURLEncoding.EncodeToString([]byte("search:" + strconv.Itoa(app.MaxSearchResults-2)))
+		result, err := app.NewSearchProducts(pr).Handle(context.Background(), app.SearchProductsQuery{Text: "red", After: cursor})
+		require.NoError(t, err)
+		page := result.(app.ProductsSearchConnection)
+		require.Len(t, page.Edges, 1)
+		require.False(t, page.PageInfo.HasNextPage)
+		require.Equal(t, app.ProductsSearchRequest{Text: "red", Offset: app.MaxSearchResults - 1, Limit: 2}, pr.SearchCalls()[0].Req)
+
+		// The cursor of the last result that can be walked through is rejected
+		_, err = app.NewSearchProducts(pr).Handle(context.Background(), app.SearchProductsQuery{Text: "red", After: page.PageInfo.EndCursor})
+		require.ErrorIs(t, err, app.ErrInvalidCursor)
+		require.Len(t, pr.SearchCalls(), 1)
+	})
+}
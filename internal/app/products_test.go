@@ -28,17 +28,24 @@ func TestProducts(t *testing.T) {
 		randomErr      = errors.New("")
 		ids            = []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
 		names          = []string{"product1", "product2", "product3"}
-		prices         = []float64{1.1, 2.2, 3.3}
+		prices         = []domain.Money{fixtures.Money("1.1"), fixtures.Money("2.2"), fixtures.Money("3.3")}
 		availabilities = []bool{true, true, false}
+		quantities     = []int{1, 1, 0}
+		statuses       = []domain.Status{domain.StatusPublished, domain.StatusPublished, domain.StatusSold}
 		products       = []domain.Product{
 			fixtures.Product{ID: helpers.UUIDPtr(ids[0]), Name: &names[0], Available: &availabilities[0], Price: &prices[0]}.Build(),
 			fixtures.Product{ID: helpers.UUIDPtr(ids[1]), Name: &names[1], Available: &availabilities[1], Price: &prices[1]}.Build(),
 			fixtures.Product{ID: helpers.UUIDPtr(ids[2]), Name: &names[2], Available: &availabilities[2], Price: &prices[2]}.Build(),
 		}
 		response = []app.Product{
-			{ID: ids[0], Name: names[0], Available: availabilities[0], Price: prices[0]},
-			{ID: ids[1], Name: names[1], Available: availabilities[1], Price: prices[1]},
-			{ID: ids[2], Name: names[2], Available: availabilities[2], Price: prices[2]},
+			{ID: ids[0], Name: names[0], Available: availabilities[0], Price: &prices[0], Quantity: quantities[0], Version: 1, Status: statuses[0]},
+			{ID: ids[1], Name: names[1], Available: availabilities[1], Price: &prices[1], Quantity: quantities[1], Version: 1, Status: statuses[1]},
+			{ID: ids[2], Name: names[2], Available: availabilities[2], Price: &prices[2], Quantity: quantities[2], Version: 1, Status: statuses[2]},
+		}
+		dollars, _ = domain.ParseMoney("2.2", "USD")
+		query      = app.ProductsQuery{
+			Filter: app.ProductFilter{Available: &availabilities[0], MinPrice: &prices[0], MaxPrice: &prices[2], NameContains: "prod", IDs: ids},
+			Order:  app.ProductOrder{Field: app.ProductOrderByPrice, Direction: app.OrderDesc},
 		}
 	)
 	testCases := []struct {
@@ -51,17 +58,58 @@ func TestProducts(t *testing.T) {
 		{
 			name:  `Given an invalid query, when it's called, then an error is returned`,
 			query: newInvalidQuery(),
+			pr:    &ProductsRepositoryMock{},
 			expectedErrFunc: func(t *testing.T, err error) {
 				require.ErrorAs(t, err, &app.InvalidQueryError{})
 			},
 		},
 		{
-			name: `Given a products repository that returns an error on FindAll, 
+			name: `Given a query with a min price greater than its max price, 
+				when it's called, 
+				then an error is returned`,
+			query: app.ProductsQuery{Filter: app.ProductFilter{MinPrice: &prices[1], MaxPrice: &prices[0]}},
+			pr:    &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductFilter)
+			},
+		},
+		{
+			name: `Given a query with min and max prices in different currencies, 
+				when it's called, 
+				then an error is returned`,
+			query: app.ProductsQuery{Filter: app.ProductFilter{MinPrice: &prices[0], MaxPrice: &dollars}},
+			pr:    &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductFilter)
+			},
+		},
+		{
+			name: `Given a query with an unknown order field, 
+				when it's called, 
+				then an error is returned`,
+			query: app.ProductsQuery{Order: app.ProductOrder{Field: "COLOR"}},
+			pr:    &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductOrder)
+			},
+		},
+		{
+			name: `Given a query with an unknown order direction, 
+				when it's called, 
+				then an error is returned`,
+			query: app.ProductsQuery{Order: app.ProductOrder{Field: app.ProductOrderByPrice, Direction: "UP"}},
+			pr:    &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrInvalidProductOrder)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on FindMatching, 
 				when it's called, 
 				then an error is returned`,
 			query: app.ProductsQuery{},
 			pr: &ProductsRepositoryMock{
-				FindAllFunc: func(_ context.Context) ([]domain.Product, error) {
+				FindMatchingFunc: func(_ context.Context, _ app.ProductFilter, _ app.ProductOrder) ([]domain.Product, error) {
 					return nil, randomErr
 				},
 			},
@@ -70,12 +118,25 @@ func TestProducts(t *testing.T) {
 			},
 		},
 		{
-			name: `Given a products repository that returns a list of products on FindAll, 
+			name: `Given a products repository that returns a list of products on FindMatching, 
 				when it's called, 
 				then the list of products are returned`,
 			query: app.ProductsQuery{},
 			pr: &ProductsRepositoryMock{
-				FindAllFunc: func(_ context.Context) ([]domain.Product, error) {
+				FindMatchingFunc: func(_ context.Context, _ app.ProductFilter, _ app.ProductOrder) ([]domain.Product, error) {
+					return products, nil
+				},
+			},
+		},
+		{
+			name: `Given a query with a filter and an order, 
+				when it's called, 
+				then they're passed to the products repository`,
+			query: query,
+			pr: &ProductsRepositoryMock{
+				FindMatchingFunc: func(_ context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error) {
+					require.Equal(t, query.Filter, filter)
+					require.Equal(t, query.Order, order)
 					return products, nil
 				},
 			},
@@ -85,13 +146,48 @@ func TestProducts(t *testing.T) {
 	for _, testCase := range testCases {
 		ch := app.NewProducts(testCase.pr)
 		result, err := ch.Handle(context.Background(), testCase.query)
-		require.Equal(t, testCase.expectedErrFunc == nil, err == nil)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
 		if err != nil {
 			testCase.expectedErrFunc(t, err)
 			continue
 		}
 
-		require.Len(t, testCase.pr.FindAllCalls(), 1)
-		require.Equal(t, response, result)
+		require.Len(t, testCase.pr.FindMatchingCalls(), 1, testCase.name)
+		require.Equal(t, response, result, testCase.name)
 	}
 }
+
+func TestProductByID(t *testing.T) {
+	product := fixtures.Product{}.Build()
+
+	t.Run(`Given an invalid query, when it's called, then an error is returned`, func(t *testing.T) {
+		_, err := app.NewProductByID(&ProductsRepositoryMock{}).Handle(context.Background(), newInvalidQuery())
+		require.ErrorAs(t, err, &app.InvalidQueryError{})
+	})
+
+	t.Run(`Given a products repository that returns not found on FindByID, 
+			when it's called, 
+			then an error is returned`, func(t *testing.T) {
+		pr := &ProductsRepositoryMock{
+			FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+				return domain.Product{}, app.ErrNotFound
+			},
+		}
+		_, err := app.NewProductByID(pr).Handle(context.Background(), app.ProductQuery{ID: product.ID()})
+		require.ErrorIs(t, err, app.ErrNotFound)
+	})
+
+	t.Run(`Given a products repository that returns a product on FindByID, 
+			when it's called, 
+			then the product is returned`, func(t *testing.T) {
+		pr := &ProductsRepositoryMock{
+			FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+				return product, nil
+			},
+		}
+		result, err := app.NewProductByID(pr).Handle(context.Background(), app.ProductQuery{ID: product.ID()})
+		require.NoError(t, err)
+		require.Equal(t, app.NewProductDTO(product), result)
+		require.Equal(t, product.ID(), pr.FindByIDCalls()[0].ID)
+	})
+}
@@ -2,6 +2,10 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
 
 	"github.com/google/uuid"
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
@@ -11,6 +15,20 @@ import (
 // PurchaseProductCmd is a command
 type PurchaseProductCmd struct {
 	ID uuid.UUID
+	// Quantity is the number of units to purchase. If it's not provided, one unit is purchased
+	Quantity int
+	// OrderID is the ID of the order to be created. If it's not provided, a new one is generated
+	OrderID uuid.UUID
+	// Key is the idempotency key. It's optional
+	Key string
+	// Client identifies the client that sends the command: the principal, or the IP of the anonymous ones.
+	// The idempotency keys are scoped by it
+	Client string
+	// Holder is the session token the product is held for. It's only needed when the product is reserved,
+	// and it's ignored when the buyer is authenticated
+	Holder string
+	// BuyerID is the ID of the authenticated buyer. It's empty for the anonymous purchases
+	BuyerID string
 }
 
 // PurchaseProductName is self-described
@@ -21,35 +39,99 @@ func (cmd PurchaseProductCmd) Name() string {
 	return PurchaseProductName
 }
 
+// IdempotencyKey implements the IdempotentCommand interface
+func (cmd PurchaseProductCmd) IdempotencyKey() string {
+	return cmd.Key
+}
+
+// IdempotencyScope implements the IdempotentCommand interface
+func (cmd PurchaseProductCmd) IdempotencyScope() string {
+	return cmd.Client
+}
+
+// Fingerprint implements the IdempotentCommand interface.
+// The order ID is not part of it, since it's derived from the key and not given by the client.
+// The buyer is part of it, so a key can't be used to get the response of the purchase of another buyer.
+func (cmd PurchaseProductCmd) Fingerprint() string {
+	fingerprint := fmt.Sprintf("%s:%d", cmd.ID, cmd.Quantity)
+	if cmd.BuyerID != "" {
+		fingerprint += ":" + cmd.BuyerID
+	}
+	return fingerprint
+}
+
+// holder returns the holder of the reservation the purchase is done for
+func (cmd PurchaseProductCmd) holder() string {
+	return reservationHolder(cmd.BuyerID, cmd.Holder)
+}
+
 // PurchaseProduct is a command handler
 type PurchaseProduct struct {
 	pr ProductsRepository
+	or OrdersRepository
+	tx Transactor
 }
 
 // NewPurchaseProduct is a constructor
-func NewPurchaseProduct(pr ProductsRepository) PurchaseProduct {
-	return PurchaseProduct{pr: pr}
+func NewPurchaseProduct(pr ProductsRepository, or OrdersRepository, tx Transactor) PurchaseProduct {
+	return PurchaseProduct{pr: pr, or: or, tx: tx}
 }
 
-// Handle implements CommandHandler interface
+// Handle implements CommandHandler interface.
+// A reserved product can only be purchased by its holder, unless its hold has expired.
+// The product update and the order creation are done in the same transaction. The product is locked meanwhile,
+// so the concurrent purchases of a product are done one after the other while there is stock.
+// If the product is modified concurrently by a change that doesn't lock it, the purchase is tried again.
 func (ch PurchaseProduct) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
 	co, ok := cmd.(PurchaseProductCmd)
 	if !ok {
 		return nil, NewInvalidCommandError(PurchaseProductName, cmd.Name())
 	}
 
-	p, err := ch.pr.FindByID(ctx, co.ID)
-	if err != nil {
-		return nil, err
+	orderID := co.OrderID
+	if orderID == uuid.Nil {
+		orderID = uuid.New()
 	}
 
-	if err := p.Purchase(); err != nil {
-		return nil, err
+	quantity := co.Quantity
+	if quantity == 0 {
+		quantity = 1
 	}
 
-	if err := ch.pr.UpdateAvailable(ctx, p); err != nil {
+	var evs []events.Event
+	err := retryOnConflict(func() error {
+		return ch.tx.WithinTx(ctx, func(ctx context.Context) error {
+			p, err := ch.pr.FindByIDForUpdate(ctx, co.ID)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now().UTC()
+			if err := releaseExpiredReservation(&p, now); err != nil {
+				return err
+			}
+
+			if err := p.PurchaseBy(co.holder(), quantity); err != nil {
+				return err
+			}
+
+			if err := ch.pr.UpdateStock(ctx, p); err != nil {
+				return err
+			}
+
+			price, _ := p.Price() // a product without price can't be purchased
+			order := domain.NewOrder(orderID, p.ID(), co.BuyerID, price, quantity, now)
+			if err := ch.or.Insert(ctx, order); err != nil {
+				return err
+			}
+
+			evs = p.Events()
+			return nil
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return p.Events(), nil
+	return evs, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/domain"
@@ -27,10 +28,13 @@ func TestPurchaseProduct(t *testing.T) {
 	var (
 		randomErr = errors.New("")
 		product   = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		reserved  = fixtures.Product{Reservation: fixtures.Reservation(domain.BuyerHolder("buyer"), time.Now().Add(time.Hour))}.Build()
+		session   = fixtures.Product{Reservation: fixtures.Reservation(domain.SessionHolder("session token"), time.Now().Add(time.Hour))}.Build()
 	)
 	testCases := []struct {
 		name              string
 		pr                *ProductsRepositoryMock
+		or                *OrdersRepositoryMock
 		cmd               cqrs.Command
 		expectedPurchased bool
 		expectedErrFunc   func(*testing.T, error)
@@ -43,12 +47,12 @@ func TestPurchaseProduct(t *testing.T) {
 			},
 		},
 		{
-			name: `Given a products repository that returns an error on FindByID, 
+			name: `Given a products repository that returns an error on FindByIDForUpdate, 
 				when it's called, 
 				then an error is returned`,
 			cmd: app.PurchaseProductCmd{},
 			pr: &ProductsRepositoryMock{
-				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
 					return domain.Product{}, randomErr
 				},
 			},
@@ -62,10 +66,10 @@ func TestPurchaseProduct(t *testing.T) {
 				then an error is returned`,
 			cmd: app.PurchaseProductCmd{},
 			pr: &ProductsRepositoryMock{
-				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
 					return product, nil
 				},
-				UpdateAvailableFunc: func(ctx context.Context, p domain.Product) error {
+				UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
 					return randomErr
 				},
 			},
@@ -73,18 +77,137 @@ func TestPurchaseProduct(t *testing.T) {
 				require.ErrorIs(t, err, randomErr)
 			},
 		},
+		{
+			name: `Given an orders repository that returns an error on Insert, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.PurchaseProductCmd{},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return product, nil
+				},
+				UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
+					return nil
+				},
+			},
+			or: &OrdersRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Order) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a product held for another buyer, 
+				when it's purchased, 
+				then an error is returned`,
+			cmd: app.PurchaseProductCmd{BuyerID: "another buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return reserved, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a product held for a buyer, 
+				when an anonymous caller purchases it giving the ID of the buyer as holder, 
+				then an error is returned`,
+			cmd: app.PurchaseProductCmd{Holder: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return reserved, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a product held for a buyer, 
+				when another buyer purchases it giving the ID of the buyer as holder, 
+				then an error is returned`,
+			cmd: app.PurchaseProductCmd{Holder: "buyer", BuyerID: "another buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return reserved, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a product held for a session token, 
+				when an anonymous caller purchases it giving the session token as holder, 
+				then no error is returned and an order is created`,
+			cmd: app.PurchaseProductCmd{
+				ID:       session.ID(),
+				OrderID:  uuid.New(),
+				Quantity: 1,
+				Holder:   "session token",
+			},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return session, nil
+				},
+				UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
+					return nil
+				},
+			},
+			or: &OrdersRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Order) error {
+					return nil
+				},
+			},
+		},
+		{
+			name: `Given a product held for the authenticated buyer, 
+				when the buyer purchases it, 
+				then no error is returned and an order of the buyer is created`,
+			cmd: app.PurchaseProductCmd{
+				ID:       reserved.ID(),
+				OrderID:  uuid.New(),
+				Quantity: 1,
+				BuyerID:  "buyer",
+			},
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return reserved, nil
+				},
+				UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
+					return nil
+				},
+			},
+			or: &OrdersRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Order) error {
+					return nil
+				},
+			},
+		},
 		{
 			name: `Given an available product, 
 				when it's purchased, 
-				then no error is returned`,
+				then no error is returned and an order is created`,
 			cmd: app.PurchaseProductCmd{
-				ID: uuid.New(),
+				ID:       uuid.New(),
+				OrderID:  uuid.New(),
+				Quantity: 1,
 			},
 			pr: &ProductsRepositoryMock{
-				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
 					return product, nil
 				},
-				UpdateAvailableFunc: func(ctx context.Context, p domain.Product) error {
+				UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
+					return nil
+				},
+			},
+			or: &OrdersRepositoryMock{
+				InsertFunc: func(_ context.Context, _ domain.Order) error {
 					return nil
 				},
 			},
@@ -92,7 +215,12 @@ func TestPurchaseProduct(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		ch := app.NewPurchaseProduct(testCase.pr)
+		tx := &TransactorMock{
+			WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+				return fn(ctx)
+			},
+		}
+		ch := app.NewPurchaseProduct(testCase.pr, testCase.or, tx)
 		_, err := ch.Handle(context.Background(), testCase.cmd)
 		require.Equal(t, testCase.expectedErrFunc == nil, err == nil)
 		if err != nil {
@@ -100,7 +228,80 @@ func TestPurchaseProduct(t *testing.T) {
 			continue
 		}
 
-		require.Len(t, testCase.pr.FindByIDCalls(), 1)
-		require.Equal(t, testCase.pr.FindByIDCalls()[0].ID, testCase.cmd.(app.PurchaseProductCmd).ID)
+		cmd := testCase.cmd.(app.PurchaseProductCmd)
+		require.Len(t, tx.WithinTxCalls(), 1)
+		require.Len(t, testCase.pr.FindByIDForUpdateCalls(), 1)
+		require.Equal(t, testCase.pr.FindByIDForUpdateCalls()[0].ID, cmd.ID)
+		require.Len(t, testCase.or.InsertCalls(), 1)
+		order := testCase.or.InsertCalls()[0].O
+		require.Equal(t, cmd.OrderID, order.ID())
+		require.Equal(t, cmd.BuyerID, order.BuyerID())
+		require.Equal(t, cmd.Quantity, order.Quantity())
+		price, _ := product.Price()
+		require.Equal(t, price, order.Price())
 	}
 }
+
+func TestPurchaseProductRetriesOnConflict(t *testing.T) {
+	t.Run(`Given a product which is modified concurrently, 
+			when it's purchased, 
+			then the purchase is tried again`, func(t *testing.T) {
+		var (
+			product = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+			pr      = &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return product, nil
+				},
+			}
+			or = &OrdersRepositoryMock{}
+			tx = &TransactorMock{
+				WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				},
+			}
+		)
+		pr.UpdateStockFunc = func(_ context.Context, _ domain.Product) error {
+			if len(pr.UpdateStockCalls()) == 1 {
+				return app.ErrVersionConflict
+			}
+			return nil
+		}
+
+		_, err := app.NewPurchaseProduct(pr, or, tx).Handle(context.Background(), app.PurchaseProductCmd{ID: product.ID()})
+		require.NoError(t, err)
+		require.Len(t, pr.FindByIDForUpdateCalls(), 2)
+		require.Len(t, or.InsertCalls(), 1)
+	})
+
+	t.Run(`Given a product which is always modified concurrently, 
+			when it's purchased, 
+			then a version conflict error is returned`, func(t *testing.T) {
+		var (
+			product = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+			pr      = &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return product, nil
+				},
+				UpdateStockFunc: func(_ context.Context, _ domain.Product) error {
+					return app.ErrVersionConflict
+				},
+			}
+			tx = &TransactorMock{
+				WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				},
+			}
+		)
+
+		_, err := app.NewPurchaseProduct(pr, &OrdersRepositoryMock{}, tx).Handle(context.Background(), app.PurchaseProductCmd{ID: product.ID()})
+		require.ErrorIs(t, err, app.ErrVersionConflict)
+		require.Len(t, pr.FindByIDForUpdateCalls(), 3)
+	})
+}
+
+func TestPurchaseProductCmdFingerprint(t *testing.T) {
+	cmd := app.PurchaseProductCmd{ID: uuid.New(), Quantity: 1, OrderID: uuid.New(), Key: "key"}
+	anotherBuyer := cmd
+	anotherBuyer.BuyerID = "another buyer"
+	require.NotEqual(t, cmd.Fingerprint(), anotherBuyer.Fingerprint())
+}
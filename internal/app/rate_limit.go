@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRateLimit is self-described
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+// RateLimit is the budget of requests of a client. It's a bucket of Requests tokens,
+// which is refilled at a steady pace, so it's full again after Per
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRateLimit parses a rate limit like "60/1m", which allows bursts of 60 requests and 60 requests per minute
+func ParseRateLimit(s string) (RateLimit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%w %q: it must be like 60/1m", ErrInvalidRateLimit, s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("%w %q: the requests must be a positive number", ErrInvalidRateLimit, s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("%w %q: the period must be a positive duration", ErrInvalidRateLimit, s)
+	}
+	return RateLimit{Requests: n, Per: d}, nil
+}
+
+// refillRate returns the tokens added to the bucket per second
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimitDecision is the outcome of taking a token of the bucket of a client
+type RateLimitDecision struct {
+	Allowed bool
+	// Remaining is the number of requests the client can still do at once
+	Remaining int
+	// RetryAfter is the time until there is a token for the client, when it's not been allowed
+	RetryAfter time.Duration
+}
+
+// TokenBucket is the state of the bucket of a client
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewTokenBucket returns a full bucket
+func NewTokenBucket(limit RateLimit, now time.Time) TokenBucket {
+	return TokenBucket{Tokens: float64(limit.Requests), UpdatedAt: now}
+}
+
+// Take refills the bucket with the tokens accrued since it was updated, and takes a token if there is one
+func (b TokenBucket) Take(limit RateLimit, now time.Time) (TokenBucket, RateLimitDecision) {
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		// The clock of another replica can be a bit ahead
+		elapsed = 0
+	}
+	tokens := math.Min(float64(limit.Requests), b.Tokens+elapsed*limit.refillRate())
+
+	if tokens < 1 {
+		retryAfter := time.Duration((1 - tokens) / limit.refillRate() * float64(time.Second))
+		return TokenBucket{Tokens: tokens, UpdatedAt: now}, RateLimitDecision{RetryAfter: retryAfter}
+	}
+	tokens--
+	return TokenBucket{Tokens: tokens, UpdatedAt: now}, RateLimitDecision{Allowed: true, Remaining: int(tokens)}
+}
+
+// FullAt returns when the bucket is full again if no token is taken
+func (b TokenBucket) FullAt(limit RateLimit) time.Time {
+	missing := float64(limit.Requests) - b.Tokens
+	return b.UpdatedAt.Add(time.Duration(missing / limit.refillRate() * float64(time.Second)))
+}
+
+// RateLimiter keeps the buckets of the clients
+type RateLimiter interface {
+	// Take takes a token of the bucket of the client identified by the key
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitDecision, error)
+}
+
+// rateLimitPruneInterval is how often the full buckets are dropped
+const rateLimitPruneInterval = time.Minute
+
+type memoryBucket struct {
+	bucket TokenBucket
+	fullAt time.Time
+}
+
+// MemoryRateLimiter keeps the buckets in memory, so each replica of the service has its own ones.
+// The buckets that are full again are dropped, since they're the same as a new one.
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	prunedAt  time.Time
+	pruneEach time.Duration
+}
+
+// NewMemoryRateLimiter is a constructor
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{buckets: make(map[string]memoryBucket), pruneEach: rateLimitPruneInterval}
+}
+
+// Take implements the RateLimiter interface
+func (rl *MemoryRateLimiter) Take(_ context.Context, key string, limit RateLimit, now time.Time) (RateLimitDecision, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.prune(now)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b.bucket = NewTokenBucket(limit, now)
+	}
+	bucket, decision := b.bucket.Take(limit, now)
+	rl.buckets[key] = memoryBucket{bucket: bucket, fullAt: bucket.FullAt(limit)}
+	return decision, nil
+}
+
+// Len returns the number of buckets kept
+func (rl *MemoryRateLimiter) Len() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.buckets)
+}
+
+func (rl *MemoryRateLimiter) prune(now time.Time) {
+	if now.Sub(rl.prunedAt) < rl.pruneEach {
+		return
+	}
+	rl.prunedAt = now
+	for key, b := range rl.buckets {
+		if !now.Before(b.fullAt) {
+			delete(rl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		s             string
+		expected      app.RateLimit
+		expectedError bool
+	}{
+		{
+			name:     `Given a valid rate limit, when it's parsed, then it's returned`,
+			s:        "60/1m",
+			expected: app.RateLimit{Requests: 60, Per: time.Minute},
+		},
+		{
+			name:          `Given a rate limit without period, when it's parsed, then an error is returned`,
+			s:             "60",
+			expectedError: true,
+		},
+		{
+			name:          `Given a rate limit with no requests, when it's parsed, then an error is returned`,
+			s:             "0/1m",
+			expectedError: true,
+		},
+		{
+			name:          `Given a rate limit with an invalid period, when it's parsed, then an error is returned`,
+			s:             "60/minute",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := app.ParseRateLimit(tc.s)
+		if tc.expectedError {
+			require.ErrorIs(t, err, app.ErrInvalidRateLimit, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, got, tc.name)
+	}
+}
+
+func TestTokenBucketTake(t *testing.T) {
+	var (
+		limit = app.RateLimit{Requests: 2, Per: 2 * time.Second}
+		now   = time.Now()
+	)
+
+	t.Run(`Given a full bucket, when its tokens are taken, then the requests are allowed until it's empty`, func(t *testing.T) {
+		b := app.NewTokenBucket(limit, now)
+		b, decision := b.Take(limit, now)
+		require.Equal(t, app.RateLimitDecision{Allowed: true, Remaining: 1}, decision)
+		b, decision = b.Take(limit, now)
+		require.Equal(t, app.RateLimitDecision{Allowed: true, Remaining: 0}, decision)
+		_, decision = b.Take(limit, now.Add(500*time.Millisecond))
+		require.Equal(t, app.RateLimitDecision{RetryAfter: 500 * time.Millisecond}, decision)
+	})
+
+	t.Run(`Given an empty bucket, when the time passes, then it's refilled up to the limit`, func(t *testing.T) {
+		b := app.TokenBucket{UpdatedAt: now}
+		require.Equal(t, now.Add(2*time.Second), b.FullAt(limit))
+
+		_, decision := b.Take(limit, now.Add(time.Second))
+		require.Equal(t, app.RateLimitDecision{Allowed: true, Remaining: 0}, decision)
+		_, decision = b.Take(limit, now.Add(time.Hour))
+		require.Equal(t, app.RateLimitDecision{Allowed: true, Remaining: 1}, decision)
+	})
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		limit = app.RateLimit{Requests: 1, Per: time.Second}
+		now   = time.Now()
+	)
+
+	t.Run(`Given two clients, when one of them runs out of tokens, then the other one is still allowed`, func(t *testing.T) {
+		rl := app.NewMemoryRateLimiter()
+		decision, err := rl.Take(ctx, "a", limit, now)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+
+		decision, err = rl.Take(ctx, "a", limit, now)
+		require.NoError(t, err)
+		require.False(t, decision.Allowed)
+		require.Equal(t, time.Second, decision.RetryAfter)
+
+		decision, err = rl.Take(ctx, "b", limit, now)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+	})
+
+	t.Run(`Given buckets that are full again, when a minute has passed, then they're dropped`, func(t *testing.T) {
+		rl := app.NewMemoryRateLimiter()
+		_, err := rl.Take(ctx, "a", limit, now)
+		require.NoError(t, err)
+		_, err = rl.Take(ctx, "b", limit, now)
+		require.NoError(t, err)
+		require.Equal(t, 2, rl.Len())
+
+		_, err = rl.Take(ctx, "c", limit, now.Add(2*time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, 1, rl.Len())
+	})
+}
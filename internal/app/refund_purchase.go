@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// RefundPurchaseCmd is a command
+type RefundPurchaseCmd struct {
+	// OrderID is the order of the purchase to be refunded
+	OrderID uuid.UUID
+	// Quantity is the number of units to refund. If it's not provided, one unit is refunded
+	Quantity int
+}
+
+// RefundPurchaseName is self-described
+var RefundPurchaseName = "refund.purchase"
+
+// Name implements the Command interface
+func (cmd RefundPurchaseCmd) Name() string {
+	return RefundPurchaseName
+}
+
+// RefundPurchase is a command handler
+type RefundPurchase struct {
+	pr ProductsRepository
+	or OrdersRepository
+	tx Transactor
+}
+
+// NewRefundPurchase is a constructor
+func NewRefundPurchase(pr ProductsRepository, or OrdersRepository, tx Transactor) RefundPurchase {
+	return RefundPurchase{pr: pr, or: or, tx: tx}
+}
+
+// Handle implements CommandHandler interface.
+// The refund is recorded in the order, so its units can't be refunded twice. The product update and the order update
+// are done in the same transaction. If the product is modified concurrently, its update is tried again.
+func (ch RefundPurchase) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(RefundPurchaseCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(RefundPurchaseName, cmd.Name())
+	}
+
+	quantity := co.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	var evs []events.Event
+	err := ch.tx.WithinTx(ctx, func(ctx context.Context) error {
+		o, err := ch.or.FindByID(ctx, co.OrderID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := o.Refund(quantity, now); err != nil {
+			return err
+		}
+
+		err = retryOnConflict(func() error {
+			p, err := ch.pr.FindByIDForUpdate(ctx, o.ProductID())
+			if err != nil {
+				return err
+			}
+
+			if err := p.Refund(quantity); err != nil {
+				return err
+			}
+
+			if err := ch.pr.UpdateStock(ctx, p); err != nil {
+				return err
+			}
+
+			evs = p.Events()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return ch.or.RecordRefund(ctx, o.ID(), quantity, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
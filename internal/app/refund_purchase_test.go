@@ -0,0 +1,188 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestRefundPurchase(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		purchased = fixtures.Product{Available: helpers.BoolPtr(false)}.Build()
+		available = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		order     = domain.NewOrder(uuid.New(), purchased.ID(), "buyer", fixtures.Money("1.1"), 1, time.Now())
+		refunded  = func() domain.Order {
+			o := order
+			require.NoError(t, o.Refund(1, time.Now()))
+			return o
+		}()
+		ordersRepository = func(o domain.Order, recordErr error) *OrdersRepositoryMock {
+			return &OrdersRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Order, error) {
+					return o, nil
+				},
+				RecordRefundFunc: func(_ context.Context, _ uuid.UUID, _ int, _ time.Time) error {
+					return recordErr
+				},
+			}
+		}
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		or              *OrdersRepositoryMock
+		cmd             cqrs.Command
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given an orders repository that returns an error on FindByID, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.RefundPurchaseCmd{},
+			or: &OrdersRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Order, error) {
+					return domain.Order{}, app.ErrNotFound
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrNotFound)
+			},
+		},
+		{
+			name: `Given an order whose units have already been refunded, 
+				when it's refunded again, 
+				then an error is returned and the product is not refunded`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(refunded, nil),
+			pr:  &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrRefundExceedsOrder)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on FindByIDForUpdate, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(order, nil),
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return domain.Product{}, randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a product that has not been purchased, 
+				when it's refunded, 
+				then an error is returned`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(order, nil),
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductNotPurchased)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Update, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(order, nil),
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return purchased, nil
+				},
+				UpdateStockFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given a concurrent refund of the same order, 
+				when the refund is recorded, 
+				then an error is returned`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(order, domain.ErrRefundExceedsOrder),
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return purchased, nil
+				},
+				UpdateStockFunc: func(_ context.Context, _ domain.Product) error {
+					return nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrRefundExceedsOrder)
+			},
+		},
+		{
+			name: `Given a purchased product, 
+				when its order is refunded, 
+				then the refund is recorded in the order and a product refunded event is returned`,
+			cmd: app.RefundPurchaseCmd{OrderID: order.ID()},
+			or:  ordersRepository(order, nil),
+			pr: &ProductsRepositoryMock{
+				FindByIDForUpdateFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return purchased, nil
+				},
+				UpdateStockFunc: func(_ context.Context, _ domain.Product) error {
+					return nil
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tx := &TransactorMock{
+			WithinTxFunc: func(ctx context.Context, fn func(context.Context) error) error {
+				return fn(ctx)
+			},
+		}
+		ch := app.NewRefundPurchase(testCase.pr, testCase.or, tx)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			if errors.Is(err, domain.ErrRefundExceedsOrder) && len(testCase.or.RecordRefundCalls()) == 0 {
+				require.Empty(t, testCase.pr.UpdateStockCalls(), testCase.name)
+			}
+			continue
+		}
+
+		require.Equal(t, order.ID(), testCase.or.FindByIDCalls()[0].ID)
+		require.Equal(t, order.ProductID(), testCase.pr.FindByIDForUpdateCalls()[0].ID)
+		require.True(t, testCase.pr.UpdateStockCalls()[0].P.IsAvailable())
+		require.Len(t, testCase.or.RecordRefundCalls(), 1)
+		require.Equal(t, 1, testCase.or.RecordRefundCalls()[0].Quantity)
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductRefundedEventName, evs[0].Name())
+	}
+}
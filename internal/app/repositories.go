@@ -2,17 +2,88 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/domain"
 
 	"github.com/google/uuid"
 )
 
-//go:generate moq -stub -out zmock_app_repositories_test.go -pkg app_test . ProductsRepository
+//go:generate moq -stub -out zmock_app_repositories_test.go -pkg app_test . ProductsRepository OrdersRepository APIKeysRepository Transactor IdempotencyStore Outbox
 
 // ProductsRepository is self-described
 type ProductsRepository interface {
 	FindByID(ctx context.Context, ID uuid.UUID) (domain.Product, error)
+	// FindByIDForUpdate is as FindByID, but the product is locked until the transaction in course ends,
+	// so the concurrent changes of the product wait for it instead of conflicting. It must be called within a transaction
+	FindByIDForUpdate(ctx context.Context, ID uuid.UUID) (domain.Product, error)
 	FindAll(ctx context.Context) ([]domain.Product, error)
-	UpdateAvailable(ctx context.Context, p domain.Product) error
+	// FindMatching returns the products that match the filter, sorted as the order says
+	FindMatching(ctx context.Context, filter ProductFilter, order ProductOrder) ([]domain.Product, error)
+	// Search returns the products that match the text, the most relevant first
+	Search(ctx context.Context, req ProductsSearchRequest) ([]ProductSearchHit, error)
+	// FindPage returns the products of the page that match its filter, sorted by its order
+	FindPage(ctx context.Context, req ProductsPageRequest) ([]domain.Product, error)
+	// FindExpiredReservations returns up to limit reserved products whose hold has expired at the given time
+	FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]domain.Product, error)
+	// UpdateStock updates the availability and the stock of the product.
+	// It returns ErrVersionConflict if the product has been modified since it was read.
+	UpdateStock(ctx context.Context, p domain.Product) error
+	// Insert returns ErrAlreadyExists if there is already a product with the same ID
+	Insert(ctx context.Context, p domain.Product) error
+	// Update updates all the fields of the product.
+	// It returns ErrVersionConflict if the product has been modified since it was read.
+	Update(ctx context.Context, p domain.Product) error
+}
+
+// OrdersRepository is self-described
+type OrdersRepository interface {
+	FindByID(ctx context.Context, ID uuid.UUID) (domain.Order, error)
+	FindAll(ctx context.Context) ([]domain.Order, error)
+	Insert(ctx context.Context, o domain.Order) error
+	// RecordRefund adds the quantity to the refunded units of the order. It returns domain.ErrRefundExceedsOrder
+	// when they would exceed the units of the order, so the concurrent refunds of an order can't exceed them either
+	RecordRefund(ctx context.Context, ID uuid.UUID, quantity int, refundedAt time.Time) error
+}
+
+// APIKeysRepository is self-described
+type APIKeysRepository interface {
+	FindByID(ctx context.Context, ID uuid.UUID) (domain.APIKey, error)
+	// FindByHash returns the key with the given hash, or ErrNotFound when there is none
+	FindByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	// FindAll returns all the keys, the revoked and expired ones too. The most recent ones come first
+	FindAll(ctx context.Context) ([]domain.APIKey, error)
+	Insert(ctx context.Context, k domain.APIKey) error
+	Update(ctx context.Context, k domain.APIKey) error
+	// TouchLastUsed records the last use of the key. To not write on each request,
+	// it's only recorded when the previous one is older than a minute.
+	TouchLastUsed(ctx context.Context, ID uuid.UUID, now time.Time) error
+}
+
+// Transactor runs a function inside a single storage transaction.
+// The repositories called with the context given to the function take part in that transaction.
+// If the function returns an error, the transaction is rolled back.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// IdempotencyStore is self-described
+type IdempotencyStore interface {
+	// Reserve stores the record if its key is not stored yet in its scope. It returns false if the key was already stored.
+	Reserve(ctx context.Context, r IdempotencyRecord) (bool, error)
+	FindByKey(ctx context.Context, scope, key string) (IdempotencyRecord, error)
+}
+
+// Outbox stores the domain events until they're published.
+// The messages are delivered to a stream, where each one has a position given when it's delivered
+type Outbox interface {
+	Save(ctx context.Context, msgs []OutboxMessage) error
+	// Deliver puts the oldest messages not delivered yet in the stream, up to limit, and returns how many of them have been delivered.
+	// It must be called inside a transaction. The messages are delivered by one transaction at a time, so a message is never
+	// committed in the stream before another one with a lower position. When another transaction is delivering them, none is delivered.
+	Deliver(ctx context.Context, limit int) (int, error)
+	// FindAfter returns the messages delivered after the one with the given Seq, in the order they were delivered, up to limit
+	FindAfter(ctx context.Context, seq int64, limit int) ([]OutboxMessage, error)
+	// LastSeq returns the Seq of the last message delivered, or 0 if none has been delivered yet
+	LastSeq(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+const (
+	reservationsSweepInterval  = 5 * time.Second
+	reservationsSweepBatchSize = 100
+)
+
+// ReservationsSweeper releases the holds of the products that have expired.
+// Each one is released by dispatching an ExpireReservationCmd, so its ProductReservationExpiredEvent
+// is stored in the outbox as the events of any other command.
+type ReservationsSweeper struct {
+	log cqrs.Logger
+	pr  ProductsRepository
+	bus bus.Bus
+}
+
+// NewReservationsSweeper is a constructor
+func NewReservationsSweeper(log cqrs.Logger, pr ProductsRepository, bus bus.Bus) ReservationsSweeper {
+	return ReservationsSweeper{log: log, pr: pr, bus: bus}
+}
+
+// Run releases the expired holds periodically until the context is done
+func (s ReservationsSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(reservationsSweepInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.Sweep(ctx)
+			if err != nil {
+				s.log.Printf("reservations sweeper: %s", err.Error())
+			}
+			if err != nil || n < reservationsSweepBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep releases a batch of expired holds and returns how many of them have been released.
+// A hold that fails to be released is logged, and it's tried again the next time.
+func (s ReservationsSweeper) Sweep(ctx context.Context) (int, error) {
+	products, err := s.pr.FindExpiredReservations(ctx, time.Now().UTC(), reservationsSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// The holds are released as the service itself, so the policy can allow it to no caller of the API
+	ctx = ContextWithPrincipal(ctx, SystemPrincipal)
+	var released int
+	for _, p := range products {
+		if _, err := s.bus.Dispatch(ctx, ExpireReservationCmd{ID: p.ID()}); err != nil {
+			s.log.Printf("reservations sweeper: product %s: %s", p.ID().String(), err.Error())
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
@@ -0,0 +1,94 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+)
+
+func TestReservationsSweeper(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		expiredAt = time.Now().Add(-time.Minute)
+		first     = fixtures.Product{Reservation: fixtures.Reservation("buyer", expiredAt)}.Build()
+		second    = fixtures.Product{Reservation: fixtures.Reservation("another buyer", expiredAt)}.Build()
+	)
+	testCases := []struct {
+		name               string
+		products           []domain.Product
+		findErr            error
+		dispatchErr        map[uuid.UUID]error
+		expectedReleased   int
+		expectedDispatched []uuid.UUID
+		expectedLogCalls   int
+		expectedErrFunc    func(*testing.T, error)
+	}{
+		{
+			name: `Given a products repository that returns an error on FindExpiredReservations, 
+				when the sweeper is run, 
+				then the error is returned`,
+			findErr: randomErr,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given an expired hold that can't be released, 
+				when the sweeper is run, 
+				then the error is logged and the rest of holds are released`,
+			products:           []domain.Product{first, second},
+			dispatchErr:        map[uuid.UUID]error{first.ID(): randomErr},
+			expectedReleased:   1,
+			expectedDispatched: []uuid.UUID{first.ID(), second.ID()},
+			expectedLogCalls:   1,
+		},
+		{
+			name: `Given expired holds, 
+				when the sweeper is run, 
+				then all of them are released`,
+			products:           []domain.Product{first, second},
+			expectedReleased:   2,
+			expectedDispatched: []uuid.UUID{first.ID(), second.ID()},
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			dispatched []uuid.UUID
+			cmdBus     = bus.New()
+			pr         = &ProductsRepositoryMock{
+				FindExpiredReservationsFunc: func(_ context.Context, _ time.Time, _ int) ([]domain.Product, error) {
+					return tc.products, tc.findErr
+				},
+			}
+			lm = &loggerMock{}
+		)
+		cmdBus.Register(app.ExpireReservationName, func(ctx context.Context, d bus.Dispatchable) (interface{}, error) {
+			principal, _ := app.PrincipalFromContext(ctx)
+			require.Equal(t, app.SystemPrincipal, principal, tc.name)
+			ID := d.(app.ExpireReservationCmd).ID
+			dispatched = append(dispatched, ID)
+			return nil, tc.dispatchErr[ID]
+		})
+
+		released, err := app.NewReservationsSweeper(lm, pr, cmdBus).Sweep(context.Background())
+		require.Equal(t, tc.expectedDispatched, dispatched, tc.name)
+		require.Equal(t, tc.expectedLogCalls, lm.calls, tc.name)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+			continue
+		}
+		require.Equal(t, tc.expectedReleased, released, tc.name)
+		require.WithinDuration(t, time.Now(), pr.FindExpiredReservationsCalls()[0].Now, time.Second, tc.name)
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// DefaultReservationTTL is how long a product is held when no other TTL is configured
+const DefaultReservationTTL = 15 * time.Minute
+
+// ReserveProductCmd is a command
+type ReserveProductCmd struct {
+	ID uuid.UUID
+	// Holder is the session token the product is held for. It's ignored when the buyer is authenticated
+	Holder string
+	// BuyerID is the ID of the authenticated buyer. It's empty for the anonymous reservations
+	BuyerID string
+}
+
+// ReserveProductName is self-described
+var ReserveProductName = "reserve.product"
+
+// Name implements the Command interface
+func (cmd ReserveProductCmd) Name() string {
+	return ReserveProductName
+}
+
+// ReserveProduct is a command handler
+type ReserveProduct struct {
+	pr  ProductsRepository
+	ttl time.Duration
+}
+
+// NewReserveProduct is a constructor. The products are held for the given TTL
+func NewReserveProduct(pr ProductsRepository, ttl time.Duration) ReserveProduct {
+	return ReserveProduct{pr: pr, ttl: ttl}
+}
+
+// Handle implements CommandHandler interface.
+// Reserving again a product already held for the same holder renews its hold.
+// If the product is modified concurrently, the reservation is tried again.
+func (ch ReserveProduct) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(ReserveProductCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(ReserveProductName, cmd.Name())
+	}
+
+	now := time.Now().UTC()
+	r, err := domain.NewReservation(reservationHolder(co.BuyerID, co.Holder), now.Add(ch.ttl))
+	if err != nil {
+		return nil, err
+	}
+
+	var evs []events.Event
+	err = retryOnConflict(func() error {
+		p, err := ch.pr.FindByID(ctx, co.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := releaseExpiredReservation(&p, now); err != nil {
+			return err
+		}
+
+		if err := p.Reserve(r); err != nil {
+			return err
+		}
+
+		if err := ch.pr.Update(ctx, p); err != nil {
+			return err
+		}
+
+		evs = p.Events()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
+
+// reservationHolder returns the holder of the reservations of the caller. The reservations of an authenticated buyer
+// are held for them, whatever the session token is, so nobody else can give their ID to purchase what they hold.
+// It's empty when the caller is anonymous and gives no session token
+func reservationHolder(buyerID, sessionToken string) string {
+	switch {
+	case buyerID != "":
+		return domain.BuyerHolder(buyerID)
+	case sessionToken != "":
+		return domain.SessionHolder(sessionToken)
+	default:
+		return ""
+	}
+}
+
+// releaseExpiredReservation releases the hold of the product when it has expired, so it can be reserved
+// or purchased by anyone without waiting for the sweeper to release it
+func releaseExpiredReservation(p *domain.Product, now time.Time) error {
+	if r, ok := p.Reservation(); ok && r.IsExpired(now) {
+		return p.ExpireReservation(now)
+	}
+	return nil
+}
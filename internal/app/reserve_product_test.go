@@ -0,0 +1,201 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestReserveProduct(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		ttl       = 10 * time.Minute
+		available = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		held      = fixtures.Product{Reservation: fixtures.Reservation(domain.BuyerHolder("another buyer"), time.Now().Add(time.Hour))}.Build()
+		overdue   = fixtures.Product{Reservation: fixtures.Reservation(domain.BuyerHolder("another buyer"), time.Now().Add(-time.Minute))}.Build()
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		cmd             cqrs.Command
+		expectedEvents  []string
+		expectedHolder  string
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a command without holder, when it's called, then an error is returned`,
+			cmd:  app.ReserveProductCmd{ID: available.ID()},
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidHolder)
+			},
+		},
+		{
+			name: `Given a products repository that returns not found on FindByID, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.ReserveProductCmd{BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return domain.Product{}, app.ErrNotFound
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrNotFound)
+			},
+		},
+		{
+			name: `Given a product held for another buyer, 
+				when it's reserved, 
+				then an error is returned`,
+			cmd: app.ReserveProductCmd{ID: held.ID(), BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return held, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a product held for a buyer, 
+				when an anonymous caller reserves it giving the ID of the buyer as holder, 
+				then an error is returned`,
+			cmd: app.ReserveProductCmd{ID: held.ID(), Holder: "another buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return held, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a product held for a buyer, 
+				when another buyer reserves it giving the ID of the buyer as holder, 
+				then an error is returned`,
+			cmd: app.ReserveProductCmd{ID: held.ID(), Holder: "another buyer", BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return held, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductReserved)
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on Update, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.ReserveProductCmd{ID: available.ID(), BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+				UpdateFunc: func(_ context.Context, _ domain.Product) error {
+					return randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given an available product, 
+				when it's reserved, 
+				then it's held for the buyer for the TTL`,
+			cmd: app.ReserveProductCmd{ID: available.ID(), BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedEvents: []string{domain.ProductReservedEventName},
+			expectedHolder: domain.BuyerHolder("buyer"),
+		},
+		{
+			name: `Given an available product, 
+				when an authenticated buyer reserves it giving a holder, 
+				then it's held for the buyer and not for the given holder`,
+			cmd: app.ReserveProductCmd{ID: available.ID(), Holder: "session token", BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedEvents: []string{domain.ProductReservedEventName},
+			expectedHolder: domain.BuyerHolder("buyer"),
+		},
+		{
+			name: `Given an available product, 
+				when an anonymous caller reserves it, 
+				then it's held for their session token`,
+			cmd: app.ReserveProductCmd{ID: available.ID(), Holder: "session token"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedEvents: []string{domain.ProductReservedEventName},
+			expectedHolder: domain.SessionHolder("session token"),
+		},
+		{
+			name: `Given a product whose hold for another buyer is overdue, 
+				when it's reserved, 
+				then the overdue hold is released and it's held for the buyer`,
+			cmd: app.ReserveProductCmd{ID: overdue.ID(), BuyerID: "buyer"},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return overdue, nil
+				},
+			},
+			expectedEvents: []string{domain.ProductReservationExpiredEventName, domain.ProductReservedEventName},
+			expectedHolder: domain.BuyerHolder("buyer"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		ch := app.NewReserveProduct(testCase.pr, ttl)
+		before := time.Now()
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		var names []string
+		for _, ev := range evs {
+			names = append(names, ev.Name())
+		}
+		require.Equal(t, testCase.expectedEvents, names, testCase.name)
+
+		reserved := testCase.pr.UpdateCalls()[0].P
+		require.Equal(t, domain.StatusReserved, reserved.Status(), testCase.name)
+		r, ok := reserved.Reservation()
+		require.True(t, ok, testCase.name)
+		require.True(t, r.IsHeldBy(testCase.expectedHolder), testCase.name)
+		require.WithinDuration(t, before.Add(ttl), r.ExpiresAt(), time.Second, testCase.name)
+	}
+}
@@ -0,0 +1,18 @@
+package app
+
+import "errors"
+
+// maxAttempts is the number of times an operation is tried when it fails because of a version conflict
+const maxAttempts = 3
+
+// retryOnConflict runs fn again while it fails because of a version conflict, up to maxAttempts times.
+// fn must read again the entities it modifies.
+func retryOnConflict(fn func() error) error {
+	var err error
+	for i := 0; i < maxAttempts; i++ {
+		if err = fn(); !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
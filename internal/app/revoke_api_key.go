@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// RevokeAPIKeyCmd is a command
+type RevokeAPIKeyCmd struct {
+	ID uuid.UUID
+}
+
+// RevokeAPIKeyName is self-described
+var RevokeAPIKeyName = "revoke.api_key"
+
+// Name implements the Command interface
+func (cmd RevokeAPIKeyCmd) Name() string {
+	return RevokeAPIKeyName
+}
+
+// RevokeAPIKey is a command handler
+type RevokeAPIKey struct {
+	kr APIKeysRepository
+}
+
+// NewRevokeAPIKey is a constructor
+func NewRevokeAPIKey(kr APIKeysRepository) RevokeAPIKey {
+	return RevokeAPIKey{kr: kr}
+}
+
+// Handle implements CommandHandler interface
+func (ch RevokeAPIKey) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(RevokeAPIKeyCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(RevokeAPIKeyName, cmd.Name())
+	}
+
+	k, err := ch.kr.FindByID(ctx, co.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.Revoke(time.Now()); err != nil {
+		return nil, err
+	}
+	return nil, ch.kr.Update(ctx, k)
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// UpdateProductCmd is a command. Only the provided fields are updated
+type UpdateProductCmd struct {
+	ID          uuid.UUID
+	ProductName *string
+	Price       *domain.Money
+	// Status can only be published, to put the product on sale, or draft, to withdraw it from sale
+	Status *domain.Status
+}
+
+// UpdateProductName is self-described
+var UpdateProductName = "update.product"
+
+// Name implements the Command interface
+func (cmd UpdateProductCmd) Name() string {
+	return UpdateProductName
+}
+
+// UpdateProduct is a command handler
+type UpdateProduct struct {
+	pr ProductsRepository
+}
+
+// NewUpdateProduct is a constructor
+func NewUpdateProduct(pr ProductsRepository) UpdateProduct {
+	return UpdateProduct{pr: pr}
+}
+
+// Handle implements CommandHandler interface.
+// If the product is modified concurrently, the update is tried again.
+func (ch UpdateProduct) Handle(ctx context.Context, cmd cqrs.Command) ([]events.Event, error) {
+	co, ok := cmd.(UpdateProductCmd)
+	if !ok {
+		return nil, NewInvalidCommandError(UpdateProductName, cmd.Name())
+	}
+
+	var evs []events.Event
+	err := retryOnConflict(func() error {
+		p, err := ch.pr.FindByID(ctx, co.ID)
+		if err != nil {
+			return err
+		}
+
+		if co.ProductName != nil {
+			if err := p.Rename(*co.ProductName); err != nil {
+				return err
+			}
+		}
+		if co.Price != nil {
+			if err := p.ChangePrice(*co.Price); err != nil {
+				return err
+			}
+		}
+		if co.Status != nil {
+			if err := changeStatus(&p, *co.Status); err != nil {
+				return err
+			}
+		}
+
+		if err := ch.pr.Update(ctx, p); err != nil {
+			return err
+		}
+
+		evs = p.Events()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evs, nil
+}
+
+// changeStatus moves the product to the given status. The other statuses are reached by archiving, purchasing or reserving it.
+func changeStatus(p *domain.Product, status domain.Status) error {
+	switch status {
+	case domain.StatusPublished:
+		return p.Publish()
+	case domain.StatusDraft:
+		return p.Unpublish()
+	default:
+		return fmt.Errorf("%w: a product can't be updated to %s", domain.ErrInvalidStatusTransition, status)
+	}
+}
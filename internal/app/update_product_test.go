@@ -0,0 +1,156 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+func TestUpdateProduct(t *testing.T) {
+	var (
+		randomErr = errors.New("")
+		price     = fixtures.Money("2.2")
+		name      = "product2"
+		available = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		archived  = fixtures.Product{Archived: true}.Build()
+		draft     = domain.StatusDraft
+		published = domain.StatusPublished
+		sold      = domain.StatusSold
+	)
+	testCases := []struct {
+		name            string
+		pr              *ProductsRepositoryMock
+		cmd             cqrs.Command
+		expectedEvents  []string
+		expectedErrFunc func(*testing.T, error)
+	}{
+		{
+			name: `Given an invalid command, when it's called, then an error is returned`,
+			cmd:  newInvalidCommand(),
+			pr:   &ProductsRepositoryMock{},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &app.InvalidCommandError{})
+			},
+		},
+		{
+			name: `Given a products repository that returns an error on FindByID, 
+				when it's called, 
+				then an error is returned`,
+			cmd: app.UpdateProductCmd{ProductName: &name},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return domain.Product{}, randomErr
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, randomErr)
+			},
+		},
+		{
+			name: `Given an archived product, 
+				when it's updated, 
+				then an error is returned`,
+			cmd: app.UpdateProductCmd{ProductName: &name},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return archived, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrProductArchived)
+			},
+		},
+		{
+			name: `Given a products repository that returns a version conflict on Update, 
+				when it's called, 
+				then the update is tried again until the attempts run out`,
+			cmd: app.UpdateProductCmd{ProductName: &name},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+				UpdateFunc: func(_ context.Context, _ domain.Product) error {
+					return app.ErrVersionConflict
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, app.ErrVersionConflict)
+			},
+		},
+		{
+			name: `Given an available product, 
+				when it's updated to a status it can't be updated to, 
+				then an error is returned`,
+			cmd: app.UpdateProductCmd{ID: available.ID(), Status: &sold},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidStatusTransition)
+			},
+		},
+		{
+			name: `Given an available product, 
+				when its name, price and status are updated, 
+				then an event is returned for each change`,
+			cmd: app.UpdateProductCmd{ID: available.ID(), ProductName: &name, Price: &price, Status: &draft},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+			expectedEvents: []string{
+				domain.ProductRenamedEventName,
+				domain.ProductPriceChangedEventName,
+				domain.ProductUnpublishedEventName,
+			},
+		},
+		{
+			name: `Given an available product, 
+				when only its status is updated with the same value, 
+				then no event is returned`,
+			cmd: app.UpdateProductCmd{ID: available.ID(), Status: &published},
+			pr: &ProductsRepositoryMock{
+				FindByIDFunc: func(_ context.Context, _ uuid.UUID) (domain.Product, error) {
+					return available, nil
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		ch := app.NewUpdateProduct(testCase.pr)
+		evs, err := ch.Handle(context.Background(), testCase.cmd)
+		require.Equal(t, testCase.expectedErrFunc == nil, err == nil, testCase.name)
+		if err != nil {
+			testCase.expectedErrFunc(t, err)
+			continue
+		}
+
+		cmd := testCase.cmd.(app.UpdateProductCmd)
+		require.Equal(t, cmd.ID, testCase.pr.FindByIDCalls()[0].ID)
+		require.Len(t, testCase.pr.UpdateCalls(), 1)
+		updated := testCase.pr.UpdateCalls()[0].P
+		if cmd.ProductName != nil {
+			require.Equal(t, *cmd.ProductName, updated.Name())
+		}
+		if cmd.Status != nil {
+			require.Equal(t, *cmd.Status, updated.Status())
+		}
+		require.Len(t, evs, len(testCase.expectedEvents))
+		for i, name := range testCase.expectedEvents {
+			require.Equal(t, name, evs[i].Name())
+		}
+	}
+}
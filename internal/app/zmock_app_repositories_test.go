@@ -9,6 +9,7 @@ import (
 	"sync"
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/domain"
+	"time"
 )
 
 // Ensure, that ProductsRepositoryMock does implement app.ProductsRepository.
@@ -27,8 +28,29 @@ var _ app.ProductsRepository = &ProductsRepositoryMock{}
 //			FindByIDFunc: func(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
 //				panic("mock out the FindByID method")
 //			},
-//			UpdateAvailableFunc: func(ctx context.Context, p domain.Product) error {
-//				panic("mock out the UpdateAvailable method")
+//			FindByIDForUpdateFunc: func(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
+//				panic("mock out the FindByIDForUpdate method")
+//			},
+//			FindExpiredReservationsFunc: func(ctx context.Context, now time.Time, limit int) ([]domain.Product, error) {
+//				panic("mock out the FindExpiredReservations method")
+//			},
+//			FindMatchingFunc: func(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error) {
+//				panic("mock out the FindMatching method")
+//			},
+//			FindPageFunc: func(ctx context.Context, req app.ProductsPageRequest) ([]domain.Product, error) {
+//				panic("mock out the FindPage method")
+//			},
+//			InsertFunc: func(ctx context.Context, p domain.Product) error {
+//				panic("mock out the Insert method")
+//			},
+//			SearchFunc: func(ctx context.Context, req app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+//				panic("mock out the Search method")
+//			},
+//			UpdateFunc: func(ctx context.Context, p domain.Product) error {
+//				panic("mock out the Update method")
+//			},
+//			UpdateStockFunc: func(ctx context.Context, p domain.Product) error {
+//				panic("mock out the UpdateStock method")
 //			},
 //		}
 //
@@ -43,8 +65,29 @@ type ProductsRepositoryMock struct {
 	// FindByIDFunc mocks the FindByID method.
 	FindByIDFunc func(ctx context.Context, ID uuid.UUID) (domain.Product, error)
 
-	// UpdateAvailableFunc mocks the UpdateAvailable method.
-	UpdateAvailableFunc func(ctx context.Context, p domain.Product) error
+	// FindByIDForUpdateFunc mocks the FindByIDForUpdate method.
+	FindByIDForUpdateFunc func(ctx context.Context, ID uuid.UUID) (domain.Product, error)
+
+	// FindExpiredReservationsFunc mocks the FindExpiredReservations method.
+	FindExpiredReservationsFunc func(ctx context.Context, now time.Time, limit int) ([]domain.Product, error)
+
+	// FindMatchingFunc mocks the FindMatching method.
+	FindMatchingFunc func(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error)
+
+	// FindPageFunc mocks the FindPage method.
+	FindPageFunc func(ctx context.Context, req app.ProductsPageRequest) ([]domain.Product, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, p domain.Product) error
+
+	// SearchFunc mocks the Search method.
+	SearchFunc func(ctx context.Context, req app.ProductsSearchRequest) ([]app.ProductSearchHit, error)
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, p domain.Product) error
+
+	// UpdateStockFunc mocks the UpdateStock method.
+	UpdateStockFunc func(ctx context.Context, p domain.Product) error
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -60,17 +103,77 @@ type ProductsRepositoryMock struct {
 			// ID is the ID argument value.
 			ID uuid.UUID
 		}
-		// UpdateAvailable holds details about calls to the UpdateAvailable method.
-		UpdateAvailable []struct {
+		// FindByIDForUpdate holds details about calls to the FindByIDForUpdate method.
+		FindByIDForUpdate []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID uuid.UUID
+		}
+		// FindExpiredReservations holds details about calls to the FindExpiredReservations method.
+		FindExpiredReservations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Now is the now argument value.
+			Now time.Time
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// FindMatching holds details about calls to the FindMatching method.
+		FindMatching []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter app.ProductFilter
+			// Order is the order argument value.
+			Order app.ProductOrder
+		}
+		// FindPage holds details about calls to the FindPage method.
+		FindPage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req app.ProductsPageRequest
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// P is the p argument value.
+			P domain.Product
+		}
+		// Search holds details about calls to the Search method.
+		Search []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req app.ProductsSearchRequest
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// P is the p argument value.
+			P domain.Product
+		}
+		// UpdateStock holds details about calls to the UpdateStock method.
+		UpdateStock []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// P is the p argument value.
 			P domain.Product
 		}
 	}
-	lockFindAll         sync.RWMutex
-	lockFindByID        sync.RWMutex
-	lockUpdateAvailable sync.RWMutex
+	lockFindAll                 sync.RWMutex
+	lockFindByID                sync.RWMutex
+	lockFindByIDForUpdate       sync.RWMutex
+	lockFindExpiredReservations sync.RWMutex
+	lockFindMatching            sync.RWMutex
+	lockFindPage                sync.RWMutex
+	lockInsert                  sync.RWMutex
+	lockSearch                  sync.RWMutex
+	lockUpdate                  sync.RWMutex
+	lockUpdateStock             sync.RWMutex
 }
 
 // FindAll calls FindAllFunc.
@@ -149,8 +252,294 @@ func (mock *ProductsRepositoryMock) FindByIDCalls() []struct {
 	return calls
 }
 
-// UpdateAvailable calls UpdateAvailableFunc.
-func (mock *ProductsRepositoryMock) UpdateAvailable(ctx context.Context, p domain.Product) error {
+// FindByIDForUpdate calls FindByIDForUpdateFunc.
+func (mock *ProductsRepositoryMock) FindByIDForUpdate(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	mock.lockFindByIDForUpdate.Lock()
+	mock.calls.FindByIDForUpdate = append(mock.calls.FindByIDForUpdate, callInfo)
+	mock.lockFindByIDForUpdate.Unlock()
+	if mock.FindByIDForUpdateFunc == nil {
+		var (
+			productOut domain.Product
+			errOut     error
+		)
+		return productOut, errOut
+	}
+	return mock.FindByIDForUpdateFunc(ctx, ID)
+}
+
+// FindByIDForUpdateCalls gets all the calls that were made to FindByIDForUpdate.
+// Check the length with:
+//
+//	len(mockedProductsRepository.FindByIDForUpdateCalls())
+func (mock *ProductsRepositoryMock) FindByIDForUpdateCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockFindByIDForUpdate.RLock()
+	calls = mock.calls.FindByIDForUpdate
+	mock.lockFindByIDForUpdate.RUnlock()
+	return calls
+}
+
+// FindExpiredReservations calls FindExpiredReservationsFunc.
+func (mock *ProductsRepositoryMock) FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]domain.Product, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Now   time.Time
+		Limit int
+	}{
+		Ctx:   ctx,
+		Now:   now,
+		Limit: limit,
+	}
+	mock.lockFindExpiredReservations.Lock()
+	mock.calls.FindExpiredReservations = append(mock.calls.FindExpiredReservations, callInfo)
+	mock.lockFindExpiredReservations.Unlock()
+	if mock.FindExpiredReservationsFunc == nil {
+		var (
+			productsOut []domain.Product
+			errOut      error
+		)
+		return productsOut, errOut
+	}
+	return mock.FindExpiredReservationsFunc(ctx, now, limit)
+}
+
+// FindExpiredReservationsCalls gets all the calls that were made to FindExpiredReservations.
+// Check the length with:
+//
+//	len(mockedProductsRepository.FindExpiredReservationsCalls())
+func (mock *ProductsRepositoryMock) FindExpiredReservationsCalls() []struct {
+	Ctx   context.Context
+	Now   time.Time
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Now   time.Time
+		Limit int
+	}
+	mock.lockFindExpiredReservations.RLock()
+	calls = mock.calls.FindExpiredReservations
+	mock.lockFindExpiredReservations.RUnlock()
+	return calls
+}
+
+// FindMatching calls FindMatchingFunc.
+func (mock *ProductsRepositoryMock) FindMatching(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Filter app.ProductFilter
+		Order  app.ProductOrder
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+		Order:  order,
+	}
+	mock.lockFindMatching.Lock()
+	mock.calls.FindMatching = append(mock.calls.FindMatching, callInfo)
+	mock.lockFindMatching.Unlock()
+	if mock.FindMatchingFunc == nil {
+		var (
+			productsOut []domain.Product
+			errOut      error
+		)
+		return productsOut, errOut
+	}
+	return mock.FindMatchingFunc(ctx, filter, order)
+}
+
+// FindMatchingCalls gets all the calls that were made to FindMatching.
+// Check the length with:
+//
+//	len(mockedProductsRepository.FindMatchingCalls())
+func (mock *ProductsRepositoryMock) FindMatchingCalls() []struct {
+	Ctx    context.Context
+	Filter app.ProductFilter
+	Order  app.ProductOrder
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter app.ProductFilter
+		Order  app.ProductOrder
+	}
+	mock.lockFindMatching.RLock()
+	calls = mock.calls.FindMatching
+	mock.lockFindMatching.RUnlock()
+	return calls
+}
+
+// FindPage calls FindPageFunc.
+func (mock *ProductsRepositoryMock) FindPage(ctx context.Context, req app.ProductsPageRequest) ([]domain.Product, error) {
+	callInfo := struct {
+		Ctx context.Context
+		Req app.ProductsPageRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockFindPage.Lock()
+	mock.calls.FindPage = append(mock.calls.FindPage, callInfo)
+	mock.lockFindPage.Unlock()
+	if mock.FindPageFunc == nil {
+		var (
+			productsOut []domain.Product
+			errOut      error
+		)
+		return productsOut, errOut
+	}
+	return mock.FindPageFunc(ctx, req)
+}
+
+// FindPageCalls gets all the calls that were made to FindPage.
+// Check the length with:
+//
+//	len(mockedProductsRepository.FindPageCalls())
+func (mock *ProductsRepositoryMock) FindPageCalls() []struct {
+	Ctx context.Context
+	Req app.ProductsPageRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req app.ProductsPageRequest
+	}
+	mock.lockFindPage.RLock()
+	calls = mock.calls.FindPage
+	mock.lockFindPage.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *ProductsRepositoryMock) Insert(ctx context.Context, p domain.Product) error {
+	callInfo := struct {
+		Ctx context.Context
+		P   domain.Product
+	}{
+		Ctx: ctx,
+		P:   p,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, p)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedProductsRepository.InsertCalls())
+func (mock *ProductsRepositoryMock) InsertCalls() []struct {
+	Ctx context.Context
+	P   domain.Product
+} {
+	var calls []struct {
+		Ctx context.Context
+		P   domain.Product
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// Search calls SearchFunc.
+func (mock *ProductsRepositoryMock) Search(ctx context.Context, req app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+	callInfo := struct {
+		Ctx context.Context
+		Req app.ProductsSearchRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockSearch.Lock()
+	mock.calls.Search = append(mock.calls.Search, callInfo)
+	mock.lockSearch.Unlock()
+	if mock.SearchFunc == nil {
+		var (
+			productSearchHitsOut []app.ProductSearchHit
+			errOut               error
+		)
+		return productSearchHitsOut, errOut
+	}
+	return mock.SearchFunc(ctx, req)
+}
+
+// SearchCalls gets all the calls that were made to Search.
+// Check the length with:
+//
+//	len(mockedProductsRepository.SearchCalls())
+func (mock *ProductsRepositoryMock) SearchCalls() []struct {
+	Ctx context.Context
+	Req app.ProductsSearchRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req app.ProductsSearchRequest
+	}
+	mock.lockSearch.RLock()
+	calls = mock.calls.Search
+	mock.lockSearch.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *ProductsRepositoryMock) Update(ctx context.Context, p domain.Product) error {
+	callInfo := struct {
+		Ctx context.Context
+		P   domain.Product
+	}{
+		Ctx: ctx,
+		P:   p,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, p)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedProductsRepository.UpdateCalls())
+func (mock *ProductsRepositoryMock) UpdateCalls() []struct {
+	Ctx context.Context
+	P   domain.Product
+} {
+	var calls []struct {
+		Ctx context.Context
+		P   domain.Product
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// UpdateStock calls UpdateStockFunc.
+func (mock *ProductsRepositoryMock) UpdateStock(ctx context.Context, p domain.Product) error {
 	callInfo := struct {
 		Ctx context.Context
 		P   domain.Product
@@ -158,23 +547,23 @@ func (mock *ProductsRepositoryMock) UpdateAvailable(ctx context.Context, p domai
 		Ctx: ctx,
 		P:   p,
 	}
-	mock.lockUpdateAvailable.Lock()
-	mock.calls.UpdateAvailable = append(mock.calls.UpdateAvailable, callInfo)
-	mock.lockUpdateAvailable.Unlock()
-	if mock.UpdateAvailableFunc == nil {
+	mock.lockUpdateStock.Lock()
+	mock.calls.UpdateStock = append(mock.calls.UpdateStock, callInfo)
+	mock.lockUpdateStock.Unlock()
+	if mock.UpdateStockFunc == nil {
 		var (
 			errOut error
 		)
 		return errOut
 	}
-	return mock.UpdateAvailableFunc(ctx, p)
+	return mock.UpdateStockFunc(ctx, p)
 }
 
-// UpdateAvailableCalls gets all the calls that were made to UpdateAvailable.
+// UpdateStockCalls gets all the calls that were made to UpdateStock.
 // Check the length with:
 //
-//	len(mockedProductsRepository.UpdateAvailableCalls())
-func (mock *ProductsRepositoryMock) UpdateAvailableCalls() []struct {
+//	len(mockedProductsRepository.UpdateStockCalls())
+func (mock *ProductsRepositoryMock) UpdateStockCalls() []struct {
 	Ctx context.Context
 	P   domain.Product
 } {
@@ -182,8 +571,1041 @@ func (mock *ProductsRepositoryMock) UpdateAvailableCalls() []struct {
 		Ctx context.Context
 		P   domain.Product
 	}
-	mock.lockUpdateAvailable.RLock()
-	calls = mock.calls.UpdateAvailable
-	mock.lockUpdateAvailable.RUnlock()
+	mock.lockUpdateStock.RLock()
+	calls = mock.calls.UpdateStock
+	mock.lockUpdateStock.RUnlock()
+	return calls
+}
+
+// Ensure, that OrdersRepositoryMock does implement app.OrdersRepository.
+// If this is not the case, regenerate this file with moq.
+var _ app.OrdersRepository = &OrdersRepositoryMock{}
+
+// OrdersRepositoryMock is a mock implementation of app.OrdersRepository.
+//
+//	func TestSomethingThatUsesOrdersRepository(t *testing.T) {
+//
+//		// make and configure a mocked app.OrdersRepository
+//		mockedOrdersRepository := &OrdersRepositoryMock{
+//			FindAllFunc: func(ctx context.Context) ([]domain.Order, error) {
+//				panic("mock out the FindAll method")
+//			},
+//			FindByIDFunc: func(ctx context.Context, ID uuid.UUID) (domain.Order, error) {
+//				panic("mock out the FindByID method")
+//			},
+//			InsertFunc: func(ctx context.Context, o domain.Order) error {
+//				panic("mock out the Insert method")
+//			},
+//			RecordRefundFunc: func(ctx context.Context, ID uuid.UUID, quantity int, refundedAt time.Time) error {
+//				panic("mock out the RecordRefund method")
+//			},
+//		}
+//
+//		// use mockedOrdersRepository in code that requires app.OrdersRepository
+//		// and then make assertions.
+//
+//	}
+type OrdersRepositoryMock struct {
+	// FindAllFunc mocks the FindAll method.
+	FindAllFunc func(ctx context.Context) ([]domain.Order, error)
+
+	// FindByIDFunc mocks the FindByID method.
+	FindByIDFunc func(ctx context.Context, ID uuid.UUID) (domain.Order, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, o domain.Order) error
+
+	// RecordRefundFunc mocks the RecordRefund method.
+	RecordRefundFunc func(ctx context.Context, ID uuid.UUID, quantity int, refundedAt time.Time) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// FindAll holds details about calls to the FindAll method.
+		FindAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// FindByID holds details about calls to the FindByID method.
+		FindByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID uuid.UUID
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// O is the o argument value.
+			O domain.Order
+		}
+		// RecordRefund holds details about calls to the RecordRefund method.
+		RecordRefund []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID uuid.UUID
+			// Quantity is the quantity argument value.
+			Quantity int
+			// RefundedAt is the refundedAt argument value.
+			RefundedAt time.Time
+		}
+	}
+	lockFindAll      sync.RWMutex
+	lockFindByID     sync.RWMutex
+	lockInsert       sync.RWMutex
+	lockRecordRefund sync.RWMutex
+}
+
+// FindAll calls FindAllFunc.
+func (mock *OrdersRepositoryMock) FindAll(ctx context.Context) ([]domain.Order, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockFindAll.Lock()
+	mock.calls.FindAll = append(mock.calls.FindAll, callInfo)
+	mock.lockFindAll.Unlock()
+	if mock.FindAllFunc == nil {
+		var (
+			ordersOut []domain.Order
+			errOut    error
+		)
+		return ordersOut, errOut
+	}
+	return mock.FindAllFunc(ctx)
+}
+
+// FindAllCalls gets all the calls that were made to FindAll.
+// Check the length with:
+//
+//	len(mockedOrdersRepository.FindAllCalls())
+func (mock *OrdersRepositoryMock) FindAllCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockFindAll.RLock()
+	calls = mock.calls.FindAll
+	mock.lockFindAll.RUnlock()
+	return calls
+}
+
+// FindByID calls FindByIDFunc.
+func (mock *OrdersRepositoryMock) FindByID(ctx context.Context, ID uuid.UUID) (domain.Order, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	mock.lockFindByID.Lock()
+	mock.calls.FindByID = append(mock.calls.FindByID, callInfo)
+	mock.lockFindByID.Unlock()
+	if mock.FindByIDFunc == nil {
+		var (
+			orderOut domain.Order
+			errOut   error
+		)
+		return orderOut, errOut
+	}
+	return mock.FindByIDFunc(ctx, ID)
+}
+
+// FindByIDCalls gets all the calls that were made to FindByID.
+// Check the length with:
+//
+//	len(mockedOrdersRepository.FindByIDCalls())
+func (mock *OrdersRepositoryMock) FindByIDCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockFindByID.RLock()
+	calls = mock.calls.FindByID
+	mock.lockFindByID.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *OrdersRepositoryMock) Insert(ctx context.Context, o domain.Order) error {
+	callInfo := struct {
+		Ctx context.Context
+		O   domain.Order
+	}{
+		Ctx: ctx,
+		O:   o,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, o)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedOrdersRepository.InsertCalls())
+func (mock *OrdersRepositoryMock) InsertCalls() []struct {
+	Ctx context.Context
+	O   domain.Order
+} {
+	var calls []struct {
+		Ctx context.Context
+		O   domain.Order
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// RecordRefund calls RecordRefundFunc.
+func (mock *OrdersRepositoryMock) RecordRefund(ctx context.Context, ID uuid.UUID, quantity int, refundedAt time.Time) error {
+	callInfo := struct {
+		Ctx        context.Context
+		ID         uuid.UUID
+		Quantity   int
+		RefundedAt time.Time
+	}{
+		Ctx:        ctx,
+		ID:         ID,
+		Quantity:   quantity,
+		RefundedAt: refundedAt,
+	}
+	mock.lockRecordRefund.Lock()
+	mock.calls.RecordRefund = append(mock.calls.RecordRefund, callInfo)
+	mock.lockRecordRefund.Unlock()
+	if mock.RecordRefundFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RecordRefundFunc(ctx, ID, quantity, refundedAt)
+}
+
+// RecordRefundCalls gets all the calls that were made to RecordRefund.
+// Check the length with:
+//
+//	len(mockedOrdersRepository.RecordRefundCalls())
+func (mock *OrdersRepositoryMock) RecordRefundCalls() []struct {
+	Ctx        context.Context
+	ID         uuid.UUID
+	Quantity   int
+	RefundedAt time.Time
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         uuid.UUID
+		Quantity   int
+		RefundedAt time.Time
+	}
+	mock.lockRecordRefund.RLock()
+	calls = mock.calls.RecordRefund
+	mock.lockRecordRefund.RUnlock()
+	return calls
+}
+
+// Ensure, that APIKeysRepositoryMock does implement app.APIKeysRepository.
+// If this is not the case, regenerate this file with moq.
+var _ app.APIKeysRepository = &APIKeysRepositoryMock{}
+
+// APIKeysRepositoryMock is a mock implementation of app.APIKeysRepository.
+//
+//	func TestSomethingThatUsesAPIKeysRepository(t *testing.T) {
+//
+//		// make and configure a mocked app.APIKeysRepository
+//		mockedAPIKeysRepository := &APIKeysRepositoryMock{
+//			FindAllFunc: func(ctx context.Context) ([]domain.APIKey, error) {
+//				panic("mock out the FindAll method")
+//			},
+//			FindByHashFunc: func(ctx context.Context, hash string) (domain.APIKey, error) {
+//				panic("mock out the FindByHash method")
+//			},
+//			FindByIDFunc: func(ctx context.Context, ID uuid.UUID) (domain.APIKey, error) {
+//				panic("mock out the FindByID method")
+//			},
+//			InsertFunc: func(ctx context.Context, k domain.APIKey) error {
+//				panic("mock out the Insert method")
+//			},
+//			TouchLastUsedFunc: func(ctx context.Context, ID uuid.UUID, now time.Time) error {
+//				panic("mock out the TouchLastUsed method")
+//			},
+//			UpdateFunc: func(ctx context.Context, k domain.APIKey) error {
+//				panic("mock out the Update method")
+//			},
+//		}
+//
+//		// use mockedAPIKeysRepository in code that requires app.APIKeysRepository
+//		// and then make assertions.
+//
+//	}
+type APIKeysRepositoryMock struct {
+	// FindAllFunc mocks the FindAll method.
+	FindAllFunc func(ctx context.Context) ([]domain.APIKey, error)
+
+	// FindByHashFunc mocks the FindByHash method.
+	FindByHashFunc func(ctx context.Context, hash string) (domain.APIKey, error)
+
+	// FindByIDFunc mocks the FindByID method.
+	FindByIDFunc func(ctx context.Context, ID uuid.UUID) (domain.APIKey, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, k domain.APIKey) error
+
+	// TouchLastUsedFunc mocks the TouchLastUsed method.
+	TouchLastUsedFunc func(ctx context.Context, ID uuid.UUID, now time.Time) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, k domain.APIKey) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// FindAll holds details about calls to the FindAll method.
+		FindAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// FindByHash holds details about calls to the FindByHash method.
+		FindByHash []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// FindByID holds details about calls to the FindByID method.
+		FindByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID uuid.UUID
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// K is the k argument value.
+			K domain.APIKey
+		}
+		// TouchLastUsed holds details about calls to the TouchLastUsed method.
+		TouchLastUsed []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID uuid.UUID
+			// Now is the now argument value.
+			Now time.Time
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// K is the k argument value.
+			K domain.APIKey
+		}
+	}
+	lockFindAll       sync.RWMutex
+	lockFindByHash    sync.RWMutex
+	lockFindByID      sync.RWMutex
+	lockInsert        sync.RWMutex
+	lockTouchLastUsed sync.RWMutex
+	lockUpdate        sync.RWMutex
+}
+
+// FindAll calls FindAllFunc.
+func (mock *APIKeysRepositoryMock) FindAll(ctx context.Context) ([]domain.APIKey, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockFindAll.Lock()
+	mock.calls.FindAll = append(mock.calls.FindAll, callInfo)
+	mock.lockFindAll.Unlock()
+	if mock.FindAllFunc == nil {
+		var (
+			aPIKeysOut []domain.APIKey
+			errOut     error
+		)
+		return aPIKeysOut, errOut
+	}
+	return mock.FindAllFunc(ctx)
+}
+
+// FindAllCalls gets all the calls that were made to FindAll.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.FindAllCalls())
+func (mock *APIKeysRepositoryMock) FindAllCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockFindAll.RLock()
+	calls = mock.calls.FindAll
+	mock.lockFindAll.RUnlock()
+	return calls
+}
+
+// FindByHash calls FindByHashFunc.
+func (mock *APIKeysRepositoryMock) FindByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	callInfo := struct {
+		Ctx  context.Context
+		Hash string
+	}{
+		Ctx:  ctx,
+		Hash: hash,
+	}
+	mock.lockFindByHash.Lock()
+	mock.calls.FindByHash = append(mock.calls.FindByHash, callInfo)
+	mock.lockFindByHash.Unlock()
+	if mock.FindByHashFunc == nil {
+		var (
+			aPIKeyOut domain.APIKey
+			errOut    error
+		)
+		return aPIKeyOut, errOut
+	}
+	return mock.FindByHashFunc(ctx, hash)
+}
+
+// FindByHashCalls gets all the calls that were made to FindByHash.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.FindByHashCalls())
+func (mock *APIKeysRepositoryMock) FindByHashCalls() []struct {
+	Ctx  context.Context
+	Hash string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Hash string
+	}
+	mock.lockFindByHash.RLock()
+	calls = mock.calls.FindByHash
+	mock.lockFindByHash.RUnlock()
+	return calls
+}
+
+// FindByID calls FindByIDFunc.
+func (mock *APIKeysRepositoryMock) FindByID(ctx context.Context, ID uuid.UUID) (domain.APIKey, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	mock.lockFindByID.Lock()
+	mock.calls.FindByID = append(mock.calls.FindByID, callInfo)
+	mock.lockFindByID.Unlock()
+	if mock.FindByIDFunc == nil {
+		var (
+			aPIKeyOut domain.APIKey
+			errOut    error
+		)
+		return aPIKeyOut, errOut
+	}
+	return mock.FindByIDFunc(ctx, ID)
+}
+
+// FindByIDCalls gets all the calls that were made to FindByID.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.FindByIDCalls())
+func (mock *APIKeysRepositoryMock) FindByIDCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockFindByID.RLock()
+	calls = mock.calls.FindByID
+	mock.lockFindByID.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *APIKeysRepositoryMock) Insert(ctx context.Context, k domain.APIKey) error {
+	callInfo := struct {
+		Ctx context.Context
+		K   domain.APIKey
+	}{
+		Ctx: ctx,
+		K:   k,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, k)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.InsertCalls())
+func (mock *APIKeysRepositoryMock) InsertCalls() []struct {
+	Ctx context.Context
+	K   domain.APIKey
+} {
+	var calls []struct {
+		Ctx context.Context
+		K   domain.APIKey
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// TouchLastUsed calls TouchLastUsedFunc.
+func (mock *APIKeysRepositoryMock) TouchLastUsed(ctx context.Context, ID uuid.UUID, now time.Time) error {
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+		Now time.Time
+	}{
+		Ctx: ctx,
+		ID:  ID,
+		Now: now,
+	}
+	mock.lockTouchLastUsed.Lock()
+	mock.calls.TouchLastUsed = append(mock.calls.TouchLastUsed, callInfo)
+	mock.lockTouchLastUsed.Unlock()
+	if mock.TouchLastUsedFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.TouchLastUsedFunc(ctx, ID, now)
+}
+
+// TouchLastUsedCalls gets all the calls that were made to TouchLastUsed.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.TouchLastUsedCalls())
+func (mock *APIKeysRepositoryMock) TouchLastUsedCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+	Now time.Time
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+		Now time.Time
+	}
+	mock.lockTouchLastUsed.RLock()
+	calls = mock.calls.TouchLastUsed
+	mock.lockTouchLastUsed.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *APIKeysRepositoryMock) Update(ctx context.Context, k domain.APIKey) error {
+	callInfo := struct {
+		Ctx context.Context
+		K   domain.APIKey
+	}{
+		Ctx: ctx,
+		K:   k,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, k)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedAPIKeysRepository.UpdateCalls())
+func (mock *APIKeysRepositoryMock) UpdateCalls() []struct {
+	Ctx context.Context
+	K   domain.APIKey
+} {
+	var calls []struct {
+		Ctx context.Context
+		K   domain.APIKey
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// Ensure, that TransactorMock does implement app.Transactor.
+// If this is not the case, regenerate this file with moq.
+var _ app.Transactor = &TransactorMock{}
+
+// TransactorMock is a mock implementation of app.Transactor.
+//
+//	func TestSomethingThatUsesTransactor(t *testing.T) {
+//
+//		// make and configure a mocked app.Transactor
+//		mockedTransactor := &TransactorMock{
+//			WithinTxFunc: func(ctx context.Context, fn func(ctx context.Context) error) error {
+//				panic("mock out the WithinTx method")
+//			},
+//		}
+//
+//		// use mockedTransactor in code that requires app.Transactor
+//		// and then make assertions.
+//
+//	}
+type TransactorMock struct {
+	// WithinTxFunc mocks the WithinTx method.
+	WithinTxFunc func(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// WithinTx holds details about calls to the WithinTx method.
+		WithinTx []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Fn is the fn argument value.
+			Fn func(ctx context.Context) error
+		}
+	}
+	lockWithinTx sync.RWMutex
+}
+
+// WithinTx calls WithinTxFunc.
+func (mock *TransactorMock) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	callInfo := struct {
+		Ctx context.Context
+		Fn  func(ctx context.Context) error
+	}{
+		Ctx: ctx,
+		Fn:  fn,
+	}
+	mock.lockWithinTx.Lock()
+	mock.calls.WithinTx = append(mock.calls.WithinTx, callInfo)
+	mock.lockWithinTx.Unlock()
+	if mock.WithinTxFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.WithinTxFunc(ctx, fn)
+}
+
+// WithinTxCalls gets all the calls that were made to WithinTx.
+// Check the length with:
+//
+//	len(mockedTransactor.WithinTxCalls())
+func (mock *TransactorMock) WithinTxCalls() []struct {
+	Ctx context.Context
+	Fn  func(ctx context.Context) error
+} {
+	var calls []struct {
+		Ctx context.Context
+		Fn  func(ctx context.Context) error
+	}
+	mock.lockWithinTx.RLock()
+	calls = mock.calls.WithinTx
+	mock.lockWithinTx.RUnlock()
+	return calls
+}
+
+// Ensure, that IdempotencyStoreMock does implement app.IdempotencyStore.
+// If this is not the case, regenerate this file with moq.
+var _ app.IdempotencyStore = &IdempotencyStoreMock{}
+
+// IdempotencyStoreMock is a mock implementation of app.IdempotencyStore.
+//
+//	func TestSomethingThatUsesIdempotencyStore(t *testing.T) {
+//
+//		// make and configure a mocked app.IdempotencyStore
+//		mockedIdempotencyStore := &IdempotencyStoreMock{
+//			FindByKeyFunc: func(ctx context.Context, scope string, key string) (app.IdempotencyRecord, error) {
+//				panic("mock out the FindByKey method")
+//			},
+//			ReserveFunc: func(ctx context.Context, r app.IdempotencyRecord) (bool, error) {
+//				panic("mock out the Reserve method")
+//			},
+//		}
+//
+//		// use mockedIdempotencyStore in code that requires app.IdempotencyStore
+//		// and then make assertions.
+//
+//	}
+type IdempotencyStoreMock struct {
+	// FindByKeyFunc mocks the FindByKey method.
+	FindByKeyFunc func(ctx context.Context, scope string, key string) (app.IdempotencyRecord, error)
+
+	// ReserveFunc mocks the Reserve method.
+	ReserveFunc func(ctx context.Context, r app.IdempotencyRecord) (bool, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// FindByKey holds details about calls to the FindByKey method.
+		FindByKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// Key is the key argument value.
+			Key string
+		}
+		// Reserve holds details about calls to the Reserve method.
+		Reserve []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// R is the r argument value.
+			R app.IdempotencyRecord
+		}
+	}
+	lockFindByKey sync.RWMutex
+	lockReserve   sync.RWMutex
+}
+
+// FindByKey calls FindByKeyFunc.
+func (mock *IdempotencyStoreMock) FindByKey(ctx context.Context, scope string, key string) (app.IdempotencyRecord, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Scope string
+		Key   string
+	}{
+		Ctx:   ctx,
+		Scope: scope,
+		Key:   key,
+	}
+	mock.lockFindByKey.Lock()
+	mock.calls.FindByKey = append(mock.calls.FindByKey, callInfo)
+	mock.lockFindByKey.Unlock()
+	if mock.FindByKeyFunc == nil {
+		var (
+			idempotencyRecordOut app.IdempotencyRecord
+			errOut               error
+		)
+		return idempotencyRecordOut, errOut
+	}
+	return mock.FindByKeyFunc(ctx, scope, key)
+}
+
+// FindByKeyCalls gets all the calls that were made to FindByKey.
+// Check the length with:
+//
+//	len(mockedIdempotencyStore.FindByKeyCalls())
+func (mock *IdempotencyStoreMock) FindByKeyCalls() []struct {
+	Ctx   context.Context
+	Scope string
+	Key   string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Scope string
+		Key   string
+	}
+	mock.lockFindByKey.RLock()
+	calls = mock.calls.FindByKey
+	mock.lockFindByKey.RUnlock()
+	return calls
+}
+
+// Reserve calls ReserveFunc.
+func (mock *IdempotencyStoreMock) Reserve(ctx context.Context, r app.IdempotencyRecord) (bool, error) {
+	callInfo := struct {
+		Ctx context.Context
+		R   app.IdempotencyRecord
+	}{
+		Ctx: ctx,
+		R:   r,
+	}
+	mock.lockReserve.Lock()
+	mock.calls.Reserve = append(mock.calls.Reserve, callInfo)
+	mock.lockReserve.Unlock()
+	if mock.ReserveFunc == nil {
+		var (
+			bOut   bool
+			errOut error
+		)
+		return bOut, errOut
+	}
+	return mock.ReserveFunc(ctx, r)
+}
+
+// ReserveCalls gets all the calls that were made to Reserve.
+// Check the length with:
+//
+//	len(mockedIdempotencyStore.ReserveCalls())
+func (mock *IdempotencyStoreMock) ReserveCalls() []struct {
+	Ctx context.Context
+	R   app.IdempotencyRecord
+} {
+	var calls []struct {
+		Ctx context.Context
+		R   app.IdempotencyRecord
+	}
+	mock.lockReserve.RLock()
+	calls = mock.calls.Reserve
+	mock.lockReserve.RUnlock()
+	return calls
+}
+
+// Ensure, that OutboxMock does implement app.Outbox.
+// If this is not the case, regenerate this file with moq.
+var _ app.Outbox = &OutboxMock{}
+
+// OutboxMock is a mock implementation of app.Outbox.
+//
+//	func TestSomethingThatUsesOutbox(t *testing.T) {
+//
+//		// make and configure a mocked app.Outbox
+//		mockedOutbox := &OutboxMock{
+//			DeliverFunc: func(ctx context.Context, limit int) (int, error) {
+//				panic("mock out the Deliver method")
+//			},
+//			FindAfterFunc: func(ctx context.Context, seq int64, limit int) ([]app.OutboxMessage, error) {
+//				panic("mock out the FindAfter method")
+//			},
+//			LastSeqFunc: func(ctx context.Context) (int64, error) {
+//				panic("mock out the LastSeq method")
+//			},
+//			SaveFunc: func(ctx context.Context, msgs []app.OutboxMessage) error {
+//				panic("mock out the Save method")
+//			},
+//		}
+//
+//		// use mockedOutbox in code that requires app.Outbox
+//		// and then make assertions.
+//
+//	}
+type OutboxMock struct {
+	// DeliverFunc mocks the Deliver method.
+	DeliverFunc func(ctx context.Context, limit int) (int, error)
+
+	// FindAfterFunc mocks the FindAfter method.
+	FindAfterFunc func(ctx context.Context, seq int64, limit int) ([]app.OutboxMessage, error)
+
+	// LastSeqFunc mocks the LastSeq method.
+	LastSeqFunc func(ctx context.Context) (int64, error)
+
+	// SaveFunc mocks the Save method.
+	SaveFunc func(ctx context.Context, msgs []app.OutboxMessage) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Deliver holds details about calls to the Deliver method.
+		Deliver []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// FindAfter holds details about calls to the FindAfter method.
+		FindAfter []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Seq is the seq argument value.
+			Seq int64
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// LastSeq holds details about calls to the LastSeq method.
+		LastSeq []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Save holds details about calls to the Save method.
+		Save []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Msgs is the msgs argument value.
+			Msgs []app.OutboxMessage
+		}
+	}
+	lockDeliver   sync.RWMutex
+	lockFindAfter sync.RWMutex
+	lockLastSeq   sync.RWMutex
+	lockSave      sync.RWMutex
+}
+
+// Deliver calls DeliverFunc.
+func (mock *OutboxMock) Deliver(ctx context.Context, limit int) (int, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockDeliver.Lock()
+	mock.calls.Deliver = append(mock.calls.Deliver, callInfo)
+	mock.lockDeliver.Unlock()
+	if mock.DeliverFunc == nil {
+		var (
+			nOut   int
+			errOut error
+		)
+		return nOut, errOut
+	}
+	return mock.DeliverFunc(ctx, limit)
+}
+
+// DeliverCalls gets all the calls that were made to Deliver.
+// Check the length with:
+//
+//	len(mockedOutbox.DeliverCalls())
+func (mock *OutboxMock) DeliverCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockDeliver.RLock()
+	calls = mock.calls.Deliver
+	mock.lockDeliver.RUnlock()
+	return calls
+}
+
+// FindAfter calls FindAfterFunc.
+func (mock *OutboxMock) FindAfter(ctx context.Context, seq int64, limit int) ([]app.OutboxMessage, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Seq   int64
+		Limit int
+	}{
+		Ctx:   ctx,
+		Seq:   seq,
+		Limit: limit,
+	}
+	mock.lockFindAfter.Lock()
+	mock.calls.FindAfter = append(mock.calls.FindAfter, callInfo)
+	mock.lockFindAfter.Unlock()
+	if mock.FindAfterFunc == nil {
+		var (
+			outboxMessagesOut []app.OutboxMessage
+			errOut            error
+		)
+		return outboxMessagesOut, errOut
+	}
+	return mock.FindAfterFunc(ctx, seq, limit)
+}
+
+// FindAfterCalls gets all the calls that were made to FindAfter.
+// Check the length with:
+//
+//	len(mockedOutbox.FindAfterCalls())
+func (mock *OutboxMock) FindAfterCalls() []struct {
+	Ctx   context.Context
+	Seq   int64
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Seq   int64
+		Limit int
+	}
+	mock.lockFindAfter.RLock()
+	calls = mock.calls.FindAfter
+	mock.lockFindAfter.RUnlock()
+	return calls
+}
+
+// LastSeq calls LastSeqFunc.
+func (mock *OutboxMock) LastSeq(ctx context.Context) (int64, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockLastSeq.Lock()
+	mock.calls.LastSeq = append(mock.calls.LastSeq, callInfo)
+	mock.lockLastSeq.Unlock()
+	if mock.LastSeqFunc == nil {
+		var (
+			nOut   int64
+			errOut error
+		)
+		return nOut, errOut
+	}
+	return mock.LastSeqFunc(ctx)
+}
+
+// LastSeqCalls gets all the calls that were made to LastSeq.
+// Check the length with:
+//
+//	len(mockedOutbox.LastSeqCalls())
+func (mock *OutboxMock) LastSeqCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockLastSeq.RLock()
+	calls = mock.calls.LastSeq
+	mock.lockLastSeq.RUnlock()
+	return calls
+}
+
+// Save calls SaveFunc.
+func (mock *OutboxMock) Save(ctx context.Context, msgs []app.OutboxMessage) error {
+	callInfo := struct {
+		Ctx  context.Context
+		Msgs []app.OutboxMessage
+	}{
+		Ctx:  ctx,
+		Msgs: msgs,
+	}
+	mock.lockSave.Lock()
+	mock.calls.Save = append(mock.calls.Save, callInfo)
+	mock.lockSave.Unlock()
+	if mock.SaveFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.SaveFunc(ctx, msgs)
+}
+
+// SaveCalls gets all the calls that were made to Save.
+// Check the length with:
+//
+//	len(mockedOutbox.SaveCalls())
+func (mock *OutboxMock) SaveCalls() []struct {
+	Ctx  context.Context
+	Msgs []app.OutboxMessage
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Msgs []app.OutboxMessage
+	}
+	mock.lockSave.RLock()
+	calls = mock.calls.Save
+	mock.lockSave.RUnlock()
 	return calls
 }
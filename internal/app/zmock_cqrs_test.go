@@ -5,9 +5,9 @@ package app_test
 
 import (
 	"context"
-	"sync"
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
 	"github.com/theskyinflames/cqrs-eda/pkg/events"
+	"sync"
 )
 
 // Ensure, that CommandMock does implement cqrs.Command.
@@ -284,4 +284,4 @@ func (mock *QueryHandlerMock) HandleCalls() []struct {
 	calls = mock.calls.Handle
 	mock.lockHandle.RUnlock()
 	return calls
-}
\ No newline at end of file
+}
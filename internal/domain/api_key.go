@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyEntity is the name of the API keys in the validation errors
+const APIKeyEntity = "api key"
+
+// MaxAPIKeyNameLength is the max number of characters of the name of an API key
+const MaxAPIKeyNameLength = 100
+
+// ErrInvalidAPIKeyName is self-described
+var ErrInvalidAPIKeyName = errors.New("invalid API key name")
+
+// ErrInvalidAPIKeyScope is self-described
+var ErrInvalidAPIKeyScope = errors.New("invalid API key scope")
+
+// ErrInvalidAPIKeyExpiry is self-described
+var ErrInvalidAPIKeyExpiry = errors.New("invalid API key expiry")
+
+// ErrAPIKeyRevoked is self-described
+var ErrAPIKeyRevoked = errors.New("API key revoked")
+
+// APIKey is an entity. It's the credential of a machine client, like a back-office integration.
+// Only the hash of the key is kept, so the key can't be read back once it's been created.
+type APIKey struct {
+	id   uuid.UUID
+	name string
+	hash string
+	// scopes are the roles granted to the clients of the key
+	scopes []string
+	// expiresAt is nil for the keys that don't expire
+	expiresAt  *time.Time
+	lastUsedAt *time.Time
+	revokedAt  *time.Time
+	createdAt  time.Time
+}
+
+// NewAPIKey is a constructor. The hash is the hash of the key given to the client.
+// When the key is not valid, a ValidationError with all the broken rules is returned.
+func NewAPIKey(ID uuid.UUID, name, hash string, scopes []string, expiresAt *time.Time, now time.Time) (APIKey, error) {
+	var vs violations
+	if strings.TrimSpace(name) == "" {
+		vs.add("name", ErrInvalidAPIKeyName, "it's empty")
+	}
+	if utf8.RuneCountInString(name) > MaxAPIKeyNameLength {
+		vs.add("name", ErrInvalidAPIKeyName, fmt.Sprintf("it's longer than %d characters", MaxAPIKeyNameLength))
+	}
+	if len(scopes) == 0 {
+		vs.add("scopes", ErrInvalidAPIKeyScope, "there is none")
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		vs.add("expiresAt", ErrInvalidAPIKeyExpiry, "it's not in the future")
+	}
+	if err := vs.err(APIKeyEntity); err != nil {
+		return APIKey{}, err
+	}
+
+	k := APIKey{
+		id:        ID,
+		name:      name,
+		hash:      hash,
+		scopes:    append([]string(nil), scopes...),
+		createdAt: now.UTC(),
+	}
+	if expiresAt != nil {
+		e := expiresAt.UTC()
+		k.expiresAt = &e
+	}
+	return k, nil
+}
+
+// ID is a getter
+func (k APIKey) ID() uuid.UUID {
+	return k.id
+}
+
+// Name is a getter
+func (k APIKey) Name() string {
+	return k.name
+}
+
+// Hash is a getter
+func (k APIKey) Hash() string {
+	return k.hash
+}
+
+// Scopes is a getter
+func (k APIKey) Scopes() []string {
+	return k.scopes
+}
+
+// ExpiresAt is a getter. It's nil for the keys that don't expire
+func (k APIKey) ExpiresAt() *time.Time {
+	return k.expiresAt
+}
+
+// LastUsedAt is a getter. It's nil for the keys that have never been used
+func (k APIKey) LastUsedAt() *time.Time {
+	return k.lastUsedAt
+}
+
+// RevokedAt is a getter. It's nil for the keys that have not been revoked
+func (k APIKey) RevokedAt() *time.Time {
+	return k.revokedAt
+}
+
+// CreatedAt is a getter
+func (k APIKey) CreatedAt() time.Time {
+	return k.createdAt
+}
+
+// IsActive returns true when the key has been neither revoked nor expired
+func (k APIKey) IsActive(now time.Time) bool {
+	return k.revokedAt == nil && (k.expiresAt == nil || now.Before(*k.expiresAt))
+}
+
+// Revoke is self-described. A revoked key can't be used anymore
+func (k *APIKey) Revoke(now time.Time) error {
+	if k.revokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+	r := now.UTC()
+	k.revokedAt = &r
+	return nil
+}
+
+// Hydrate hydrates an API key instance. It's used to retrieve entities from DB.
+func (k *APIKey) Hydrate(ID uuid.UUID, name, hash string, scopes []string, expiresAt, lastUsedAt, revokedAt *time.Time, createdAt time.Time) {
+	k.id = ID
+	k.name = name
+	k.hash = hash
+	k.scopes = scopes
+	k.expiresAt = expiresAt
+	k.lastUsedAt = lastUsedAt
+	k.revokedAt = revokedAt
+	k.createdAt = createdAt
+}
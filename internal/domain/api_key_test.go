@@ -0,0 +1,91 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	var (
+		now     = time.Now()
+		future  = now.Add(time.Hour)
+		past    = now.Add(-time.Hour)
+		longStr = strings.Repeat("a", domain.MaxAPIKeyNameLength+1)
+	)
+	testCases := []struct {
+		name           string
+		keyName        string
+		scopes         []string
+		expiresAt      *time.Time
+		expectedFields []string
+	}{
+		{
+			name:    `Given a valid key without expiry, when it's created, then no error is returned`,
+			keyName: "erp",
+			scopes:  []string{"merchandiser"},
+		},
+		{
+			name:      `Given a valid key with expiry, when it's created, then no error is returned`,
+			keyName:   "erp",
+			scopes:    []string{"merchandiser"},
+			expiresAt: &future,
+		},
+		{
+			name:           `Given a key with all its fields invalid, when it's created, then all of them are reported`,
+			keyName:        " ",
+			expiresAt:      &past,
+			expectedFields: []string{"name", "scopes", "expiresAt"},
+		},
+		{
+			name:           `Given a key with a too long name, when it's created, then an error is returned`,
+			keyName:        longStr,
+			scopes:         []string{"admin"},
+			expectedFields: []string{"name"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k, err := domain.NewAPIKey(uuid.New(), tc.keyName, "hash", tc.scopes, tc.expiresAt, now)
+			if tc.expectedFields == nil {
+				require.NoError(t, err)
+				require.Equal(t, tc.scopes, k.Scopes())
+				require.True(t, k.IsActive(now))
+				return
+			}
+			var verr domain.ValidationError
+			require.ErrorAs(t, err, &verr)
+			var fields []string
+			for _, v := range verr.Violations {
+				fields = append(fields, v.Field)
+			}
+			require.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
+
+func TestAPIKeyIsActive(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	k, err := domain.NewAPIKey(uuid.New(), "erp", "hash", []string{"admin"}, &expiresAt, now)
+	require.NoError(t, err)
+
+	t.Run(`Given a key, when its expiry is reached, then it's not active`, func(t *testing.T) {
+		require.True(t, k.IsActive(expiresAt.Add(-time.Second)))
+		require.False(t, k.IsActive(expiresAt))
+	})
+
+	t.Run(`Given a key, when it's revoked, then it's not active and it can't be revoked again`, func(t *testing.T) {
+		revoked := k
+		require.NoError(t, revoked.Revoke(now))
+		require.NotNil(t, revoked.RevokedAt())
+		require.False(t, revoked.IsActive(now))
+		require.ErrorIs(t, revoked.Revoke(now), domain.ErrAPIKeyRevoked)
+	})
+}
@@ -1,20 +1,349 @@
 package domain
 
 import (
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/theskyinflames/cqrs-eda/pkg/events"
 )
 
+// ProductCreatedEventName is self-described
+const ProductCreatedEventName = "product.created"
+
+// ProductCreatedEvent is an event. It's the first event of the history of a product
+type ProductCreatedEvent struct {
+	events.EventBasic
+	ProductName string
+	// Price is nil when the product has not been priced yet
+	Price *Money
+	Stock int
+}
+
+// NewProductCreatedEvent is a constructor
+func NewProductCreatedEvent(p Product) ProductCreatedEvent {
+	return ProductCreatedEvent{
+		EventBasic:  events.NewEventBasic(p.ID(), ProductCreatedEventName, nil),
+		ProductName: p.name,
+		Price:       p.price,
+		Stock:       p.stock,
+	}
+}
+
+// Hydrate hydrates a product created event. It's used to retrieve events from DB.
+func (e *ProductCreatedEvent) Hydrate(ID, productID uuid.UUID, productName string, price *Money, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductCreatedEventName, nil)
+	e.ID = ID
+	e.ProductName = productName
+	e.Price = price
+	e.Stock = stock
+}
+
 // ProductPurchasedEventName is self-described
 const ProductPurchasedEventName = "product.purchased"
 
 // ProductPurchasedEvent is an event
 type ProductPurchasedEvent struct {
 	events.EventBasic
+	Quantity int
+	// Stock is the number of units left after the purchase
+	Stock int
 }
 
-// NewProductPurchasedEvent is a constructor
-func NewProductPurchasedEvent(p Product) ProductPurchasedEvent {
+// NewProductPurchasedEvent is a constructor. The product is the one before the purchase
+func NewProductPurchasedEvent(p Product, quantity int) ProductPurchasedEvent {
 	return ProductPurchasedEvent{
 		EventBasic: events.NewEventBasic(p.ID(), ProductPurchasedEventName, nil),
+		Quantity:   quantity,
+		Stock:      p.stock - quantity,
+	}
+}
+
+// Hydrate hydrates a product purchased event. It's used to retrieve events from DB.
+func (e *ProductPurchasedEvent) Hydrate(ID, productID uuid.UUID, quantity, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductPurchasedEventName, nil)
+	e.ID = ID
+	e.Quantity = quantity
+	e.Stock = stock
+}
+
+// ProductRefundedEventName is self-described
+const ProductRefundedEventName = "product.refunded"
+
+// ProductRefundedEvent is an event
+type ProductRefundedEvent struct {
+	events.EventBasic
+	Quantity int
+	// Stock is the number of units left after the refund
+	Stock int
+	// Status is the status of the product after the refund
+	Status Status
+}
+
+// NewProductRefundedEvent is a constructor. The product is the one before the refund
+func NewProductRefundedEvent(p Product, quantity int) ProductRefundedEvent {
+	return ProductRefundedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductRefundedEventName, nil),
+		Quantity:   quantity,
+		Stock:      p.stock + quantity,
+		Status:     refundedStatus(p.status),
+	}
+}
+
+// Hydrate hydrates a product refunded event. It's used to retrieve events from DB.
+func (e *ProductRefundedEvent) Hydrate(ID, productID uuid.UUID, quantity, stock int, status Status) {
+	e.EventBasic = events.NewEventBasic(productID, ProductRefundedEventName, nil)
+	e.ID = ID
+	e.Quantity = quantity
+	e.Stock = stock
+	e.Status = status
+}
+
+// ProductPriceChangedEventName is self-described
+const ProductPriceChangedEventName = "product.price_changed"
+
+// ProductPriceChangedEvent is an event
+type ProductPriceChangedEvent struct {
+	events.EventBasic
+	Price Money
+}
+
+// NewProductPriceChangedEvent is a constructor
+func NewProductPriceChangedEvent(p Product, price Money) ProductPriceChangedEvent {
+	return ProductPriceChangedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductPriceChangedEventName, nil),
+		Price:      price,
+	}
+}
+
+// Hydrate hydrates a product price changed event. It's used to retrieve events from DB.
+func (e *ProductPriceChangedEvent) Hydrate(ID, productID uuid.UUID, price Money) {
+	e.EventBasic = events.NewEventBasic(productID, ProductPriceChangedEventName, nil)
+	e.ID = ID
+	e.Price = price
+}
+
+// ProductRenamedEventName is self-described
+const ProductRenamedEventName = "product.renamed"
+
+// ProductRenamedEvent is an event
+type ProductRenamedEvent struct {
+	events.EventBasic
+	ProductName string
+}
+
+// NewProductRenamedEvent is a constructor
+func NewProductRenamedEvent(p Product, name string) ProductRenamedEvent {
+	return ProductRenamedEvent{
+		EventBasic:  events.NewEventBasic(p.ID(), ProductRenamedEventName, nil),
+		ProductName: name,
+	}
+}
+
+// Hydrate hydrates a product renamed event. It's used to retrieve events from DB.
+func (e *ProductRenamedEvent) Hydrate(ID, productID uuid.UUID, name string) {
+	e.EventBasic = events.NewEventBasic(productID, ProductRenamedEventName, nil)
+	e.ID = ID
+	e.ProductName = name
+}
+
+// ProductPublishedEventName is self-described
+const ProductPublishedEventName = "product.published"
+
+// ProductPublishedEvent is an event. It's recorded when the product is put on sale,
+// not when it's put back on sale by a refund.
+type ProductPublishedEvent struct {
+	events.EventBasic
+	// Stock is the number of units left
+	Stock int
+}
+
+// NewProductPublishedEvent is a constructor
+func NewProductPublishedEvent(p Product) ProductPublishedEvent {
+	return ProductPublishedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductPublishedEventName, nil),
+		Stock:      p.stock,
 	}
 }
+
+// Hydrate hydrates a product published event. It's used to retrieve events from DB.
+func (e *ProductPublishedEvent) Hydrate(ID, productID uuid.UUID, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductPublishedEventName, nil)
+	e.ID = ID
+	e.Stock = stock
+}
+
+// ProductUnpublishedEventName is self-described
+const ProductUnpublishedEventName = "product.unpublished"
+
+// ProductUnpublishedEvent is an event. It's recorded when the product is withdrawn from sale and goes back to draft.
+type ProductUnpublishedEvent struct {
+	events.EventBasic
+	// Stock is the number of units left
+	Stock int
+}
+
+// NewProductUnpublishedEvent is a constructor
+func NewProductUnpublishedEvent(p Product) ProductUnpublishedEvent {
+	return ProductUnpublishedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductUnpublishedEventName, nil),
+		Stock:      p.stock,
+	}
+}
+
+// Hydrate hydrates a product unpublished event. It's used to retrieve events from DB.
+func (e *ProductUnpublishedEvent) Hydrate(ID, productID uuid.UUID, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductUnpublishedEventName, nil)
+	e.ID = ID
+	e.Stock = stock
+}
+
+// ProductArchivedEventName is self-described
+const ProductArchivedEventName = "product.archived"
+
+// ProductArchivedEvent is an event
+type ProductArchivedEvent struct {
+	events.EventBasic
+	// From is the status of the product before it was archived
+	From Status
+	// Stock is the number of units left
+	Stock int
+}
+
+// NewProductArchivedEvent is a constructor. The product is the one before being archived
+func NewProductArchivedEvent(p Product) ProductArchivedEvent {
+	return ProductArchivedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductArchivedEventName, nil),
+		From:       p.status,
+		Stock:      p.stock,
+	}
+}
+
+// Hydrate hydrates a product archived event. It's used to retrieve events from DB.
+func (e *ProductArchivedEvent) Hydrate(ID, productID uuid.UUID, from Status, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductArchivedEventName, nil)
+	e.ID = ID
+	e.From = from
+	e.Stock = stock
+}
+
+// ProductReservedEventName is self-described
+const ProductReservedEventName = "product.reserved"
+
+// ProductReservedEvent is an event. It's recorded when the product is put on hold, and when its hold is renewed
+type ProductReservedEvent struct {
+	events.EventBasic
+	// Holder is the hash of the holder
+	Holder    string
+	ExpiresAt time.Time
+	// Stock is the number of units left
+	Stock int
+}
+
+// NewProductReservedEvent is a constructor
+func NewProductReservedEvent(p Product, r Reservation) ProductReservedEvent {
+	return ProductReservedEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductReservedEventName, nil),
+		Holder:     r.holder,
+		ExpiresAt:  r.expiresAt,
+		Stock:      p.stock,
+	}
+}
+
+// Hydrate hydrates a product reserved event. It's used to retrieve events from DB.
+func (e *ProductReservedEvent) Hydrate(ID, productID uuid.UUID, holder string, expiresAt time.Time, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductReservedEventName, nil)
+	e.ID = ID
+	e.Holder = holder
+	e.ExpiresAt = expiresAt
+	e.Stock = stock
+}
+
+// ProductReservationExpiredEventName is self-described
+const ProductReservationExpiredEventName = "product.reservation_expired"
+
+// ProductReservationExpiredEvent is an event. It's recorded when the hold of the product expires and it's put back on sale
+type ProductReservationExpiredEvent struct {
+	events.EventBasic
+	// Holder is the hash of the holder
+	Holder string
+	// Stock is the number of units left
+	Stock int
+}
+
+// NewProductReservationExpiredEvent is a constructor. The product is the one before its hold expired
+func NewProductReservationExpiredEvent(p Product) ProductReservationExpiredEvent {
+	var holder string
+	if p.reservation != nil {
+		holder = p.reservation.holder
+	}
+	return ProductReservationExpiredEvent{
+		EventBasic: events.NewEventBasic(p.ID(), ProductReservationExpiredEventName, nil),
+		Holder:     holder,
+		Stock:      p.stock,
+	}
+}
+
+// Hydrate hydrates a product reservation expired event. It's used to retrieve events from DB.
+func (e *ProductReservationExpiredEvent) Hydrate(ID, productID uuid.UUID, holder string, stock int) {
+	e.EventBasic = events.NewEventBasic(productID, ProductReservationExpiredEventName, nil)
+	e.ID = ID
+	e.Holder = holder
+	e.Stock = stock
+}
+
+// ProductSnapshotTakenEventName is self-described
+const ProductSnapshotTakenEventName = "product.snapshot_taken"
+
+// ProductSnapshotTakenEvent is an event. It records the whole state of the product, so its history can go on
+// from it when the product has been changed without recording its events. The events before it are kept,
+// but they're not needed to rebuild the product, so a history can also start with it.
+type ProductSnapshotTakenEvent struct {
+	events.EventBasic
+	ProductName string
+	// Price is nil when the product has not been priced yet
+	Price  *Money
+	Status Status
+	// Holder is the hash of the holder, and it's empty when the product is not reserved
+	Holder    string
+	ExpiresAt time.Time
+	Stock     int
+	Sold      int
+}
+
+// NewProductSnapshotTakenEvent is a constructor
+func NewProductSnapshotTakenEvent(p Product) ProductSnapshotTakenEvent {
+	ev := ProductSnapshotTakenEvent{
+		EventBasic:  events.NewEventBasic(p.ID(), ProductSnapshotTakenEventName, nil),
+		ProductName: p.name,
+		Price:       p.price,
+		Status:      p.status,
+		Stock:       p.stock,
+		Sold:        p.sold,
+	}
+	if p.reservation != nil {
+		ev.Holder = p.reservation.holder
+		ev.ExpiresAt = p.reservation.expiresAt
+	}
+	return ev
+}
+
+// Hydrate hydrates a product snapshot taken event. It's used to retrieve events from DB.
+func (e *ProductSnapshotTakenEvent) Hydrate(
+	ID, productID uuid.UUID,
+	productName string,
+	price *Money,
+	status Status,
+	holder string,
+	expiresAt time.Time,
+	stock, sold int,
+) {
+	e.EventBasic = events.NewEventBasic(productID, ProductSnapshotTakenEventName, nil)
+	e.ID = ID
+	e.ProductName = productName
+	e.Price = price
+	e.Status = status
+	e.Holder = holder
+	e.ExpiresAt = expiresAt
+	e.Stock = stock
+	e.Sold = sold
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MoneyScale is the number of decimal digits an amount of money can have.
+// It's the same scale used by the price column in DB.
+const MoneyScale = 6
+
+const moneyUnit = 1_000_000 // 10^MoneyScale
+
+// MoneyIntegerDigits is the number of integer digits an amount of money can have.
+// It's the same precision used by the price column in DB, numeric(15,6).
+const MoneyIntegerDigits = 9
+
+// maxMoneyUnits is the largest amount of money that fits in the price column, in millionths of the currency unit
+const maxMoneyUnits = 999_999_999_999_999
+
+// DefaultCurrency is the currency used when none is specified
+const DefaultCurrency = "EUR"
+
+// ErrInvalidMoney is self-described
+var ErrInvalidMoney = errors.New("invalid money")
+
+// ErrMoneyOutOfRange is self-described
+var ErrMoneyOutOfRange = fmt.Errorf("%w: out of range", ErrInvalidMoney)
+
+// Money is a value object. It represents an exact amount of a currency.
+// The amount is kept as an integer number of millionths of the currency unit, so it's never rounded.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// ParseMoney is a constructor. The amount is a decimal number like "10.99" and the currency is an ISO 4217 code like "EUR".
+// Amounts with more decimal digits than MoneyScale are rejected instead of being rounded, and the ones with more integer digits
+// than MoneyIntegerDigits are rejected with a ValidationError, since they can't be stored.
+func ParseMoney(amount, currency string) (Money, error) {
+	if !validCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: currency %q is not an ISO 4217 code", ErrInvalidMoney, currency)
+	}
+
+	// Only a single leading minus sign is allowed, so the rest must be digits and a decimal point
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return Money{}, fmt.Errorf("%w: amount %q is not a decimal number", ErrInvalidMoney, amount)
+	}
+	if len(fracPart) > MoneyScale {
+		return Money{}, fmt.Errorf("%w: amount %q has more than %d decimal digits", ErrInvalidMoney, amount, MoneyScale)
+	}
+	fracPart += strings.Repeat("0", MoneyScale-len(fracPart))
+
+	var units int64
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("%w: amount %q is not a decimal number", ErrInvalidMoney, amount)
+		}
+		if units > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, outOfRange(amount)
+		}
+		units = units*10 + int64(r-'0')
+	}
+	if units > maxMoneyUnits {
+		return Money{}, outOfRange(amount)
+	}
+	if negative {
+		units = -units
+	}
+
+	return Money{amount: units, currency: currency}, nil
+}
+
+// outOfRange returns the ValidationError of an amount with too many integer digits
+func outOfRange(amount string) error {
+	var vs violations
+	vs.add("amount", ErrMoneyOutOfRange, fmt.Sprintf("amount %q has more than %d integer digits", amount, MoneyIntegerDigits))
+	return vs.err("money")
+}
+
+// validCurrency checks that the currency looks like an ISO 4217 code
+func validCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Currency is a getter
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Amount returns the amount as a decimal number. It has at least two decimal digits, like "10.99" or "40.00"
+func (m Money) Amount() string {
+	units := m.amount
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", MoneyScale, units%moneyUnit), "0")
+	if len(frac) < 2 {
+		frac += strings.Repeat("0", 2-len(frac))
+	}
+	return fmt.Sprintf("%s%d.%s", sign, units/moneyUnit, frac)
+}
+
+// Multiply returns the amount multiplied by n. It returns ErrMoneyOutOfRange when the result overflows
+func (m Money) Multiply(n int) (Money, error) {
+	a, b := m.amount, int64(n)
+	product := a * b
+	if b != 0 && (product/b != a || (b == -1 && a == math.MinInt64)) {
+		return Money{}, fmt.Errorf("%w: %s multiplied by %d", ErrMoneyOutOfRange, m, n)
+	}
+	return Money{amount: product, currency: m.currency}, nil
+}
+
+// IsNegative is self-described
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// LessThan is self-described. It only compares the amounts, so both must be of the same currency
+func (m Money) LessThan(other Money) bool {
+	return m.amount < other.amount
+}
+
+// Equal is self-described
+func (m Money) Equal(other Money) bool {
+	return m == other
+}
+
+// String implements fmt.Stringer interface
+func (m Money) String() string {
+	return m.Amount() + " " + m.currency
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount(), Currency: m.currency})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var mj moneyJSON
+	if err := json.Unmarshal(b, &mj); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(mj.Amount, mj.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
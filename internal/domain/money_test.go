@@ -0,0 +1,228 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		name           string
+		amount         string
+		currency       string
+		expectedAmount string
+		expectedErr    error
+	}{
+		{
+			name:        `Given a currency which is not an ISO 4217 code, when it's parsed, then an error is returned`,
+			amount:      "1.1",
+			currency:    "euro",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount which is not a number, when it's parsed, then an error is returned`,
+			amount:      "1,1",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount with malformed signs, when it's parsed, then an error is returned`,
+			amount:      "-+5",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount with a plus sign, when it's parsed, then an error is returned`,
+			amount:      "+5",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount with two minus signs, when it's parsed, then an error is returned`,
+			amount:      "--5",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an empty amount, when it's parsed, then an error is returned`,
+			amount:      "",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount with more decimal digits than supported, when it's parsed, then an error is returned`,
+			amount:      "0.1234567",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount out of range, when it's parsed, then an error is returned`,
+			amount:      "99999999999999999999",
+			currency:    "EUR",
+			expectedErr: domain.ErrInvalidMoney,
+		},
+		{
+			name:        `Given an amount with more integer digits than the DB can store, when it's parsed, then an error is returned`,
+			amount:      "1000000000",
+			currency:    "EUR",
+			expectedErr: domain.ErrMoneyOutOfRange,
+		},
+		{
+			name:           `Given the largest amount the DB can store, when it's parsed, then it's kept`,
+			amount:         "-999999999.999999",
+			currency:       "EUR",
+			expectedAmount: "-999999999.999999",
+		},
+		{
+			name:           `Given a valid amount, when it's parsed, then it's kept without rounding`,
+			amount:         "10.990000",
+			currency:       "EUR",
+			expectedAmount: "10.99",
+		},
+		{
+			name:           `Given a valid amount with the maximum number of decimal digits, when it's parsed, then it's kept without rounding`,
+			amount:         "0.000001",
+			currency:       "USD",
+			expectedAmount: "0.000001",
+		},
+		{
+			name:           `Given an integer amount, when it's parsed, then it's returned with two decimal digits`,
+			amount:         "40",
+			currency:       "EUR",
+			expectedAmount: "40.00",
+		},
+		{
+			name:           `Given a negative amount, when it's parsed, then the sign is kept`,
+			amount:         "-0.5",
+			currency:       "EUR",
+			expectedAmount: "-0.50",
+		},
+	}
+
+	for _, tc := range testCases {
+		m, err := domain.ParseMoney(tc.amount, tc.currency)
+		if tc.expectedErr != nil {
+			require.ErrorIs(t, err, tc.expectedErr, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedAmount, m.Amount(), tc.name)
+		require.Equal(t, tc.currency, m.Currency(), tc.name)
+	}
+}
+
+func TestMoneyIsExact(t *testing.T) {
+	t.Run(`Given two amounts which are not exact as float64, 
+			when they are parsed, 
+			then they are exactly equal to their decimal representation`, func(t *testing.T) {
+		a, err := domain.ParseMoney("0.3", "EUR")
+		require.NoError(t, err)
+		b, err := domain.ParseMoney("0.300000", "EUR")
+		require.NoError(t, err)
+		require.True(t, a.Equal(b))
+		require.Equal(t, "0.30 EUR", a.String())
+	})
+}
+
+func TestParseMoneyOutOfRange(t *testing.T) {
+	_, err := domain.ParseMoney("1000000000.5", "EUR")
+	var verr domain.ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Violations, 1)
+	require.Equal(t, "amount", verr.Violations[0].Field)
+	require.ErrorIs(t, err, domain.ErrInvalidMoney)
+}
+
+func TestMoneyMultiply(t *testing.T) {
+	price, err := domain.ParseMoney("999999999.999999", "EUR")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		n              int
+		expectedAmount string
+		expectedErr    error
+	}{
+		{
+			name: `Given an amount and a quantity whose product fits, 
+				when they're multiplied, 
+				then the product is returned`,
+			n:              9000,
+			expectedAmount: "8999999999999.991",
+		},
+		{
+			name: `Given an amount and a quantity whose product overflows, 
+				when they're multiplied, 
+				then an error is returned`,
+			n:           10_000,
+			expectedErr: domain.ErrMoneyOutOfRange,
+		},
+		{
+			name: `Given a negative quantity whose product overflows, 
+				when they're multiplied, 
+				then an error is returned`,
+			n:           -10_000,
+			expectedErr: domain.ErrMoneyOutOfRange,
+		},
+		{
+			name: `Given a zero quantity, 
+				when they're multiplied, 
+				then the product is zero`,
+			n:              0,
+			expectedAmount: "0.00",
+		},
+	}
+
+	for _, tc := range testCases {
+		m, err := price.Multiply(tc.n)
+		if tc.expectedErr != nil {
+			require.ErrorIs(t, err, tc.expectedErr, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedAmount, m.Amount(), tc.name)
+		require.Equal(t, "EUR", m.Currency(), tc.name)
+	}
+}
+
+func TestMoneyLessThan(t *testing.T) {
+	t.Run(`Given two amounts of the same currency, 
+			when they are compared, 
+			then the lower one is less than the other`, func(t *testing.T) {
+		a, err := domain.ParseMoney("10.99", "EUR")
+		require.NoError(t, err)
+		b, err := domain.ParseMoney("10.990001", "EUR")
+		require.NoError(t, err)
+		require.True(t, a.LessThan(b))
+		require.False(t, b.LessThan(a))
+		require.False(t, a.LessThan(a))
+	})
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Run(`Given an amount of money, 
+			when it's encoded to JSON and decoded back, 
+			then it's the same amount`, func(t *testing.T) {
+		m, err := domain.ParseMoney("10.990001", "USD")
+		require.NoError(t, err)
+		b, err := json.Marshal(m)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"amount":"10.990001","currency":"USD"}`, string(b))
+
+		var decoded domain.Money
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		require.True(t, m.Equal(decoded))
+	})
+
+	t.Run(`Given an invalid JSON amount of money, 
+			when it's decoded, 
+			then it returns an error`, func(t *testing.T) {
+		var decoded domain.Money
+		err := json.Unmarshal([]byte(`{"amount":"1,1","currency":"EUR"}`), &decoded)
+		require.ErrorIs(t, err, domain.ErrInvalidMoney)
+	})
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/ddd"
+)
+
+// Order is an entity. It records the purchase of a product
+type Order struct {
+	ddd.AggregateBasic
+
+	productID uuid.UUID
+	// buyerID is the ID of the buyer who purchased the product. It's empty for the anonymous purchases
+	buyerID string
+	// price is a snapshot of the product unit price when it was purchased
+	price       Money
+	quantity    int
+	purchasedAt time.Time
+	// refunded is the number of units of the order that have been refunded
+	refunded   int
+	refundedAt *time.Time
+}
+
+// NewOrder is a constructor
+func NewOrder(ID, productID uuid.UUID, buyerID string, price Money, quantity int, purchasedAt time.Time) Order {
+	return Order{
+		AggregateBasic: ddd.NewAggregateBasic(ID),
+		productID:      productID,
+		buyerID:        buyerID,
+		price:          price,
+		quantity:       quantity,
+		purchasedAt:    purchasedAt,
+	}
+}
+
+// ProductID is a getter
+func (o Order) ProductID() uuid.UUID {
+	return o.productID
+}
+
+// BuyerID is a getter
+func (o Order) BuyerID() string {
+	return o.buyerID
+}
+
+// Price is a getter
+func (o Order) Price() Money {
+	return o.price
+}
+
+// Quantity is a getter
+func (o Order) Quantity() int {
+	return o.quantity
+}
+
+// Total returns the unit price multiplied by the quantity. It returns ErrMoneyOutOfRange when it overflows
+func (o Order) Total() (Money, error) {
+	return o.price.Multiply(o.quantity)
+}
+
+// PurchasedAt is a getter
+func (o Order) PurchasedAt() time.Time {
+	return o.purchasedAt
+}
+
+// Refunded is a getter
+func (o Order) Refunded() int {
+	return o.refunded
+}
+
+// RefundedAt is a getter. It's the time of the last refund, or nil when the order has not been refunded
+func (o Order) RefundedAt() *time.Time {
+	return o.refundedAt
+}
+
+// ErrRefundExceedsOrder is self-described
+var ErrRefundExceedsOrder = errors.New("refund exceeds the units of the order not refunded yet")
+
+// Refund records the refund of the given quantity of units of the order.
+// The units of an order can only be refunded once
+func (o *Order) Refund(quantity int, now time.Time) error {
+	if quantity < 1 {
+		return ErrInvalidQuantity
+	}
+	if o.refunded+quantity > o.quantity {
+		return ErrRefundExceedsOrder
+	}
+	o.refunded += quantity
+	o.refundedAt = &now
+	return nil
+}
+
+// Hydrate hydrates an order instance. It's used to retrieve entities from DB.
+func (o *Order) Hydrate(
+	ID, productID uuid.UUID,
+	buyerID string,
+	price Money,
+	quantity int,
+	purchasedAt time.Time,
+	refunded int,
+	refundedAt *time.Time,
+) {
+	o.AggregateBasic = ddd.NewAggregateBasic(ID)
+	o.productID = productID
+	o.buyerID = buyerID
+	o.price = price
+	o.quantity = quantity
+	o.purchasedAt = purchasedAt
+	o.refunded = refunded
+	o.refundedAt = refundedAt
+}
@@ -0,0 +1,38 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRefund(t *testing.T) {
+	now := time.Now()
+
+	t.Run(`Given an order of two units, 
+		when its units are refunded one by one, 
+		then they're recorded until all of them have been refunded`, func(t *testing.T) {
+		o := domain.NewOrder(uuid.New(), uuid.New(), "buyer", fixtures.Money("1.1"), 2, now)
+		require.NoError(t, o.Refund(1, now))
+		require.NoError(t, o.Refund(1, now))
+		require.Equal(t, 2, o.Refunded())
+		require.Equal(t, now, *o.RefundedAt())
+		require.ErrorIs(t, o.Refund(1, now), domain.ErrRefundExceedsOrder)
+		require.Equal(t, 2, o.Refunded())
+	})
+
+	t.Run(`Given an order, 
+		when more units than purchased are refunded, 
+		then an error is returned and nothing is recorded`, func(t *testing.T) {
+		o := domain.NewOrder(uuid.New(), uuid.New(), "buyer", fixtures.Money("1.1"), 1, now)
+		require.ErrorIs(t, o.Refund(2, now), domain.ErrRefundExceedsOrder)
+		require.ErrorIs(t, o.Refund(0, now), domain.ErrInvalidQuantity)
+		require.Zero(t, o.Refunded())
+		require.Nil(t, o.RefundedAt())
+	})
+}
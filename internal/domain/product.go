@@ -2,29 +2,102 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/theskyinflames/cqrs-eda/pkg/ddd"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
 )
 
 // Product is an entity
 type Product struct {
 	ddd.AggregateBasic
 
-	name      string
-	available bool
-	// float64 type used for simplicity, but in a real service a lib like better use github.com/shopspring/decimal
-	// take a look to this article to understand which is the problem with using float64 for currency values:
-	//    https://waclawthedev.medium.com/inaccurate-float32-and-float64-how-to-avoid-the-trap-in-go-golang-6de59e66aed9
-	price float64
+	name string
+	// status is the stage of the lifecycle of the product. The archived products are kept because their orders refer to them,
+	// but they can't be sold nor changed anymore
+	status Status
+	// price is nil when the product has not been priced yet
+	price *Money
+	// reservation is the hold of the product for a buyer. It's only set when the product is reserved
+	reservation *Reservation
+	// stock is the number of units left. Unique products are products with only one unit
+	stock int
+	// sold is the number of units purchased and not refunded
+	sold int
+	// version is used for optimistic concurrency control. It's the version of the product when it was read from DB
+	version int
 }
 
-// NewProduct is a constructor
-func NewProduct(ID uuid.UUID, name string, price float64) Product {
-	return Product{
+// MaxProductNameLength is the max number of characters of the name of a product
+const MaxProductNameLength = 100
+
+// ErrInvalidProductName is self-described
+var ErrInvalidProductName = errors.New("invalid product name")
+
+// ErrInvalidStock is self-described
+var ErrInvalidStock = errors.New("invalid stock")
+
+// NewProduct is a constructor. The product is published when it has stock, and it's a draft otherwise.
+// A nil price means that the product has not been priced yet.
+// When the product is not valid, a ValidationError with all the broken rules is returned.
+func NewProduct(ID uuid.UUID, name string, price *Money, stock int) (Product, error) {
+	if err := validateProduct(name, price, stock); err != nil {
+		return Product{}, err
+	}
+
+	p := Product{
 		AggregateBasic: ddd.NewAggregateBasic(ID),
 		name:           name,
 		price:          price,
+		stock:          stock,
+		status:         initialStatus(stock),
+	}
+	p.RecordEvent(NewProductCreatedEvent(p))
+	return p, nil
+}
+
+const productEntity = "product"
+
+func initialStatus(stock int) Status {
+	if stock > 0 {
+		return StatusPublished
+	}
+	return StatusDraft
+}
+
+// validateProduct checks the rules on the fields of a new product, and returns a ValidationError with the broken ones
+func validateProduct(name string, price *Money, stock int) error {
+	var vs violations
+	validateProductName(&vs, name)
+	if price != nil {
+		validatePrice(&vs, *price)
+	}
+	if stock < 0 {
+		vs.add("stock", ErrInvalidStock, fmt.Sprintf("it's negative: %d", stock))
+	}
+	return vs.err(productEntity)
+}
+
+// validateProductName checks that the name fits in the name column of the products table
+func validateProductName(vs *violations, name string) {
+	if strings.TrimSpace(name) == "" {
+		vs.add("name", ErrInvalidProductName, "it's empty")
+	}
+	if utf8.RuneCountInString(name) > MaxProductNameLength {
+		vs.add("name", ErrInvalidProductName, fmt.Sprintf("it's longer than %d characters", MaxProductNameLength))
+	}
+}
+
+func validatePrice(vs *violations, price Money) {
+	if price.IsNegative() {
+		vs.add("price", ErrInvalidMoney, fmt.Sprintf("it's negative: %s", price.Amount()))
+	}
+	if !validCurrency(price.Currency()) {
+		vs.add("price", ErrInvalidMoney, fmt.Sprintf("currency %q is not an ISO 4217 code", price.Currency()))
 	}
 }
 
@@ -33,44 +106,352 @@ func (p Product) Name() string {
 	return p.name
 }
 
-// Available is a getter
+// Status is a getter
+func (p Product) Status() Status {
+	return p.status
+}
+
+// Available is derived from the status. Only the published products are on sale
 func (p Product) Available() bool {
-	return p.available
+	return p.status == StatusPublished
+}
+
+// Price is a getter. The second returned value is false when the product has no price
+func (p Product) Price() (Money, bool) {
+	if p.price == nil {
+		return Money{}, false
+	}
+	return *p.price, true
 }
 
-// Price is a getter
-func (p Product) Price() float64 {
-	return p.price
+// Reservation is a getter. The second returned value is false when the product is not reserved
+func (p Product) Reservation() (Reservation, bool) {
+	if p.reservation == nil {
+		return Reservation{}, false
+	}
+	return *p.reservation, true
+}
+
+// Archived is derived from the status
+func (p Product) Archived() bool {
+	return p.status == StatusArchived
+}
+
+// Stock is a getter
+func (p Product) Stock() int {
+	return p.stock
+}
+
+// Sold is a getter
+func (p Product) Sold() int {
+	return p.sold
+}
+
+// Version is a getter
+func (p Product) Version() int {
+	return p.version
 }
 
 // ErrProductPurchased is self-described
 var ErrProductPurchased = errors.New("product not available for purchasing")
 
-// Purchase tries to purchase the product
-func (p *Product) Purchase() error {
-	if !p.available {
+// ErrProductWithoutPrice is self-described
+var ErrProductWithoutPrice = errors.New("product without price")
+
+// ErrInvalidQuantity is self-described
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+// ErrInsufficientStock is self-described
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrProductReserved is self-described
+var ErrProductReserved = errors.New("product reserved by another buyer")
+
+// Purchase tries to purchase the given quantity of units of the product without being its holder.
+// Only the published products can be purchased. When there is no stock left, the product is sold.
+func (p *Product) Purchase(quantity int) error {
+	return p.PurchaseBy("", quantity)
+}
+
+// PurchaseBy tries to purchase the given quantity of units of the product for the given holder, which can be empty.
+// The published products can be purchased by anyone, and the reserved ones only by their holder.
+// When there is no stock left, the product is sold.
+func (p *Product) PurchaseBy(holder string, quantity int) error {
+	if quantity < 1 {
+		return ErrInvalidQuantity
+	}
+	switch {
+	case p.status == StatusReserved && !p.reservation.IsHeldBy(holder):
+		return ErrProductReserved
+	case p.status != StatusPublished && p.status != StatusReserved:
 		return ErrProductPurchased
 	}
-	p.available = false
+	if p.price == nil {
+		return ErrProductWithoutPrice
+	}
+	if p.stock < quantity {
+		return ErrInsufficientStock
+	}
+	return p.record(NewProductPurchasedEvent(*p, quantity))
+}
 
-	p.RecordEvent(NewProductPurchasedEvent(*p))
-	return nil
+// ErrProductNotPurchased is self-described
+var ErrProductNotPurchased = errors.New("product not purchased")
+
+// Refund undoes the purchase of the given quantity of units of the product, so they can be purchased again.
+// A sold product is put back on sale. The archived products can't be refunded, since they can't be on sale again.
+func (p *Product) Refund(quantity int) error {
+	if quantity < 1 {
+		return ErrInvalidQuantity
+	}
+	if p.status == StatusArchived {
+		return ErrProductArchived
+	}
+	if p.sold < quantity {
+		return ErrProductNotPurchased
+	}
+	return p.record(NewProductRefundedEvent(*p, quantity))
+}
+
+// refundedStatus is the status of a product after a refund. A sold product goes back on sale,
+// but a product withdrawn from sale is kept withdrawn
+func refundedStatus(s Status) Status {
+	if s == StatusSold {
+		return StatusPublished
+	}
+	return s
+}
+
+// ErrProductArchived is self-described
+var ErrProductArchived = errors.New("product archived")
+
+// ChangePrice sets a new price to the product
+func (p *Product) ChangePrice(price Money) error {
+	if p.status == StatusArchived {
+		return ErrProductArchived
+	}
+	var vs violations
+	validatePrice(&vs, price)
+	if err := vs.err(productEntity); err != nil {
+		return err
+	}
+	return p.record(NewProductPriceChangedEvent(*p, price))
+}
+
+// Rename is self-described. Nothing is recorded when the name doesn't change
+func (p *Product) Rename(name string) error {
+	if p.status == StatusArchived {
+		return ErrProductArchived
+	}
+	var vs violations
+	validateProductName(&vs, name)
+	if err := vs.err(productEntity); err != nil {
+		return err
+	}
+	if name == p.name {
+		return nil
+	}
+	return p.record(NewProductRenamedEvent(*p, name))
+}
+
+// Publish puts the product on sale. Only products with stock can be put on sale.
+// Nothing is recorded when the product is already published.
+func (p *Product) Publish() error {
+	if p.status == StatusPublished {
+		return nil
+	}
+	// A reserved product is put back on sale when its reservation ends, not by publishing it
+	if p.status == StatusReserved {
+		return fmt.Errorf("%w: the product is reserved", ErrInvalidStatusTransition)
+	}
+	if err := p.checkTransition(StatusPublished); err != nil {
+		return err
+	}
+	if p.stock == 0 {
+		return ErrInsufficientStock
+	}
+	return p.record(NewProductPublishedEvent(*p))
+}
+
+// Unpublish withdraws the product from sale, so it goes back to draft and it's kept there when its units are refunded.
+// Nothing is recorded when the product is already a draft.
+func (p *Product) Unpublish() error {
+	if p.status == StatusDraft {
+		return nil
+	}
+	if err := p.checkTransition(StatusDraft); err != nil {
+		return err
+	}
+	return p.record(NewProductUnpublishedEvent(*p))
+}
+
+// Archive withdraws the product from sale for good
+func (p *Product) Archive() error {
+	if err := p.checkTransition(StatusArchived); err != nil {
+		return err
+	}
+	return p.record(NewProductArchivedEvent(*p))
+}
+
+// ErrNotUniqueProduct is self-described
+var ErrNotUniqueProduct = errors.New("not a unique product")
+
+// Reserve puts the product on hold for the holder of the reservation, so nobody else can purchase it until it expires.
+// Only the published unique products can be reserved. When the product is already held for the same holder, its hold is renewed.
+// A hold for another holder must expire before the product can be reserved again, even if it's overdue.
+func (p *Product) Reserve(r Reservation) error {
+	switch p.status {
+	case StatusReserved:
+		if p.reservation.holder != r.holder {
+			return ErrProductReserved
+		}
+		return p.record(NewProductReservedEvent(*p, r))
+	case StatusArchived:
+		return ErrProductArchived
+	case StatusPublished:
+	default:
+		return ErrProductPurchased
+	}
+	if p.stock != 1 {
+		return ErrNotUniqueProduct
+	}
+	if p.price == nil {
+		return ErrProductWithoutPrice
+	}
+	return p.record(NewProductReservedEvent(*p, r))
+}
+
+// ErrProductNotReserved is self-described
+var ErrProductNotReserved = errors.New("product not reserved")
+
+// ErrReservationNotExpired is self-described
+var ErrReservationNotExpired = errors.New("reservation not expired")
+
+// ExpireReservation releases the hold of the product when it has expired, and puts it back on sale
+func (p *Product) ExpireReservation(now time.Time) error {
+	if p.status != StatusReserved {
+		return ErrProductNotReserved
+	}
+	if !p.reservation.IsExpired(now) {
+		return ErrReservationNotExpired
+	}
+	return p.record(NewProductReservationExpiredEvent(*p))
 }
 
 // IsPurchased is self-described
 func (p Product) IsPurchased() bool {
-	return !p.available
+	return p.status == StatusSold
 }
 
 // IsAvailable is self-described
 func (p Product) IsAvailable() bool {
-	return p.available
+	return p.Available()
 }
 
 // Hydrate hydrates a product instance. It's used to retrieve entities from DB.
-func (p *Product) Hydrate(ID uuid.UUID, name string, available bool, price float64) {
+// A nil price means that the product has no price, and a nil reservation that it's not reserved.
+// The rules of NewProduct are not checked, so a product stored before they were enforced can still be loaded.
+// They're checked when its fields are changed.
+func (p *Product) Hydrate(ID uuid.UUID, name string, status Status, price *Money, reservation *Reservation, stock, sold, version int) {
 	p.AggregateBasic = ddd.NewAggregateBasic(ID)
 	p.name = name
-	p.available = available
+	p.status = status
 	p.price = price
+	p.reservation = reservation
+	p.stock = stock
+	p.sold = sold
+	p.version = version
+}
+
+// ErrInvalidHistory is self-described
+var ErrInvalidHistory = errors.New("invalid history of events")
+
+// ReplayProduct rebuilds a product from its history of events, which must start with a ProductCreatedEvent
+// or with a ProductSnapshotTakenEvent.
+// It's used to retrieve event-sourced products. The version of the product is the number of events in its history.
+func ReplayProduct(ID uuid.UUID, history []events.Event) (Product, error) {
+	if len(history) == 0 {
+		return Product{}, fmt.Errorf("%w: product %s has no events", ErrInvalidHistory, ID)
+	}
+	switch history[0].(type) {
+	case ProductCreatedEvent, ProductSnapshotTakenEvent:
+	default:
+		return Product{}, fmt.Errorf("%w: history of product %s starts with %s", ErrInvalidHistory, ID, history[0].Name())
+	}
+
+	p := Product{AggregateBasic: ddd.NewAggregateBasic(ID)}
+	for _, ev := range history {
+		if err := p.apply(ev); err != nil {
+			return Product{}, err
+		}
+	}
+	p.version = len(history)
+	return p, nil
+}
+
+// record applies a new event to the product and records it
+func (p *Product) record(ev events.Event) error {
+	if err := p.apply(ev); err != nil {
+		return err
+	}
+	p.RecordEvent(ev)
+	return nil
+}
+
+// ErrUnknownEvent is self-described
+var ErrUnknownEvent = errors.New("unknown event")
+
+// apply changes the state of the product as the event says. It doesn't check the business rules,
+// because the event has already happened. It's used both to record new events and to replay the history of the product.
+func (p *Product) apply(ev events.Event) error {
+	switch e := ev.(type) {
+	case ProductCreatedEvent:
+		p.name = e.ProductName
+		p.price = e.Price
+		p.stock = e.Stock
+		p.status = initialStatus(e.Stock)
+	case ProductPurchasedEvent:
+		p.stock -= e.Quantity
+		p.sold += e.Quantity
+		p.reservation = nil
+		if p.stock == 0 {
+			p.status = StatusSold
+		} else if p.status == StatusReserved {
+			p.status = StatusPublished
+		}
+	case ProductRefundedEvent:
+		p.status = refundedStatus(p.status)
+		p.stock += e.Quantity
+		p.sold -= e.Quantity
+	case ProductPriceChangedEvent:
+		price := e.Price
+		p.price = &price
+	case ProductRenamedEvent:
+		p.name = e.ProductName
+	case ProductPublishedEvent:
+		p.status = StatusPublished
+	case ProductUnpublishedEvent:
+		p.status = StatusDraft
+	case ProductArchivedEvent:
+		p.status = StatusArchived
+	case ProductReservedEvent:
+		p.status = StatusReserved
+		p.reservation = &Reservation{holder: e.Holder, expiresAt: e.ExpiresAt}
+	case ProductReservationExpiredEvent:
+		p.status = StatusPublished
+		p.reservation = nil
+	case ProductSnapshotTakenEvent:
+		p.name = e.ProductName
+		p.price = e.Price
+		p.status = e.Status
+		p.stock = e.Stock
+		p.sold = e.Sold
+		p.reservation = nil
+		if e.Holder != "" {
+			p.reservation = &Reservation{holder: e.Holder, expiresAt: e.ExpiresAt}
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, ev.Name())
+	}
+	return nil
 }
@@ -1,13 +1,17 @@
 package domain_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/domain"
 	"theskyinflames/graphql-challenge/internal/fixtures"
 	"theskyinflames/graphql-challenge/internal/helpers"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
 )
 
 func TestPurchase(t *testing.T) {
@@ -15,14 +19,794 @@ func TestPurchase(t *testing.T) {
 			when it's tried to be purchased, 
 			then it returns an error`, func(t *testing.T) {
 		p := fixtures.Product{Available: helpers.BoolPtr(false)}.Build()
-		require.ErrorIs(t, p.Purchase(), domain.ErrProductPurchased)
+		require.ErrorIs(t, p.Purchase(1), domain.ErrProductPurchased)
+	})
+
+	t.Run(`Given an available product without price, 
+			when it's tried to be purchased, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true), NoPrice: true}.Build()
+		require.ErrorIs(t, p.Purchase(1), domain.ErrProductWithoutPrice)
+		require.True(t, p.IsAvailable())
+	})
+
+	t.Run(`Given an available product, 
+			when an invalid quantity is tried to be purchased, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.ErrorIs(t, p.Purchase(0), domain.ErrInvalidQuantity)
+	})
+
+	t.Run(`Given an available product, 
+			when more units than the ones in stock are tried to be purchased, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(2)}.Build()
+		require.ErrorIs(t, p.Purchase(3), domain.ErrInsufficientStock)
+		require.Equal(t, 2, p.Stock())
+	})
+
+	t.Run(`Given an available product with stock, 
+			when some of its units are purchased, 
+			then the stock is decremented and it's still available`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(3)}.Build()
+		require.NoError(t, p.Purchase(2))
+		require.Equal(t, 1, p.Stock())
+		require.Equal(t, 2, p.Sold())
+		require.True(t, p.IsAvailable())
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, 1, evs[0].(domain.ProductPurchasedEvent).Stock)
 	})
 
 	t.Run(`Given an available product, 
 			when it's tried to be purchased, 
 			then it returns no error`, func(t *testing.T) {
 		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
-		require.NoError(t, p.Purchase())
+		require.NoError(t, p.Purchase(1))
 		require.Len(t, p.Events(), 1)
+		require.Equal(t, 0, p.Stock())
+		require.False(t, p.IsAvailable())
+	})
+}
+
+func TestRefund(t *testing.T) {
+	t.Run(`Given a product which has not been purchased, 
+			when it's tried to be refunded, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.ErrorIs(t, p.Refund(1), domain.ErrProductNotPurchased)
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a product with some units purchased, 
+			when more units than the purchased ones are tried to be refunded, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(1), Sold: helpers.IntPtr(2)}.Build()
+		require.ErrorIs(t, p.Refund(3), domain.ErrProductNotPurchased)
+		require.NoError(t, p.Refund(2))
+		require.Equal(t, 3, p.Stock())
+	})
+
+	t.Run(`Given an archived product with some units purchased, 
+			when it's tried to be refunded, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Archived: true, Stock: helpers.IntPtr(1), Sold: helpers.IntPtr(2)}.Build()
+		require.ErrorIs(t, p.Refund(1), domain.ErrProductArchived)
+		require.Equal(t, domain.StatusArchived, p.Status())
+		require.Equal(t, 1, p.Stock())
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a purchased product, 
+			when it's tried to be refunded, 
+			then it returns no error and it becomes available`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(false)}.Build()
+		require.NoError(t, p.Refund(1))
+		require.True(t, p.IsAvailable())
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductRefundedEventName, evs[0].Name())
+	})
+}
+
+func TestChangePrice(t *testing.T) {
+	t.Run(`Given a product, 
+			when a negative price is set, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.ErrorIs(t, p.ChangePrice(fixtures.Money("-1")), domain.ErrInvalidMoney)
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a product without price, 
+			when a price is set, 
+			then it has the new price`, func(t *testing.T) {
+		p := fixtures.Product{NoPrice: true}.Build()
+		require.NoError(t, p.ChangePrice(fixtures.Money("2.5")))
+		price, ok := p.Price()
+		require.True(t, ok)
+		require.True(t, fixtures.Money("2.5").Equal(price))
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductPriceChangedEventName, evs[0].Name())
+	})
+}
+
+func TestNewProduct(t *testing.T) {
+	price := fixtures.Money("1.1")
+	negative := fixtures.Money("-1.1")
+	testCases := []struct {
+		name               string
+		productName        string
+		price              *domain.Money
+		stock              int
+		expectedErr        error
+		expectedViolations []string
+	}{
+		{
+			name:               `Given a blank name, when a product is created, then it returns an error`,
+			productName:        "  ",
+			price:              &price,
+			expectedErr:        domain.ErrInvalidProductName,
+			expectedViolations: []string{"name"},
+		},
+		{
+			name:               `Given a name longer than the max length, when a product is created, then it returns an error`,
+			productName:        strings.Repeat("ñ", domain.MaxProductNameLength+1),
+			price:              &price,
+			expectedErr:        domain.ErrInvalidProductName,
+			expectedViolations: []string{"name"},
+		},
+		{
+			name:               `Given a negative price, when a product is created, then it returns an error`,
+			productName:        "product1",
+			price:              &negative,
+			expectedErr:        domain.ErrInvalidMoney,
+			expectedViolations: []string{"price"},
+		},
+		{
+			name:               `Given a price without currency, when a product is created, then it returns an error`,
+			productName:        "product1",
+			price:              &domain.Money{},
+			expectedErr:        domain.ErrInvalidMoney,
+			expectedViolations: []string{"price"},
+		},
+		{
+			name:               `Given a negative stock, when a product is created, then it returns an error`,
+			productName:        "product1",
+			price:              &price,
+			stock:              -1,
+			expectedErr:        domain.ErrInvalidStock,
+			expectedViolations: []string{"stock"},
+		},
+		{
+			name: `Given an empty name, a negative price and a negative stock, 
+				when a product is created, 
+				then it returns an error with all the violations`,
+			price:              &negative,
+			stock:              -1,
+			expectedErr:        domain.ErrInvalidStock,
+			expectedViolations: []string{"name", "price", "stock"},
+		},
+		{
+			name:        `Given a name of the max length and no price, when a product is created, then it's created without price`,
+			productName: strings.Repeat("ñ", domain.MaxProductNameLength),
+			stock:       1,
+		},
+		{
+			name:        `Given valid fields, when a product is created, then it's created`,
+			productName: "product1",
+			price:       &price,
+			stock:       2,
+		},
+	}
+
+	for _, tc := range testCases {
+		p, err := domain.NewProduct(uuid.New(), tc.productName, tc.price, tc.stock)
+		require.ErrorIs(t, err, tc.expectedErr, tc.name)
+		if err != nil {
+			var verr domain.ValidationError
+			require.ErrorAs(t, err, &verr, tc.name)
+			var fields []string
+			for _, v := range verr.Violations {
+				fields = append(fields, v.Field)
+			}
+			require.Equal(t, tc.expectedViolations, fields, tc.name)
+			continue
+		}
+		require.Equal(t, tc.productName, p.Name(), tc.name)
+		require.Equal(t, tc.stock, p.Stock(), tc.name)
+		require.Equal(t, tc.stock > 0, p.IsAvailable(), tc.name)
+		_, priced := p.Price()
+		require.Equal(t, tc.price != nil, priced, tc.name)
+		evs := p.Events()
+		require.Len(t, evs, 1, tc.name)
+		require.Equal(t, domain.ProductCreatedEventName, evs[0].Name(), tc.name)
+	}
+}
+
+func TestHydrate(t *testing.T) {
+	t.Run(`Given the fields of a product stored before the rules were enforced, 
+			when it's hydrated, 
+			then it's loaded as it is, and its invalid fields are rejected when they're changed`, func(t *testing.T) {
+		var (
+			p     domain.Product
+			name  = strings.Repeat("a", domain.MaxProductNameLength+1)
+			price = fixtures.Money("-1")
+		)
+		p.Hydrate(uuid.New(), name, domain.StatusPublished, &price, nil, 1, 0, 1)
+		require.Equal(t, name, p.Name())
+		storedPrice, _ := p.Price()
+		require.Equal(t, price, storedPrice)
+
+		require.ErrorIs(t, p.Rename(name), domain.ErrInvalidProductName)
+		require.ErrorIs(t, p.ChangePrice(price), domain.ErrInvalidMoney)
+		require.NoError(t, p.Rename("product1"))
+		require.Empty(t, p.Events()[1:])
+	})
+
+	t.Run(`Given the fields of a valid product, 
+			when it's hydrated, 
+			then it has them`, func(t *testing.T) {
+		var p domain.Product
+		ID := uuid.New()
+		p.Hydrate(ID, "product1", domain.StatusPublished, nil, nil, 2, 1, 3)
+		require.Equal(t, ID, p.ID())
+		require.Equal(t, "product1", p.Name())
+		require.Equal(t, domain.StatusPublished, p.Status())
+		require.True(t, p.IsAvailable())
+		require.Equal(t, 2, p.Stock())
+		require.Equal(t, 1, p.Sold())
+		require.Equal(t, 3, p.Version())
+	})
+}
+
+func TestRename(t *testing.T) {
+	t.Run(`Given a product, 
+			when it's renamed to an empty name, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.ErrorIs(t, p.Rename(""), domain.ErrInvalidProductName)
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given an archived product, 
+			when it's renamed, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Archived: true}.Build()
+		require.ErrorIs(t, p.Rename("product2"), domain.ErrProductArchived)
+	})
+
+	t.Run(`Given a product, 
+			when it's renamed with the same name, 
+			then nothing is recorded`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.NoError(t, p.Rename(p.Name()))
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a product, 
+			when it's renamed, 
+			then it has the new name`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.NoError(t, p.Rename("product2"))
+		require.Equal(t, "product2", p.Name())
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductRenamedEventName, evs[0].Name())
+	})
+}
+
+func TestStatusTransitions(t *testing.T) {
+	allowed := map[domain.Status][]domain.Status{
+		domain.StatusDraft:     {domain.StatusPublished, domain.StatusArchived},
+		domain.StatusPublished: {domain.StatusDraft, domain.StatusReserved, domain.StatusSold, domain.StatusArchived},
+		domain.StatusReserved:  {domain.StatusPublished, domain.StatusSold},
+		domain.StatusSold:      {domain.StatusPublished, domain.StatusDraft, domain.StatusArchived},
+	}
+	for _, from := range domain.Statuses {
+		for _, to := range domain.Statuses {
+			var expected bool
+			for _, a := range allowed[from] {
+				expected = expected || a == to
+			}
+			require.Equal(t, expected, from.CanTransitionTo(to), "from %s to %s", from, to)
+		}
+	}
+	require.False(t, domain.Status("unknown").IsValid())
+}
+
+func TestNewProductStatus(t *testing.T) {
+	t.Run(`Given a product created with stock, 
+			when it's checked, 
+			then it's published`, func(t *testing.T) {
+		p := fixtures.NewProduct("product1", "1.1", 1)
+		require.Equal(t, domain.StatusPublished, p.Status())
+		require.True(t, p.IsAvailable())
+	})
+
+	t.Run(`Given a product created without stock, 
+			when it's checked, 
+			then it's a draft`, func(t *testing.T) {
+		p := fixtures.NewProduct("product1", "1.1", 0)
+		require.Equal(t, domain.StatusDraft, p.Status())
+		require.False(t, p.IsAvailable())
+	})
+}
+
+func TestPublish(t *testing.T) {
+	t.Run(`Given a draft without stock, 
+			when it's published, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Status: statusPtr(domain.StatusDraft), Stock: helpers.IntPtr(0)}.Build()
+		require.ErrorIs(t, p.Publish(), domain.ErrInsufficientStock)
+	})
+
+	t.Run(`Given a sold product, 
+			when it's published, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.Equal(t, domain.StatusSold, p.Status())
+		require.ErrorIs(t, p.Publish(), domain.ErrInsufficientStock)
+	})
+
+	t.Run(`Given an archived product, 
+			when it's published, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Archived: true, Stock: helpers.IntPtr(1)}.Build()
+		require.ErrorIs(t, p.Publish(), domain.ErrProductArchived)
+	})
+
+	t.Run(`Given a reserved product, 
+			when it's published, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		require.ErrorIs(t, p.Publish(), domain.ErrInvalidStatusTransition)
+		require.Equal(t, domain.StatusReserved, p.Status())
+	})
+
+	t.Run(`Given a published product, 
+			when it's published, 
+			then nothing is recorded`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.NoError(t, p.Publish())
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a draft with stock, 
+			when it's published, 
+			then it can be purchased`, func(t *testing.T) {
+		p := fixtures.Product{Stock: helpers.IntPtr(1)}.Build()
+		require.Equal(t, domain.StatusDraft, p.Status())
+		require.NoError(t, p.Publish())
+		require.True(t, p.IsAvailable())
+		require.NoError(t, p.Purchase(1))
+		require.Equal(t, domain.StatusSold, p.Status())
+
+		evs := p.Events()
+		require.Len(t, evs, 2)
+		require.Equal(t, domain.ProductPublishedEventName, evs[0].Name())
+		require.Equal(t, 1, evs[0].(domain.ProductPublishedEvent).Stock)
+	})
+}
+
+func TestUnpublish(t *testing.T) {
+	t.Run(`Given a reserved product, 
+			when it's unpublished, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		require.ErrorIs(t, p.Unpublish(), domain.ErrInvalidStatusTransition)
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a draft, 
+			when it's unpublished, 
+			then nothing is recorded`, func(t *testing.T) {
+		p := fixtures.Product{Status: statusPtr(domain.StatusDraft)}.Build()
+		require.NoError(t, p.Unpublish())
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a published product, 
+			when it's unpublished and a purchase of it is refunded, 
+			then it's still a draft`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(1), Sold: helpers.IntPtr(1)}.Build()
+		require.NoError(t, p.Unpublish())
+		require.Equal(t, domain.StatusDraft, p.Status())
+		require.NoError(t, p.Refund(1))
+		require.Equal(t, domain.StatusDraft, p.Status())
+		require.ErrorIs(t, p.Purchase(1), domain.ErrProductPurchased)
+
+		evs := p.Events()
+		require.Len(t, evs, 2)
+		require.Equal(t, domain.ProductUnpublishedEventName, evs[0].Name())
+		require.Equal(t, 1, evs[0].(domain.ProductUnpublishedEvent).Stock)
+	})
+
+	t.Run(`Given a sold product, 
+			when it's unpublished and its purchase is refunded, 
+			then it's not put back on sale`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.NoError(t, p.Unpublish())
+		require.NoError(t, p.Refund(1))
+		require.Equal(t, domain.StatusDraft, p.Status())
+		require.Equal(t, 1, p.Stock())
+	})
+}
+
+func TestArchive(t *testing.T) {
+	t.Run(`Given an archived product, 
+			when it's archived, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Archived: true}.Build()
+		require.ErrorIs(t, p.Archive(), domain.ErrProductArchived)
+	})
+
+	t.Run(`Given a reserved product, 
+			when it's archived, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		require.ErrorIs(t, p.Archive(), domain.ErrInvalidStatusTransition)
+	})
+
+	t.Run(`Given a published product, 
+			when it's archived, 
+			then it's withdrawn from sale and it can't be changed anymore`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.NoError(t, p.Archive())
+		require.True(t, p.Archived())
+		require.False(t, p.IsAvailable())
+		require.ErrorIs(t, p.ChangePrice(fixtures.Money("2.2")), domain.ErrProductArchived)
+		require.ErrorIs(t, p.Unpublish(), domain.ErrProductArchived)
+		require.ErrorIs(t, p.Purchase(1), domain.ErrProductPurchased)
+
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductArchivedEventName, evs[0].Name())
+		require.Equal(t, domain.StatusPublished, evs[0].(domain.ProductArchivedEvent).From)
+	})
+
+	t.Run(`Given a sold product, 
+			when it's archived and its purchase is refunded, 
+			then the refund is rejected and it's still archived`, func(t *testing.T) {
+		p := fixtures.Product{}.Build()
+		require.NoError(t, p.Archive())
+		require.ErrorIs(t, p.Refund(1), domain.ErrProductArchived)
+		require.Equal(t, domain.StatusArchived, p.Status())
+		require.Equal(t, 0, p.Stock())
+
+		evs := p.Events()
+		require.Len(t, evs, 1)
+		require.Equal(t, domain.ProductArchivedEventName, evs[0].Name())
+	})
+}
+
+func statusPtr(s domain.Status) *domain.Status {
+	return &s
+}
+
+func TestReplayProduct(t *testing.T) {
+	t.Run(`Given an empty history, 
+			when it's replayed, 
+			then it returns an error`, func(t *testing.T) {
+		_, err := domain.ReplayProduct(uuid.New(), nil)
+		require.ErrorIs(t, err, domain.ErrInvalidHistory)
+	})
+
+	t.Run(`Given a history that doesn't start with the creation of the product, 
+			when it's replayed, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		_, err := domain.ReplayProduct(p.ID(), []events.Event{domain.NewProductPurchasedEvent(p, 1)})
+		require.ErrorIs(t, err, domain.ErrInvalidHistory)
+	})
+
+	t.Run(`Given a history with an unknown event, 
+			when it's replayed, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.NewProduct("product1", "1.1", 1)
+		history := append(p.Events(), events.NewEventBasic(p.ID(), "unknown", nil))
+		_, err := domain.ReplayProduct(p.ID(), history)
+		require.ErrorIs(t, err, domain.ErrUnknownEvent)
+	})
+
+	t.Run(`Given the events recorded by a product, 
+			when they're replayed, 
+			then the product is rebuilt with the same state`, func(t *testing.T) {
+		p := fixtures.NewProduct("product1", "1.1", 3)
+		require.NoError(t, p.Purchase(2))
+		require.NoError(t, p.Refund(1))
+		require.NoError(t, p.Unpublish())
+		require.NoError(t, p.Publish())
+		require.NoError(t, p.Purchase(2))
+		require.NoError(t, p.ChangePrice(fixtures.Money("2.2")))
+		require.NoError(t, p.Rename("product2"))
+		require.NoError(t, p.Archive())
+		history := p.Events()
+		require.Len(t, history, 9)
+
+		replayed, err := domain.ReplayProduct(p.ID(), history)
+		require.NoError(t, err)
+		require.Equal(t, "product2", replayed.Name())
+		require.True(t, replayed.Archived())
+		require.Equal(t, domain.StatusArchived, replayed.Status())
+		require.Equal(t, 0, replayed.Stock())
+		require.Equal(t, 3, replayed.Sold())
+		require.False(t, replayed.IsAvailable())
+		price, ok := replayed.Price()
+		require.True(t, ok)
+		require.True(t, fixtures.Money("2.2").Equal(price))
+		require.Equal(t, len(history), replayed.Version())
+		require.Len(t, replayed.Events(), 0)
+	})
+}
+
+func TestReserve(t *testing.T) {
+	var (
+		now     = time.Now().UTC()
+		later   = now.Add(time.Hour)
+		buyer   = fixtures.Reservation("buyer", later)
+		another = fixtures.Reservation("another buyer", later)
+	)
+
+	testCases := []struct {
+		name        string
+		product     fixtures.Product
+		expectedErr error
+	}{
+		{
+			name: `Given a sold product, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{},
+			expectedErr: domain.ErrProductPurchased,
+		},
+		{
+			name: `Given a draft, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{Status: statusPtr(domain.StatusDraft), Stock: helpers.IntPtr(1)},
+			expectedErr: domain.ErrProductPurchased,
+		},
+		{
+			name: `Given an archived product, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{Archived: true},
+			expectedErr: domain.ErrProductArchived,
+		},
+		{
+			name: `Given a published product with several units, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(2)},
+			expectedErr: domain.ErrNotUniqueProduct,
+		},
+		{
+			name: `Given a product without price, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{Available: helpers.BoolPtr(true), NoPrice: true},
+			expectedErr: domain.ErrProductWithoutPrice,
+		},
+		{
+			name: `Given a product reserved by another buyer, 
+				when it's reserved, 
+				then it returns an error`,
+			product:     fixtures.Product{Reservation: another},
+			expectedErr: domain.ErrProductReserved,
+		},
+		{
+			name: `Given a published unique product, 
+				when it's reserved, 
+				then it's held for the buyer`,
+			product: fixtures.Product{Available: helpers.BoolPtr(true)},
+		},
+		{
+			name: `Given a product reserved by the same buyer, 
+				when it's reserved again, 
+				then its hold is renewed`,
+			product: fixtures.Product{Reservation: fixtures.Reservation("buyer", now)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.product.Build()
+			err := p.Reserve(*buyer)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				require.Len(t, p.Events(), 0)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, domain.StatusReserved, p.Status())
+			require.False(t, p.IsAvailable())
+			r, ok := p.Reservation()
+			require.True(t, ok)
+			require.True(t, r.IsHeldBy("buyer"))
+			require.Equal(t, later, r.ExpiresAt())
+
+			evs := p.Events()
+			require.Len(t, evs, 1)
+			require.Equal(t, domain.ProductReservedEventName, evs[0].Name())
+			require.Equal(t, buyer.Holder(), evs[0].(domain.ProductReservedEvent).Holder)
+			require.Equal(t, later, evs[0].(domain.ProductReservedEvent).ExpiresAt)
+		})
+	}
+}
+
+func TestPurchaseBy(t *testing.T) {
+	t.Run(`Given a reserved product, 
+			when it's purchased by someone who is not its holder, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		require.ErrorIs(t, p.Purchase(1), domain.ErrProductReserved)
+		require.ErrorIs(t, p.PurchaseBy("another buyer", 1), domain.ErrProductReserved)
+		require.Len(t, p.Events(), 0)
+	})
+
+	t.Run(`Given a reserved product, 
+			when it's purchased by its holder, 
+			then it's sold and its hold is released`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", time.Now().Add(time.Hour))}.Build()
+		require.NoError(t, p.PurchaseBy("buyer", 1))
+		require.Equal(t, domain.StatusSold, p.Status())
+		_, reserved := p.Reservation()
+		require.False(t, reserved)
+	})
+
+	t.Run(`Given a published product, 
+			when it's purchased by a holder, 
+			then it's purchased`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.NoError(t, p.PurchaseBy("buyer", 1))
+		require.Equal(t, domain.StatusSold, p.Status())
+	})
+}
+
+func TestExpireReservation(t *testing.T) {
+	now := time.Now().UTC()
+
+	t.Run(`Given a product not reserved, 
+			when its reservation is expired, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		require.ErrorIs(t, p.ExpireReservation(now), domain.ErrProductNotReserved)
+	})
+
+	t.Run(`Given a reserved product whose hold has not expired, 
+			when its reservation is expired, 
+			then it returns an error`, func(t *testing.T) {
+		p := fixtures.Product{Reservation: fixtures.Reservation("buyer", now.Add(time.Minute))}.Build()
+		require.ErrorIs(t, p.ExpireReservation(now), domain.ErrReservationNotExpired)
+		require.Equal(t, domain.StatusReserved, p.Status())
+	})
+
+	t.Run(`Given a reserved product whose hold has expired, 
+			when its reservation is expired, 
+			then it's put back on sale`, func(t *testing.T) {
+		r := fixtures.Reservation("buyer", now)
+		p := fixtures.Product{Reservation: r}.Build()
+		require.NoError(t, p.ExpireReservation(now))
+		require.Equal(t, domain.StatusPublished, p.Status())
+		_, reserved := p.Reservation()
+		require.False(t, reserved)
+		require.NoError(t, p.Purchase(1))
+
+		evs := p.Events()
+		require.Len(t, evs, 2)
+		require.Equal(t, domain.ProductReservationExpiredEventName, evs[0].Name())
+		require.Equal(t, r.Holder(), evs[0].(domain.ProductReservationExpiredEvent).Holder)
+	})
+
+	t.Run(`Given the events of a reservation that has expired, 
+			when they're replayed, 
+			then the product is on sale again`, func(t *testing.T) {
+		p := fixtures.NewProduct("product1", "1.1", 1)
+		require.NoError(t, p.Reserve(*fixtures.Reservation("buyer", now)))
+		history := p.Events()
+		reserved, err := domain.ReplayProduct(p.ID(), history)
+		require.NoError(t, err)
+		require.Equal(t, domain.StatusReserved, reserved.Status())
+		r, ok := reserved.Reservation()
+		require.True(t, ok)
+		require.True(t, r.IsHeldBy("buyer"))
+
+		require.NoError(t, reserved.ExpireReservation(now))
+		history = append(history, reserved.Events()...)
+		replayed, err := domain.ReplayProduct(p.ID(), history)
+		require.NoError(t, err)
+		require.Equal(t, domain.StatusPublished, replayed.Status())
 	})
 }
+
+func TestReservation(t *testing.T) {
+	_, err := domain.NewReservation("", time.Now())
+	require.ErrorIs(t, err, domain.ErrInvalidHolder)
+
+	r, err := domain.NewReservation("session token", time.Now())
+	require.NoError(t, err)
+	require.NotContains(t, r.Holder(), "session token")
+	require.True(t, r.IsHeldBy("session token"))
+	require.False(t, r.IsHeldBy("another session token"))
+	require.False(t, r.IsHeldBy(""))
+}
+
+func TestProductSnapshotTakenEvent(t *testing.T) {
+	var (
+		price       = fixtures.Money("1.1")
+		reservation domain.Reservation
+	)
+	reservation.Hydrate("holder", time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		name        string
+		status      domain.Status
+		reservation *domain.Reservation
+		stock, sold int
+	}{
+		{
+			name: `Given a published product with units sold, 
+				when its snapshot is replayed, 
+				then it's rebuilt as it is`,
+			status: domain.StatusPublished,
+			stock:  2,
+			sold:   1,
+		},
+		{
+			name: `Given a sold product, 
+				when its snapshot is replayed, 
+				then it's rebuilt as it is`,
+			status: domain.StatusSold,
+			sold:   3,
+		},
+		{
+			name: `Given an archived product with units sold, 
+				when its snapshot is replayed, 
+				then it's rebuilt as it is`,
+			status: domain.StatusArchived,
+			stock:  1,
+			sold:   1,
+		},
+		{
+			name: `Given a reserved product, 
+				when its snapshot is replayed, 
+				then it's rebuilt with its hold`,
+			status:      domain.StatusReserved,
+			reservation: &reservation,
+			stock:       1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p domain.Product
+			p.Hydrate(uuid.New(), "product1", tc.status, &price, tc.reservation, tc.stock, tc.sold, 7)
+			snapshot := domain.NewProductSnapshotTakenEvent(p)
+
+			// The snapshot rebuilds the product whether it starts its history or it's appended to a history that differs from it
+			created := fixtures.NewProduct("another name", "2.2", 5)
+			histories := [][]events.Event{
+				{snapshot},
+				append(created.Events(), snapshot),
+			}
+			for _, history := range histories {
+				replayed, err := domain.ReplayProduct(p.ID(), history)
+				require.NoError(t, err)
+				require.Equal(t, len(history), replayed.Version())
+				require.Equal(t, p.Name(), replayed.Name())
+				require.Equal(t, p.Status(), replayed.Status())
+				require.Equal(t, p.Stock(), replayed.Stock())
+				require.Equal(t, p.Sold(), replayed.Sold())
+				gotReservation, _ := replayed.Reservation()
+				wantReservation, _ := p.Reservation()
+				require.Equal(t, wantReservation, gotReservation)
+				replayedPrice, ok := replayed.Price()
+				require.True(t, ok)
+				require.True(t, price.Equal(replayedPrice))
+			}
+		})
+	}
+}
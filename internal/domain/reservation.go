@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrInvalidHolder is self-described
+var ErrInvalidHolder = errors.New("invalid reservation holder")
+
+// Reservation is a value object. It's the hold of a product for a buyer until it expires.
+// The holder is a buyer or a session token, as they're returned by BuyerHolder and SessionHolder. Only its hash is kept, because the reservations
+// are recorded in the events. The holder is left out of the events when they're streamed to the clients.
+type Reservation struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// BuyerHolder returns the holder of the reservations of an authenticated buyer
+func BuyerHolder(buyerID string) string {
+	return "buyer:" + buyerID
+}
+
+// SessionHolder returns the holder of the reservations of an anonymous caller, identified by a session token.
+// It's namespaced apart from the buyers, so a session token can't match the holder of a buyer
+func SessionHolder(token string) string {
+	return "session:" + token
+}
+
+// NewReservation is a constructor
+func NewReservation(holder string, expiresAt time.Time) (Reservation, error) {
+	if holder == "" {
+		return Reservation{}, ErrInvalidHolder
+	}
+	return Reservation{holder: hashHolder(holder), expiresAt: expiresAt.UTC()}, nil
+}
+
+// Hydrate hydrates a reservation instance. It's used to retrieve value objects from DB.
+// The holder is the hash of the holder, as it's returned by Holder.
+func (r *Reservation) Hydrate(holder string, expiresAt time.Time) {
+	r.holder = holder
+	r.expiresAt = expiresAt.UTC()
+}
+
+// Holder is a getter. It returns the hash of the holder
+func (r Reservation) Holder() string {
+	return r.holder
+}
+
+// ExpiresAt is a getter
+func (r Reservation) ExpiresAt() time.Time {
+	return r.expiresAt
+}
+
+// IsHeldBy returns true when the given holder is the one the product is held for
+func (r Reservation) IsHeldBy(holder string) bool {
+	return holder != "" && subtle.ConstantTimeCompare([]byte(hashHolder(holder)), []byte(r.holder)) == 1
+}
+
+// IsExpired is self-described
+func (r Reservation) IsExpired(now time.Time) bool {
+	return !now.Before(r.expiresAt)
+}
+
+func hashHolder(holder string) string {
+	sum := sha256.Sum256([]byte(holder))
+	return hex.EncodeToString(sum[:])
+}
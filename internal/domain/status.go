@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Status is the stage of the lifecycle of a product
+type Status string
+
+const (
+	// StatusDraft is the status of the products which are not on sale yet, or have been withdrawn from sale
+	StatusDraft Status = "draft"
+	// StatusPublished is the status of the products on sale. They're the only ones that can be purchased
+	StatusPublished Status = "published"
+	// StatusReserved is the status of the products on hold for a buyer
+	StatusReserved Status = "reserved"
+	// StatusSold is the status of the products whose units have all been purchased
+	StatusSold Status = "sold"
+	// StatusArchived is the status of the products withdrawn from sale for good. It's the end of the lifecycle
+	StatusArchived Status = "archived"
+)
+
+// Statuses are all the statuses of the lifecycle
+var Statuses = []Status{StatusDraft, StatusPublished, StatusReserved, StatusSold, StatusArchived}
+
+// ErrInvalidStatusTransition is self-described
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// statusTransitions are the allowed transitions of the lifecycle of a product, and the events that record them:
+//
+//   - draft -> published: ProductPublishedEvent, when the product has stock
+//   - published -> draft, sold -> draft: ProductUnpublishedEvent
+//   - published -> sold: ProductPurchasedEvent, when its last units are purchased
+//   - sold -> published: ProductRefundedEvent
+//   - published <-> reserved, reserved -> sold: by the reservations
+//   - draft, published, sold -> archived: ProductArchivedEvent
+var statusTransitions = map[Status][]Status{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusDraft, StatusReserved, StatusSold, StatusArchived},
+	StatusReserved:  {StatusPublished, StatusSold},
+	StatusSold:      {StatusPublished, StatusDraft, StatusArchived},
+	StatusArchived:  {},
+}
+
+// IsValid is self-described
+func (s Status) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo returns true when the lifecycle allows to go from the status to the given one
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition returns an error when the product can't go to the given status
+func (p Product) checkTransition(to Status) error {
+	if p.status == StatusArchived {
+		return ErrProductArchived
+	}
+	if !p.status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, p.status, to)
+	}
+	return nil
+}
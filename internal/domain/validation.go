@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldViolation is a rule broken by the value of a field
+type FieldViolation struct {
+	Field  string
+	Reason string
+	// Err is the error of the broken rule, like ErrInvalidProductName
+	Err error
+}
+
+// ValidationError is returned when an entity can't be built or changed because its fields are invalid.
+// It lists all the broken rules, not only the first one, so they can be fixed at once.
+type ValidationError struct {
+	Entity     string
+	Violations []FieldViolation
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	reasons := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		reasons = append(reasons, v.Field+": "+v.Reason)
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Entity, strings.Join(reasons, "; "))
+}
+
+// Is makes errors.Is match the errors of the broken rules
+func (e ValidationError) Is(target error) bool {
+	for _, v := range e.Violations {
+		if errors.Is(v.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// violations collects the broken rules of an entity
+type violations []FieldViolation
+
+func (vs *violations) add(field string, err error, reason string) {
+	*vs = append(*vs, FieldViolation{Field: field, Reason: reason, Err: err})
+}
+
+// err returns a ValidationError with the violations, or nil when there are none
+func (vs violations) err(entity string) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return ValidationError{Entity: entity, Violations: vs}
+}
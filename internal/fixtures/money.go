@@ -0,0 +1,14 @@
+package fixtures
+
+import (
+	"theskyinflames/graphql-challenge/internal/domain"
+)
+
+// Money returns a money value of the default currency. It panics if the amount is not valid
+func Money(amount string) domain.Money {
+	m, err := domain.ParseMoney(amount, domain.DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -1,6 +1,8 @@
 package fixtures
 
 import (
+	"time"
+
 	"theskyinflames/graphql-challenge/internal/domain"
 
 	"github.com/google/uuid"
@@ -11,7 +13,16 @@ type Product struct {
 	ID        *uuid.UUID
 	Name      *string
 	Available *bool
-	Price     *float64
+	Archived  bool
+	// Status overrides the status derived from Available and Archived
+	Status  *domain.Status
+	Price   *domain.Money
+	NoPrice bool
+	// Reservation puts the product on hold. It's a reserved unique product unless the status or the stock say otherwise
+	Reservation *domain.Reservation
+	Stock       *int
+	Sold        *int
+	Version     *int
 }
 
 // Build is self-described
@@ -28,12 +39,67 @@ func (e Product) Build() domain.Product {
 	if e.Available != nil {
 		available = *e.Available
 	}
-	price := 1.1
+	price := Money("1.1")
 	if e.Price != nil {
 		price = *e.Price
 	}
+	pricePtr := &price
+	if e.NoPrice {
+		pricePtr = nil
+	}
+
+	// By default, it's a unique product which is purchased when it's not available
+	stock, sold := 0, 1
+	if available || e.Reservation != nil {
+		stock, sold = 1, 0
+	}
+	if e.Stock != nil {
+		stock = *e.Stock
+	}
+	if e.Sold != nil {
+		sold = *e.Sold
+	}
+	version := 1
+	if e.Version != nil {
+		version = *e.Version
+	}
+
+	// The products not available are sold when they have no stock left, and withdrawn from sale otherwise
+	status := domain.StatusPublished
+	switch {
+	case e.Status != nil:
+		status = *e.Status
+	case e.Reservation != nil:
+		status = domain.StatusReserved
+	case e.Archived:
+		status = domain.StatusArchived
+	case !available && stock == 0:
+		status = domain.StatusSold
+	case !available:
+		status = domain.StatusDraft
+	}
 
 	p := domain.Product{}
-	p.Hydrate(id, name, available, price)
+	p.Hydrate(id, name, status, pricePtr, e.Reservation, stock, sold, version)
+	return p
+}
+
+// NewProduct returns a new product priced in the default currency, with its creation event recorded.
+// It panics if the product is not valid
+func NewProduct(name, price string, stock int) domain.Product {
+	m := Money(price)
+	p, err := domain.NewProduct(uuid.New(), name, &m, stock)
+	if err != nil {
+		panic(err)
+	}
 	return p
 }
+
+// Reservation returns a reservation for the holder. It panics if the holder is empty
+func Reservation(holder string, expiresAt time.Time) *domain.Reservation {
+	r, err := domain.NewReservation(holder, expiresAt)
+	if err != nil {
+		panic(err)
+	}
+	return &r
+}
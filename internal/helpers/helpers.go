@@ -9,6 +9,11 @@ func BoolPtr(b bool) *bool {
 	return &b
 }
 
+// IntPtr is a helper
+func IntPtr(i int) *int {
+	return &i
+}
+
 // UUIDPtr is a helper
 func UUIDPtr(uuid uuid.UUID) *uuid.UUID {
 	return &uuid
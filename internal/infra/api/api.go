@@ -1,9 +1,11 @@
 package api
 
 import (
-	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/domain"
@@ -13,14 +15,59 @@ import (
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
 )
 
+// Money is a DTO
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// NewMoney builds a money DTO. It returns nil when no money is given
+func NewMoney(m *domain.Money) *Money {
+	if m == nil {
+		return nil
+	}
+	return &Money{Amount: m.Amount(), Currency: m.Currency()}
+}
+
+// The amount is served as a string to not lose precision
+var moneyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Money",
+	Fields: graphql.Fields{
+		"amount": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"currency": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+	},
+})
+
 // Product is a DTO
 type Product struct {
-	ID        string  `json:"id"`
-	Name      string  `json:"name"`
-	Available bool    `json:"available"`
-	Price     float64 `json:"price"`
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Status    domain.Status `json:"status"`
+	Available bool          `json:"available"`
+	Price     *Money        `json:"price"`
+	Quantity  int           `json:"quantity"`
+	Version   int           `json:"version"`
+	Archived  bool          `json:"archived"`
+	// ReservedUntil is the time the hold of a reserved product expires, in RFC 3339 format
+	ReservedUntil string `json:"reservedUntil,omitempty"`
 }
 
+var productStatusType = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "ProductStatus",
+	Description: "The stage of the lifecycle of a product",
+	Values: graphql.EnumValueConfigMap{
+		"DRAFT":     &graphql.EnumValueConfig{Value: domain.StatusDraft, Description: "Not on sale yet, or withdrawn from sale"},
+		"PUBLISHED": &graphql.EnumValueConfig{Value: domain.StatusPublished, Description: "On sale"},
+		"RESERVED":  &graphql.EnumValueConfig{Value: domain.StatusReserved, Description: "On hold for a buyer"},
+		"SOLD":      &graphql.EnumValueConfig{Value: domain.StatusSold, Description: "All its units have been purchased"},
+		"ARCHIVED":  &graphql.EnumValueConfig{Value: domain.StatusArchived, Description: "Withdrawn from sale for good"},
+	},
+})
+
 var productType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "Product",
 	Fields: graphql.Fields{
@@ -30,11 +77,284 @@ var productType = graphql.NewObject(graphql.ObjectConfig{
 		"name": &graphql.Field{
 			Type: graphql.String,
 		},
+		"status": &graphql.Field{
+			Type: graphql.NewNonNull(productStatusType),
+		},
 		"available": &graphql.Field{
+			Type:              graphql.Boolean,
+			Description:       "It's true when the product is published",
+			DeprecationReason: "Use status",
+		},
+		"price": &graphql.Field{
+			Type: moneyType,
+		},
+		"quantity": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"version": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"archived": &graphql.Field{
+			Type:              graphql.Boolean,
+			Description:       "It's true when the product is archived",
+			DeprecationReason: "Use status",
+		},
+		"reservedUntil": &graphql.Field{
+			Type:        graphql.String,
+			Description: "When the product is reserved, the time its hold expires, in RFC 3339 format",
+		},
+	},
+})
+
+// NewProduct builds a product DTO
+func NewProduct(p app.Product) Product {
+	product := Product{
+		ID:        p.ID.String(),
+		Name:      p.Name,
+		Status:    p.Status,
+		Available: p.Available,
+		Price:     NewMoney(p.Price),
+		Quantity:  p.Quantity,
+		Version:   p.Version,
+		Archived:  p.Status == domain.StatusArchived,
+	}
+	if p.ReservedUntil != nil {
+		product.ReservedUntil = p.ReservedUntil.Format(time.RFC3339)
+	}
+	return product
+}
+
+// ProductEdge is a DTO
+type ProductEdge struct {
+	Cursor string  `json:"cursor"`
+	Node   Product `json:"node"`
+}
+
+var productEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"node": &graphql.Field{
+			Type: graphql.NewNonNull(productType),
+		},
+	},
+})
+
+// PageInfo is a DTO
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"hasPreviousPage": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"startCursor": &graphql.Field{
+			Type: graphql.String,
+		},
+		"endCursor": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+// ProductConnection is a DTO
+type ProductConnection struct {
+	Edges    []ProductEdge `json:"edges"`
+	PageInfo PageInfo      `json:"pageInfo"`
+}
+
+var productConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductConnection",
+	Fields: graphql.Fields{
+		"edges": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productEdgeType))),
+		},
+		"pageInfo": &graphql.Field{
+			Type: graphql.NewNonNull(pageInfoType),
+		},
+	},
+})
+
+// ProductSearchEdge is a DTO
+type ProductSearchEdge struct {
+	Cursor  string  `json:"cursor"`
+	Node    Product `json:"node"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+var productSearchEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductSearchEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"node": &graphql.Field{
+			Type: graphql.NewNonNull(productType),
+		},
+		"rank": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Float),
+			Description: "The relevance of the product for the search. The higher, the more relevant",
+		},
+		"snippet": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The name of the product, HTML-escaped, with the matching words wrapped in <mark> tags. It's safe to be inserted as HTML",
+		},
+	},
+})
+
+// ProductSearchConnection is a DTO
+type ProductSearchConnection struct {
+	Edges    []ProductSearchEdge `json:"edges"`
+	PageInfo PageInfo            `json:"pageInfo"`
+}
+
+var productSearchConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductSearchConnection",
+	Fields: graphql.Fields{
+		"edges": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productSearchEdgeType))),
+		},
+		"pageInfo": &graphql.Field{
+			Type: graphql.NewNonNull(pageInfoType),
+		},
+	},
+})
+
+var moneyInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "MoneyInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"amount": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"currency": &graphql.InputObjectFieldConfig{
+			Type:         graphql.String,
+			DefaultValue: domain.DefaultCurrency,
+		},
+	},
+})
+
+var productFilterInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"available": &graphql.InputObjectFieldConfig{
 			Type: graphql.Boolean,
 		},
+		"minPrice": &graphql.InputObjectFieldConfig{
+			Type:        moneyInputType,
+			Description: "Inclusive. The products without price, or priced in another currency, don't match it",
+		},
+		"maxPrice": &graphql.InputObjectFieldConfig{
+			Type:        moneyInputType,
+			Description: "Inclusive. The products without price, or priced in another currency, don't match it",
+		},
+		"nameContains": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "The case is ignored",
+		},
+		"ids": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewList(graphql.NewNonNull(graphql.ID)),
+		},
+	},
+})
+
+var productOrderFieldType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ProductOrderField",
+	Values: graphql.EnumValueConfigMap{
+		"ID":       &graphql.EnumValueConfig{Value: app.ProductOrderByID},
+		"NAME":     &graphql.EnumValueConfig{Value: app.ProductOrderByName},
+		"PRICE":    &graphql.EnumValueConfig{Value: app.ProductOrderByPrice, Description: "The products without price come last"},
+		"QUANTITY": &graphql.EnumValueConfig{Value: app.ProductOrderByQuantity},
+	},
+})
+
+var orderDirectionType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderDirection",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: app.OrderAsc},
+		"DESC": &graphql.EnumValueConfig{Value: app.OrderDesc},
+	},
+})
+
+var productOrderInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductOrder",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(productOrderFieldType),
+		},
+		"direction": &graphql.InputObjectFieldConfig{
+			Type:         orderDirectionType,
+			DefaultValue: app.OrderAsc,
+		},
+	},
+})
+
+// Order is a DTO
+type Order struct {
+	ID          string  `json:"id"`
+	ProductID   string  `json:"productID"`
+	BuyerID     string  `json:"buyerID,omitempty"`
+	Price       Money   `json:"price"`
+	Quantity    int     `json:"quantity"`
+	PurchasedAt string  `json:"purchasedAt"`
+	Refunded    int     `json:"refunded"`
+	RefundedAt  *string `json:"refundedAt,omitempty"`
+}
+
+// NewOrder builds an order DTO
+func NewOrder(o app.Order) Order {
+	return Order{
+		ID:          o.ID.String(),
+		ProductID:   o.ProductID.String(),
+		BuyerID:     o.BuyerID,
+		Price:       *NewMoney(&o.Price),
+		Quantity:    o.Quantity,
+		PurchasedAt: o.PurchasedAt.Format(time.RFC3339),
+		Refunded:    o.Refunded,
+		RefundedAt:  formatTime(o.RefundedAt),
+	}
+}
+
+var orderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Order",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"productID": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"buyerID": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The buyer who purchased the product. It's empty for the anonymous purchases",
+		},
 		"price": &graphql.Field{
-			Type: graphql.Float,
+			Type: graphql.NewNonNull(moneyType),
+		},
+		"quantity": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"purchasedAt": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"refunded": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "The number of units of the order that have been refunded",
+		},
+		"refundedAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The time of the last refund. It's empty when the order has not been refunded",
 		},
 	},
 })
@@ -43,6 +363,10 @@ var productType = graphql.NewObject(graphql.ObjectConfig{
 type PurchaseResponse struct {
 	Success bool   `json:"success,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// OrderID is the ID of the order created by a successful purchase
+	OrderID string `json:"orderID,omitempty"`
+	// Retryable tells the client that the operation failed because of a concurrent change, so it can be retried
+	Retryable bool `json:"retryable,omitempty"`
 }
 
 var purchaseResponseType = graphql.NewObject(graphql.ObjectConfig{
@@ -54,6 +378,12 @@ var purchaseResponseType = graphql.NewObject(graphql.ObjectConfig{
 		"error": &graphql.Field{
 			Type: graphql.String,
 		},
+		"retryable": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"orderID": &graphql.Field{
+			Type: graphql.String,
+		},
 	},
 })
 
@@ -63,6 +393,131 @@ var purchaseProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"productID": &graphql.InputObjectFieldConfig{
 			Type: graphql.NewNonNull(graphql.ID),
 		},
+		"quantity": &graphql.InputObjectFieldConfig{
+			Type:         graphql.Int,
+			DefaultValue: 1,
+		},
+		"idempotencyKey": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "When it's provided, a retried purchase with the same key and input returns the original response",
+		},
+		"holder": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+			Description: "The session token the product is held for. It's only needed to purchase a reserved product, " +
+				"and it's ignored when the buyer is authenticated",
+		},
+	},
+})
+
+// CheckoutFailure is a DTO
+type CheckoutFailure struct {
+	ProductID string `json:"productID"`
+	Error     string `json:"error"`
+}
+
+var checkoutFailureType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CheckoutFailure",
+	Fields: graphql.Fields{
+		"productID": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"error": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+	},
+})
+
+// CheckoutResponse is a DTO
+type CheckoutResponse struct {
+	Success   bool   `json:"success,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+	// OrderIDs are the IDs of the orders created by a successful checkout, in the same order as the items
+	OrderIDs []string `json:"orderIDs,omitempty"`
+	// Failures are the reasons why some items could not be purchased
+	Failures []CheckoutFailure `json:"failures,omitempty"`
+}
+
+var checkoutResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CheckoutResponse",
+	Fields: graphql.Fields{
+		"success": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"error": &graphql.Field{
+			Type: graphql.String,
+		},
+		"retryable": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"orderIDs": &graphql.Field{
+			Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+		},
+		"failures": &graphql.Field{
+			Type: graphql.NewList(graphql.NewNonNull(checkoutFailureType)),
+		},
+	},
+})
+
+var checkoutItemInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CheckoutItemInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"productID": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"quantity": &graphql.InputObjectFieldConfig{
+			Type:         graphql.Int,
+			DefaultValue: 1,
+		},
+	},
+})
+
+var checkoutInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CheckoutInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"items": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(checkoutItemInputType))),
+		},
+		"holder": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "The session token the reserved items are held for. It's ignored when the buyer is authenticated",
+		},
+	},
+})
+
+// RefundResponse is a DTO
+type RefundResponse struct {
+	Success   bool   `json:"success,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+var refundResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RefundResponse",
+	Fields: graphql.Fields{
+		"success": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"error": &graphql.Field{
+			Type: graphql.String,
+		},
+		"retryable": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+	},
+})
+
+var refundPurchaseInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RefundPurchaseInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"orderID": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "The order of the purchase to be refunded",
+		},
+		"quantity": &graphql.InputObjectFieldConfig{
+			Type:         graphql.Int,
+			DefaultValue: 1,
+		},
 	},
 })
 
@@ -71,8 +526,82 @@ func queryType(log cqrs.Logger, bus cqrs.Bus) *graphql.Object {
 		Name: "Query",
 		Fields: graphql.Fields{
 			"products": &graphql.Field{
-				Type:    graphql.NewList(productType),
-				Resolve: ProductsResolver(log, bus),
+				Type: graphql.NewList(productType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{
+						Type: productFilterInputType,
+					},
+					"orderBy": &graphql.ArgumentConfig{
+						Type: productOrderInputType,
+					},
+				},
+				Resolve:           ProductsResolver(log, bus),
+				DeprecationReason: "Use productsConnection, which is paginated",
+			},
+			"productsConnection": &graphql.Field{
+				Type: graphql.NewNonNull(productConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{
+						Type: productFilterInputType,
+					},
+					"orderBy": &graphql.ArgumentConfig{
+						Type: productOrderInputType,
+					},
+					"first": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"after": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"last": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"before": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: ProductsConnectionResolver(log, bus),
+			},
+			"searchProducts": &graphql.Field{
+				Type: graphql.NewNonNull(productSearchConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"text": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"first": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"after": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: SearchProductsResolver(log, bus),
+			},
+			"product": &graphql.Field{
+				Type: productType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: ProductResolver(log, bus),
+			},
+			"orders": &graphql.Field{
+				Type:    graphql.NewList(orderType),
+				Resolve: OrdersResolver(log, bus),
+			},
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: OrderResolver(log, bus),
+			},
+			"apiKeys": &graphql.Field{
+				Type:    graphql.NewList(apiKeyType),
+				Resolve: APIKeysResolver(log, bus),
 			},
 		},
 	})
@@ -91,14 +620,87 @@ func mutationType(log cqrs.Logger, bus cqrs.Bus) *graphql.Object {
 				},
 				Resolve: PurchaseProductResolver(log, bus),
 			},
+			"refund_purchase": &graphql.Field{
+				Type: refundResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(refundPurchaseInputType),
+					},
+				},
+				Resolve: RefundPurchaseResolver(log, bus),
+			},
+			"checkout": &graphql.Field{
+				Type: checkoutResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(checkoutInputType),
+					},
+				},
+				Resolve: CheckoutResolver(log, bus),
+			},
+			"createProduct": &graphql.Field{
+				Type: productResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(createProductInputType),
+					},
+				},
+				Resolve: CreateProductResolver(log, bus),
+			},
+			"updateProduct": &graphql.Field{
+				Type: productResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(updateProductInputType),
+					},
+				},
+				Resolve: UpdateProductResolver(log, bus),
+			},
+			"archiveProduct": &graphql.Field{
+				Type: productResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(archiveProductInputType),
+					},
+				},
+				Resolve: ArchiveProductResolver(log, bus),
+			},
+			"reserveProduct": &graphql.Field{
+				Type: reservationResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(reserveProductInputType),
+					},
+				},
+				Resolve: ReserveProductResolver(log, bus),
+			},
+			"createAPIKey": &graphql.Field{
+				Type: apiKeyResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(createAPIKeyInputType),
+					},
+				},
+				Resolve: CreateAPIKeyResolver(log, bus),
+			},
+			"revokeAPIKey": &graphql.Field{
+				Type: apiKeyResponseType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(revokeAPIKeyInputType),
+					},
+				},
+				Resolve: RevokeAPIKeyResolver(log, bus),
+			},
 		},
 	})
 }
 
-func schema(log cqrs.Logger, bus cqrs.Bus) (graphql.Schema, error) {
+func schema(log cqrs.Logger, bus cqrs.Bus, subscriber EventsSubscriber) (graphql.Schema, error) {
 	return graphql.NewSchema(graphql.SchemaConfig{
-		Query:    queryType(log, bus),
-		Mutation: mutationType(log, bus),
+		Query:        queryType(log, bus),
+		Mutation:     mutationType(log, bus),
+		Subscription: subscriptionType(subscriber),
 	})
 }
 
@@ -114,59 +716,572 @@ func ExecuteQuery(query string, schema graphql.Schema) *graphql.Result {
 	return result
 }
 
-// ProductsResolver is a resolver function
+// ProductsResolver is a resolver function. The errors of the filter and the order are returned to the client
 func ProductsResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
 	return func(p graphql.ResolveParams) (interface{}, error) {
-		response, err := bus.Dispatch(context.Background(), app.ProductsQuery{})
+		q, err := productsQueryFromArgs(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return nil, err
+		}
+
+		response, err := bus.Dispatch(p.Context, q)
 		if err != nil {
-			log.Printf("something went wrong when executing the query %s: %s\n", app.ProductsQuery{}.Name(), err.Error())
+			log.Printf("something went wrong when executing the query %s: %s\n", q.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			if errors.Is(err, app.ErrInvalidProductFilter) || errors.Is(err, app.ErrInvalidProductOrder) {
+				return nil, err
+			}
 			return nil, nil
 		}
 		var products []Product
 		for _, item := range response.([]app.Product) {
-			products = append(products, Product{
-				ID:        item.ID.String(),
-				Name:      item.Name,
-				Available: item.Available,
-				Price:     item.Price,
-			})
+			products = append(products, NewProduct(item))
 		}
 		return products, nil
 	}
 }
 
+// moneyFromInput parses a MoneyInput
+func moneyFromInput(input map[string]interface{}) (domain.Money, error) {
+	amount, _ := input["amount"].(string)
+	currency, _ := input["currency"].(string)
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	return domain.ParseMoney(amount, currency)
+}
+
+// productsQueryFromArgs returns the products query of the filter and orderBy arguments
+func productsQueryFromArgs(p graphql.ResolveParams) (app.ProductsQuery, error) {
+	var q app.ProductsQuery
+
+	filter, _ := p.Args["filter"].(map[string]interface{})
+	if available, ok := filter["available"].(bool); ok {
+		q.Filter.Available = &available
+	}
+	for field, price := range map[string]**domain.Money{"minPrice": &q.Filter.MinPrice, "maxPrice": &q.Filter.MaxPrice} {
+		input, ok := filter[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		m, err := moneyFromInput(input)
+		if err != nil {
+			return app.ProductsQuery{}, fmt.Errorf("%w: %s: %s", app.ErrInvalidProductFilter, field, err.Error())
+		}
+		*price = &m
+	}
+	q.Filter.NameContains, _ = filter["nameContains"].(string)
+	if ids, ok := filter["ids"].([]interface{}); ok {
+		q.Filter.IDs = make([]uuid.UUID, 0, len(ids))
+		for _, param := range ids {
+			ID, err := uuid.Parse(param.(string))
+			if err != nil {
+				return app.ProductsQuery{}, fmt.Errorf("%w: invalid product UUID %q", app.ErrInvalidProductFilter, param)
+			}
+			q.Filter.IDs = append(q.Filter.IDs, ID)
+		}
+	}
+
+	order, _ := p.Args["orderBy"].(map[string]interface{})
+	q.Order.Field, _ = order["field"].(app.ProductOrderField)
+	q.Order.Direction, _ = order["direction"].(app.OrderDirection)
+
+	return q, nil
+}
+
+// NotFoundErrorCode is the code of the extensions of a NotFoundError
+const NotFoundErrorCode = "NOT_FOUND"
+
+// NotFoundError is returned to the client when the requested entity does not exist.
+// Its code is sent in the extensions of the GraphQL error, so the clients don't need to parse the message.
+type NotFoundError struct {
+	Entity string
+	ID     string
+}
+
+// Error implements the error.Error interface
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Entity, e.ID)
+}
+
+// Extensions implements the gqlerrors.ExtendedError interface
+func (e NotFoundError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": NotFoundErrorCode}
+}
+
+// ValidationErrorCode is the code of the extensions of a ValidationError
+const ValidationErrorCode = "BAD_USER_INPUT"
+
+// FieldError is a DTO
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned to the client when the input is not valid.
+// Its extensions have the code and all the invalid fields of the input, so the clients can show them next to each field.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError is a constructor
+func NewValidationError(violations []domain.FieldViolation) ValidationError {
+	fields := make([]FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, FieldError{Field: v.Field, Message: v.Reason})
+	}
+	return ValidationError{Fields: fields}
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+// Extensions implements the gqlerrors.ExtendedError interface
+func (e ValidationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ValidationErrorCode, "fields": e.Fields}
+}
+
+// Codes of the extensions of a ForbiddenError
+const (
+	ForbiddenErrorCode       = "FORBIDDEN"
+	UnauthenticatedErrorCode = "UNAUTHENTICATED"
+)
+
+// ForbiddenError is returned to the client when it's not allowed to run the request.
+// Its code is UNAUTHENTICATED when the client has not been authenticated, so it knows that signing in may allow it.
+type ForbiddenError struct {
+	Anonymous bool
+}
+
+// Error implements the error interface
+func (e ForbiddenError) Error() string {
+	if e.Anonymous {
+		return "authentication required"
+	}
+	return "forbidden"
+}
+
+// Extensions implements the gqlerrors.ExtendedError interface
+func (e ForbiddenError) Extensions() map[string]interface{} {
+	if e.Anonymous {
+		return map[string]interface{}{"code": UnauthenticatedErrorCode}
+	}
+	return map[string]interface{}{"code": ForbiddenErrorCode}
+}
+
+// forbiddenError returns the ForbiddenError to be sent to the client when the caller has not been allowed to run a command or query
+func forbiddenError(err error) (ForbiddenError, bool) {
+	var ferr app.ForbiddenError
+	if !errors.As(err, &ferr) {
+		return ForbiddenError{}, false
+	}
+	return ForbiddenError{Anonymous: ferr.Anonymous}, true
+}
+
+// ProductResolver is a resolver function. When the product does not exist, a NotFoundError is returned
+func ProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		param, _ := p.Args["id"].(string)
+		pID, err := uuid.Parse(param)
+		if err != nil {
+			log.Printf("invalid product UUID\n")
+			return nil, errors.New("invalid product UUID")
+		}
+
+		response, err := bus.Dispatch(p.Context, app.ProductQuery{ID: pID})
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", app.ProductQuery{}.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			if errors.Is(err, app.ErrNotFound) {
+				return nil, NotFoundError{Entity: "product", ID: pID.String()}
+			}
+			return nil, errors.New("internal error")
+		}
+		return NewProduct(response.(app.Product)), nil
+	}
+}
+
+// ProductsConnectionResolver is a resolver function. The errors of the pagination, filter and order arguments are returned to the client
+func ProductsConnectionResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		list, err := productsQueryFromArgs(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return nil, err
+		}
+
+		q := app.ProductsConnectionQuery{Filter: list.Filter, Order: list.Order}
+		if first, ok := p.Args["first"].(int); ok {
+			q.First = &first
+		}
+		if last, ok := p.Args["last"].(int); ok {
+			q.Last = &last
+		}
+		q.After, _ = p.Args["after"].(string)
+		q.Before, _ = p.Args["before"].(string)
+
+		response, err := bus.Dispatch(p.Context, q)
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", q.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			if errors.Is(err, app.ErrInvalidCursor) || errors.Is(err, app.ErrInvalidPageSize) ||
+				errors.Is(err, app.ErrInvalidProductFilter) || errors.Is(err, app.ErrInvalidProductOrder) {
+				return nil, err
+			}
+			return nil, errors.New("internal error")
+		}
+
+		page := response.(app.ProductsConnection)
+		connection := ProductConnection{
+			Edges: []ProductEdge{},
+			PageInfo: PageInfo{
+				HasNextPage:     page.PageInfo.HasNextPage,
+				HasPreviousPage: page.PageInfo.HasPreviousPage,
+			},
+		}
+		for _, edge := range page.Edges {
+			connection.Edges = append(connection.Edges, ProductEdge{Cursor: edge.Cursor, Node: NewProduct(edge.Node)})
+		}
+		if len(page.Edges) > 0 {
+			connection.PageInfo.StartCursor = &page.PageInfo.StartCursor
+			connection.PageInfo.EndCursor = &page.PageInfo.EndCursor
+		}
+		return connection, nil
+	}
+}
+
+// SearchProductsResolver is a resolver function. The errors of the search arguments are returned to the client
+func SearchProductsResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		var q app.SearchProductsQuery
+		q.Text, _ = p.Args["text"].(string)
+		if first, ok := p.Args["first"].(int); ok {
+			q.First = &first
+		}
+		q.After, _ = p.Args["after"].(string)
+
+		response, err := bus.Dispatch(p.Context, q)
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", q.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			if errors.Is(err, app.ErrInvalidSearchText) || errors.Is(err, app.ErrInvalidCursor) || errors.Is(err, app.ErrInvalidPageSize) {
+				return nil, err
+			}
+			return nil, errors.New("internal error")
+		}
+
+		page := response.(app.ProductsSearchConnection)
+		connection := ProductSearchConnection{
+			Edges: []ProductSearchEdge{},
+			PageInfo: PageInfo{
+				HasNextPage:     page.PageInfo.HasNextPage,
+				HasPreviousPage: page.PageInfo.HasPreviousPage,
+			},
+		}
+		for _, edge := range page.Edges {
+			connection.Edges = append(connection.Edges, ProductSearchEdge{
+				Cursor:  edge.Cursor,
+				Node:    NewProduct(edge.Node),
+				Rank:    edge.Rank,
+				Snippet: edge.Snippet,
+			})
+		}
+		if len(page.Edges) > 0 {
+			connection.PageInfo.StartCursor = &page.PageInfo.StartCursor
+			connection.PageInfo.EndCursor = &page.PageInfo.EndCursor
+		}
+		return connection, nil
+	}
+}
+
 // PurchaseProductResolver is a resolver function
 func PurchaseProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
 	return func(p graphql.ResolveParams) (interface{}, error) {
-		input, ok := p.Args["input"]
-		if !ok {
-			log.Printf("input field not found\n")
-			return PurchaseResponse{Success: false, Error: errors.New("input field not found").Error()}, nil
+		pID, err := productIDFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return PurchaseResponse{Success: false, Error: err.Error()}, nil
 		}
-		param, ok := input.(map[string]interface{})["productID"]
-		if !ok {
-			log.Printf("productID field not found\n")
-			return PurchaseResponse{Success: false, Error: errors.New("productID field not found").Error()}, nil
+
+		quantity, err := quantityFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return PurchaseResponse{Success: false, Error: err.Error()}, nil
 		}
 
-		pID, error := uuid.Parse(param.(string))
-		if error != nil {
-			log.Printf("invalid product UUID\n")
-			return PurchaseResponse{Success: false, Error: errors.New("invalid product UUID").Error()}, nil
+		key, err := idempotencyKeyFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return PurchaseResponse{Success: false, Error: err.Error()}, nil
 		}
 
-		_, err := bus.Dispatch(context.Background(), app.PurchaseProductCmd{ID: pID})
+		// When there is an idempotency key, the order ID is derived from it. So a replay returns the original order ID.
+		orderID := uuid.New()
+		client := clientFromContext(p.Context)
+		if key != "" {
+			orderID = app.IDFromIdempotencyKey(client, key)
+		}
+
+		cmd := app.PurchaseProductCmd{
+			ID:       pID,
+			OrderID:  orderID,
+			Quantity: quantity,
+			Key:      key,
+			Client:   client,
+			Holder:   holderFromInput(p),
+			BuyerID:  buyerIDFromContext(p),
+		}
+		_, err = bus.Dispatch(p.Context, cmd)
 		if err != nil {
 			log.Printf("something went wrong when executing the command %s: %s\n", app.PurchaseProductCmd{}.Name(), err.Error())
-			if errors.Is(err, domain.ErrProductPurchased) {
-				return PurchaseResponse{Success: false, Error: errors.New("productID not available for purchasing").Error()}, nil
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			msg, retryable := purchaseErrorMessage(err)
+			return PurchaseResponse{Success: false, Error: msg, Retryable: retryable}, nil
+		}
+
+		return PurchaseResponse{Success: true, OrderID: orderID.String()}, nil
+	}
+}
+
+// purchaseErrorMessage returns the message to be sent to the client for an error found purchasing a product,
+// and whether the purchase can be retried
+func purchaseErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, domain.ErrProductPurchased):
+		return "productID not available for purchasing", false
+	case errors.Is(err, domain.ErrProductReserved):
+		return "productID reserved by another buyer", false
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return "not enough units of productID in stock", false
+	case errors.Is(err, domain.ErrInvalidQuantity):
+		return "invalid quantity", false
+	case errors.Is(err, domain.ErrProductWithoutPrice):
+		return "productID has no price", false
+	case errors.Is(err, app.ErrNotFound):
+		return "productID not found", false
+	case errors.Is(err, app.ErrVersionConflict):
+		return "product modified concurrently", true
+	case errors.Is(err, app.ErrIdempotencyKeyReused):
+		return "idempotencyKey already used with another input", false
+	default:
+		return "internal error", false
+	}
+}
+
+// CheckoutResolver is a resolver function
+func CheckoutResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		input, _ := p.Args["input"].(map[string]interface{})
+		items, ok := input["items"].([]interface{})
+		if !ok {
+			log.Printf("items field not found\n")
+			return CheckoutResponse{Success: false, Error: errors.New("items field not found").Error()}, nil
+		}
+
+		cmd := app.CheckoutCmd{Holder: holderFromInput(p), BuyerID: buyerIDFromContext(p)}
+		for _, item := range items {
+			itemParams := graphql.ResolveParams{Args: map[string]interface{}{"input": item}}
+			pID, err := productIDFromInput(itemParams)
+			if err != nil {
+				log.Printf("%s\n", err.Error())
+				return CheckoutResponse{Success: false, Error: err.Error()}, nil
+			}
+			quantity, err := quantityFromInput(itemParams)
+			if err != nil {
+				log.Printf("%s\n", err.Error())
+				return CheckoutResponse{Success: false, Error: err.Error()}, nil
+			}
+			cmd.Items = append(cmd.Items, app.CheckoutItem{ProductID: pID, Quantity: quantity, OrderID: uuid.New()})
+		}
+
+		_, err := bus.Dispatch(p.Context, cmd)
+		if err != nil {
+			log.Printf("something went wrong when executing the command %s: %s\n", cmd.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			var checkoutErr app.CheckoutError
+			if errors.As(err, &checkoutErr) {
+				response := CheckoutResponse{Success: false, Error: errors.New("some items can't be purchased").Error()}
+				for _, f := range checkoutErr.Failures {
+					msg, _ := purchaseErrorMessage(f.Err)
+					response.Failures = append(response.Failures, CheckoutFailure{ProductID: f.ProductID.String(), Error: msg})
+				}
+				return response, nil
+			}
+			if errors.Is(err, app.ErrEmptyCheckout) {
+				return CheckoutResponse{Success: false, Error: errors.New("no items to checkout").Error()}, nil
+			}
+			msg, retryable := purchaseErrorMessage(err)
+			return CheckoutResponse{Success: false, Error: msg, Retryable: retryable}, nil
+		}
+
+		response := CheckoutResponse{Success: true}
+		for _, item := range cmd.Items {
+			response.OrderIDs = append(response.OrderIDs, item.OrderID.String())
+		}
+		return response, nil
+	}
+}
+
+// RefundPurchaseResolver is a resolver function
+func RefundPurchaseResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		input, _ := p.Args["input"].(map[string]interface{})
+		param, _ := input["orderID"].(string)
+		orderID, err := uuid.Parse(param)
+		if err != nil {
+			log.Printf("invalid order UUID\n")
+			return RefundResponse{Success: false, Error: "invalid order UUID"}, nil
+		}
+
+		quantity, err := quantityFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return RefundResponse{Success: false, Error: err.Error()}, nil
+		}
+
+		_, err = bus.Dispatch(p.Context, app.RefundPurchaseCmd{OrderID: orderID, Quantity: quantity})
+		if err != nil {
+			log.Printf("something went wrong when executing the command %s: %s\n", app.RefundPurchaseCmd{}.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			if errors.Is(err, domain.ErrRefundExceedsOrder) {
+				return RefundResponse{Success: false, Error: errors.New("the units of the order have already been refunded").Error()}, nil
+			}
+			if errors.Is(err, domain.ErrProductNotPurchased) {
+				return RefundResponse{Success: false, Error: errors.New("product has not been purchased").Error()}, nil
+			}
+			if errors.Is(err, domain.ErrProductArchived) {
+				return RefundResponse{Success: false, Error: errors.New("the product of the order is archived").Error()}, nil
 			}
 			if errors.Is(err, app.ErrNotFound) {
-				return PurchaseResponse{Success: false, Error: errors.New("productID not found").Error()}, nil
+				return RefundResponse{Success: false, Error: errors.New("orderID not found").Error()}, nil
+			}
+			if errors.Is(err, app.ErrVersionConflict) {
+				return RefundResponse{Success: false, Error: errors.New("product modified concurrently").Error(), Retryable: true}, nil
 			}
-			return PurchaseResponse{Success: false, Error: errors.New("internal error").Error()}, nil
+			return RefundResponse{Success: false, Error: errors.New("internal error").Error()}, nil
 		}
 
-		return PurchaseResponse{Success: true}, nil
+		return RefundResponse{Success: true}, nil
+	}
+}
+
+// productIDFromInput returns the productID field of the input argument
+func productIDFromInput(p graphql.ResolveParams) (uuid.UUID, error) {
+	input, ok := p.Args["input"]
+	if !ok {
+		return uuid.Nil, errors.New("input field not found")
+	}
+	param, ok := input.(map[string]interface{})["productID"]
+	if !ok {
+		return uuid.Nil, errors.New("productID field not found")
+	}
+
+	pID, err := uuid.Parse(param.(string))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid product UUID")
+	}
+	return pID, nil
+}
+
+// holderFromInput returns the holder field of the input argument. It's empty if it's not provided
+func holderFromInput(p graphql.ResolveParams) string {
+	input, _ := p.Args["input"].(map[string]interface{})
+	holder, _ := input["holder"].(string)
+	return holder
+}
+
+// idempotencyKeyFromInput returns the idempotencyKey field of the input argument. It's empty if it's not provided
+func idempotencyKeyFromInput(p graphql.ResolveParams) (string, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+	key, _ := input["idempotencyKey"].(string)
+	if utf8.RuneCountInString(key) > app.MaxIdempotencyKeyLength {
+		return "", fmt.Errorf("idempotencyKey must have at most %d characters", app.MaxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// buyerIDFromContext returns the ID of the authenticated caller. It's empty when the caller is anonymous
+func buyerIDFromContext(p graphql.ResolveParams) string {
+	principal, _ := app.PrincipalFromContext(p.Context)
+	return principal.ID
+}
+
+// quantityFromInput returns the quantity field of the input argument. It's 1 if it's not provided
+func quantityFromInput(p graphql.ResolveParams) (int, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+	param, ok := input["quantity"]
+	if !ok || param == nil {
+		return 1, nil
+	}
+	quantity, ok := param.(int)
+	if !ok || quantity < 1 {
+		return 0, errors.New("invalid quantity")
+	}
+	return quantity, nil
+}
+
+// OrdersResolver is a resolver function
+func OrdersResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		response, err := bus.Dispatch(p.Context, app.OrdersQuery{})
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", app.OrdersQuery{}.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			return nil, nil
+		}
+		var orders []Order
+		for _, item := range response.([]app.Order) {
+			orders = append(orders, NewOrder(item))
+		}
+		return orders, nil
+	}
+}
+
+// OrderResolver is a resolver function. It returns null when the order is not found
+func OrderResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		param, ok := p.Args["id"].(string)
+		if !ok {
+			log.Printf("id field not found\n")
+			return nil, nil
+		}
+		oID, err := uuid.Parse(param)
+		if err != nil {
+			log.Printf("invalid order UUID\n")
+			return nil, nil
+		}
+
+		response, err := bus.Dispatch(p.Context, app.OrderQuery{ID: oID})
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", app.OrderQuery{}.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			return nil, nil
+		}
+		return NewOrder(response.(app.Order)), nil
 	}
 }
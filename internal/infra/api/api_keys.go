@@ -0,0 +1,224 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// APIKey is a DTO. The times are in RFC 3339 format
+type APIKey struct {
+	ID         string   `json:"id,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
+	LastUsedAt *string  `json:"lastUsedAt,omitempty"`
+	RevokedAt  *string  `json:"revokedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt,omitempty"`
+}
+
+// NewAPIKey is a constructor
+func NewAPIKey(k app.APIKey) APIKey {
+	return APIKey{
+		ID:         k.ID.String(),
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		ExpiresAt:  formatTime(k.ExpiresAt),
+		LastUsedAt: formatTime(k.LastUsedAt),
+		RevokedAt:  formatTime(k.RevokedAt),
+		CreatedAt:  k.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
+var apiKeyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "APIKey",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"name": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"scopes": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "The roles granted to the clients of the key",
+		},
+		"expiresAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "It's empty for the keys that don't expire",
+		},
+		"lastUsedAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "It's recorded once a minute at most",
+		},
+		"revokedAt": &graphql.Field{
+			Type: graphql.String,
+		},
+		"createdAt": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+	},
+})
+
+// APIKeyResponse is a DTO
+type APIKeyResponse struct {
+	Success bool    `json:"success,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	APIKey  *APIKey `json:"apiKey,omitempty"`
+	// Key is the plaintext key. It's only returned when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+var apiKeyResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "APIKeyResponse",
+	Fields: graphql.Fields{
+		"success": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"error": &graphql.Field{
+			Type: graphql.String,
+		},
+		"apiKey": &graphql.Field{
+			Type: apiKeyType,
+		},
+		"key": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The key to be sent in the X-API-Key header. It's only returned when the key is created, so it must be kept then",
+		},
+	},
+})
+
+var createAPIKeyInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateAPIKeyInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"scopes": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "The roles granted to the clients of the key: buyer, merchandiser or admin",
+		},
+		"expiresAt": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "The time the key expires, in RFC 3339 format. When it's not provided, the key doesn't expire",
+		},
+	},
+})
+
+var revokeAPIKeyInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RevokeAPIKeyInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	},
+})
+
+// CreateAPIKeyResolver is a resolver function. The key is generated here, so it's only known by the response.
+// When the input is not valid, a ValidationError is returned
+func CreateAPIKeyResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		input, _ := p.Args["input"].(map[string]interface{})
+
+		cmd := app.CreateAPIKeyCmd{ID: uuid.New()}
+		cmd.KeyName, _ = input["name"].(string)
+		scopes, _ := input["scopes"].([]interface{})
+		for _, s := range scopes {
+			scope, _ := s.(string)
+			cmd.Scopes = append(cmd.Scopes, scope)
+		}
+		if param, ok := input["expiresAt"].(string); ok {
+			expiresAt, err := time.Parse(time.RFC3339, param)
+			if err != nil {
+				log.Printf("invalid API key expiry %q\n", param)
+				return nil, ValidationError{Fields: []FieldError{{Field: "expiresAt", Message: "it's not a RFC 3339 time"}}}
+			}
+			cmd.ExpiresAt = &expiresAt
+		}
+
+		key, err := app.NewAPIKeySecret()
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return APIKeyResponse{Success: false, Error: "internal error"}, nil
+		}
+		cmd.Key = key
+
+		if _, err := bus.Dispatch(p.Context, cmd); err != nil {
+			log.Printf("something went wrong when executing the command %s: %s\n", cmd.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			var verr domain.ValidationError
+			if errors.As(err, &verr) {
+				return nil, NewValidationError(verr.Violations)
+			}
+			return APIKeyResponse{Success: false, Error: "internal error"}, nil
+		}
+
+		created := NewAPIKey(app.APIKey{ID: cmd.ID, Name: cmd.KeyName, Scopes: cmd.Scopes, ExpiresAt: cmd.ExpiresAt, CreatedAt: time.Now()})
+		return APIKeyResponse{Success: true, APIKey: &created, Key: key}, nil
+	}
+}
+
+// RevokeAPIKeyResolver is a resolver function
+func RevokeAPIKeyResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		input, _ := p.Args["input"].(map[string]interface{})
+		param, _ := input["id"].(string)
+		ID, err := uuid.Parse(param)
+		if err != nil {
+			log.Printf("invalid API key UUID\n")
+			return APIKeyResponse{Success: false, Error: "invalid API key UUID"}, nil
+		}
+
+		cmd := app.RevokeAPIKeyCmd{ID: ID}
+		if _, err := bus.Dispatch(p.Context, cmd); err != nil {
+			log.Printf("something went wrong when executing the command %s: %s\n", cmd.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			switch {
+			case errors.Is(err, app.ErrNotFound):
+				return APIKeyResponse{Success: false, Error: "id not found"}, nil
+			case errors.Is(err, domain.ErrAPIKeyRevoked):
+				return APIKeyResponse{Success: false, Error: "id already revoked"}, nil
+			default:
+				return APIKeyResponse{Success: false, Error: "internal error"}, nil
+			}
+		}
+		return APIKeyResponse{Success: true}, nil
+	}
+}
+
+// APIKeysResolver is a resolver function
+func APIKeysResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		response, err := bus.Dispatch(p.Context, app.APIKeysQuery{})
+		if err != nil {
+			log.Printf("something went wrong when executing the query %s: %s\n", app.APIKeysQuery{}.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			return nil, errors.New("internal error")
+		}
+		keys := []APIKey{}
+		for _, k := range response.([]app.APIKey) {
+			keys = append(keys, NewAPIKey(k))
+		}
+		return keys, nil
+	}
+}
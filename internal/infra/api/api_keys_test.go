@@ -0,0 +1,130 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAPIKeyResolver(t *testing.T) {
+	input := func(expiresAt interface{}) graphql.ResolveParams {
+		in := map[string]interface{}{"name": "erp", "scopes": []interface{}{"merchandiser"}}
+		if expiresAt != nil {
+			in["expiresAt"] = expiresAt
+		}
+		return graphql.ResolveParams{Args: map[string]interface{}{"input": in}}
+	}
+
+	t.Run(`Given a valid input, when it's called, then the key is returned once and the command carries it`, func(t *testing.T) {
+		bm := &recordingBusMock{}
+		response, err := api.CreateAPIKeyResolver(&loggerMock{}, bm)(input("2100-01-02T15:04:05Z"))
+		require.NoError(t, err)
+
+		got := response.(api.APIKeyResponse)
+		require.True(t, got.Success)
+		require.NotEmpty(t, got.Key)
+		require.Equal(t, []string{"merchandiser"}, got.APIKey.Scopes)
+		require.Equal(t, "2100-01-02T15:04:05Z", *got.APIKey.ExpiresAt)
+
+		require.Len(t, bm.dispatched, 1)
+		cmd := bm.dispatched[0].(app.CreateAPIKeyCmd)
+		require.Equal(t, got.Key, cmd.Key)
+		require.Equal(t, got.APIKey.ID, cmd.ID.String())
+		require.Equal(t, "erp", cmd.KeyName)
+	})
+
+	t.Run(`Given an expiry that is not a RFC 3339 time, when it's called, then a ValidationError is returned`, func(t *testing.T) {
+		bm := &recordingBusMock{}
+		_, err := api.CreateAPIKeyResolver(&loggerMock{}, bm)(input("tomorrow"))
+		require.ErrorAs(t, err, &api.ValidationError{})
+		require.Empty(t, bm.dispatched)
+	})
+
+	t.Run(`Given a bus that returns a validation error, when it's called, then a ValidationError with its fields is returned`, func(t *testing.T) {
+		verr := domain.ValidationError{
+			Entity:     domain.APIKeyEntity,
+			Violations: []domain.FieldViolation{{Field: "scopes", Reason: `unknown scope "system"`, Err: domain.ErrInvalidAPIKeyScope}},
+		}
+		_, err := api.CreateAPIKeyResolver(&loggerMock{}, busMock{expectedError: verr})(input(nil))
+		require.Equal(t, api.NewValidationError(verr.Violations), err)
+	})
+}
+
+func TestRevokeAPIKeyResolver(t *testing.T) {
+	params := graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"id": uuid.New().String()}}}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+	}{
+		{
+			name:             `Given an invalid UUID, when it's called, then an error response is returned`,
+			params:           graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"id": "invalid"}}},
+			lm:               &loggerMock{},
+			expectedResponse: api.APIKeyResponse{Error: "invalid API key UUID"},
+			expectedLogCalls: 1,
+		},
+		{
+			name:             `Given a bus that returns app.ErrNotFound, when it's called, then an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrNotFound},
+			lm:               &loggerMock{},
+			expectedResponse: api.APIKeyResponse{Error: "id not found"},
+			expectedLogCalls: 1,
+		},
+		{
+			name:             `Given a bus that returns domain.ErrAPIKeyRevoked, when it's called, then an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrAPIKeyRevoked},
+			lm:               &loggerMock{},
+			expectedResponse: api.APIKeyResponse{Error: "id already revoked"},
+			expectedLogCalls: 1,
+		},
+		{
+			name:             `Given a bus that returns no error, when it's called, then a success response is returned`,
+			params:           params,
+			lm:               &loggerMock{},
+			expectedResponse: api.APIKeyResponse{Success: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		response, err := api.RevokeAPIKeyResolver(tc.lm, tc.bm)(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestAPIKeysResolver(t *testing.T) {
+	t.Run(`Given a bus that returns keys, when it's called, then they're returned`, func(t *testing.T) {
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		k := app.APIKey{ID: uuid.New(), Name: "erp", Scopes: []string{"admin"}, RevokedAt: &createdAt, CreatedAt: createdAt}
+		response, err := api.APIKeysResolver(&loggerMock{}, busMock{expectedResult: []app.APIKey{k}})(graphql.ResolveParams{})
+		require.NoError(t, err)
+		revokedAt := "2024-01-02T03:04:05Z"
+		require.Equal(t, []api.APIKey{{
+			ID:        k.ID.String(),
+			Name:      "erp",
+			Scopes:    []string{"admin"},
+			RevokedAt: &revokedAt,
+			CreatedAt: "2024-01-02T03:04:05Z",
+		}}, response)
+	})
+
+	t.Run(`Given a bus that returns an error, when it's called, then an error is returned`, func(t *testing.T) {
+		_, err := api.APIKeysResolver(&loggerMock{}, busMock{expectedError: errors.New("")})(graphql.ResolveParams{})
+		require.Error(t, err)
+	})
+}
@@ -3,16 +3,20 @@ package api_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
 	"theskyinflames/graphql-challenge/internal/infra/api"
 
 	"github.com/google/uuid"
 	"github.com/graphql-go/graphql"
 	"github.com/stretchr/testify/require"
 	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
 )
 
 type busMock struct {
@@ -33,12 +37,14 @@ func (lm *loggerMock) Printf(string, ...interface{}) {
 }
 
 func TestProductsResolver(t *testing.T) {
+	prices := []domain.Money{fixtures.Money("1.1"), fixtures.Money("2.2")}
 	products := []app.Product{
-		{ID: uuid.New(), Name: "product1", Available: true, Price: 1.1},
-		{ID: uuid.New(), Name: "product2", Available: true, Price: 2.2},
+		{ID: uuid.New(), Name: "product1", Available: true, Price: &prices[0]},
+		{ID: uuid.New(), Name: "product2", Available: true, Price: &prices[1]},
 	}
 	testCases := []struct {
 		name             string
+		params           graphql.ResolveParams
 		bm               busMock
 		lm               *loggerMock
 		expectedResponse interface{}
@@ -65,19 +71,349 @@ func TestProductsResolver(t *testing.T) {
 			lm:               &loggerMock{},
 			expectedResponse: products,
 		},
+		{
+			name: `Given a filter with an invalid product ID, 
+				when it's called, 
+				then the error is logged and returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"filter": map[string]interface{}{"ids": []interface{}{"invalid"}}},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidProductFilter,
+		},
+		{
+			name: `Given a filter with an invalid price, 
+				when it's called, 
+				then the error is logged and returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"filter": map[string]interface{}{
+					"minPrice": map[string]interface{}{"amount": "1,1", "currency": "EUR"},
+				}},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidProductFilter,
+		},
+		{
+			name: `Given a bus that returns an app.ErrInvalidProductOrder error, 
+				when it's called, 
+				then the error is logged and returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"orderBy": map[string]interface{}{"field": app.ProductOrderByPrice}},
+			},
+			bm: busMock{
+				expectedError: app.ErrInvalidProductOrder,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidProductOrder,
+		},
+		{
+			name: `Given a filter and an order, 
+				when it's called, 
+				then the list of products is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"filter": map[string]interface{}{
+						"available":    true,
+						"minPrice":     map[string]interface{}{"amount": "1.1", "currency": "EUR"},
+						"nameContains": "product",
+						"ids":          []interface{}{products[0].ID.String()},
+					},
+					"orderBy": map[string]interface{}{"field": app.ProductOrderByPrice, "direction": app.OrderDesc},
+				},
+			},
+			bm: busMock{
+				expectedResult: products,
+			},
+			lm:               &loggerMock{},
+			expectedResponse: products,
+		},
 	}
 
 	for _, tc := range testCases {
 		pr := api.ProductsResolver(tc.lm, tc.bm)
-		response, err := pr(graphql.ResolveParams{})
-		require.NoError(t, err)
-		require.Equal(t, tc.expectedLogCalls, tc.lm.calls)
+		response, err := pr(tc.params)
+		require.ErrorIs(t, err, tc.expectedError, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
 		if tc.expectedResponse != nil {
 			require.Len(t, tc.expectedResponse, len(response.([]api.Product)))
 		}
 	}
 }
 
+func TestProductResolver(t *testing.T) {
+	price := fixtures.Money("1.1")
+	product := app.Product{ID: uuid.New(), Name: "product1", Available: true, Price: &price, Quantity: 1, Version: 1}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+		expectedErrFunc  func(*testing.T, error)
+	}{
+		{
+			name: `Given a query with an invalid id, 
+				when it's called, 
+				then the error is logged and returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": "invalid"},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.EqualError(t, err, "invalid product UUID")
+			},
+		},
+		{
+			name: `Given a bus that returns an app.ErrNotFound error, 
+				when it's called, 
+				then the error is logged and a not found error is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": product.ID.String()},
+			},
+			bm: busMock{
+				expectedError: app.ErrNotFound,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedErrFunc: func(t *testing.T, err error) {
+				var nfErr api.NotFoundError
+				require.ErrorAs(t, err, &nfErr)
+				require.Equal(t, product.ID.String(), nfErr.ID)
+				require.Equal(t, api.NotFoundErrorCode, nfErr.Extensions()["code"])
+			},
+		},
+		{
+			name: `Given a bus that returns an unexpected error, 
+				when it's called, 
+				then the error is logged and an internal error is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": product.ID.String()},
+			},
+			bm: busMock{
+				expectedError: errors.New("connection refused"),
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedErrFunc: func(t *testing.T, err error) {
+				require.EqualError(t, err, "internal error")
+			},
+		},
+		{
+			name: `Given a bus that returns a product, 
+				when it's called, 
+				then the product is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": product.ID.String()},
+			},
+			bm: busMock{
+				expectedResult: product,
+			},
+			lm:               &loggerMock{},
+			expectedResponse: api.NewProduct(product),
+		},
+	}
+
+	for _, tc := range testCases {
+		pr := api.ProductResolver(tc.lm, tc.bm)
+		response, err := pr(tc.params)
+		require.Equal(t, tc.expectedErrFunc == nil, err == nil, tc.name)
+		if err != nil {
+			tc.expectedErrFunc(t, err)
+		}
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse == nil {
+			require.Nil(t, response, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestProductsConnectionResolver(t *testing.T) {
+	price := fixtures.Money("1.1")
+	product := app.Product{ID: uuid.New(), Name: "product1", Available: true, Price: &price}
+	cursor := app.EncodeProductCursor(product.ID)
+	testCases := []struct {
+		name             string
+		args             map[string]interface{}
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+		expectedError    error
+		expectedErrorIs  error
+	}{
+		{
+			name: `Given a bus that returns an app.ErrInvalidCursor error, 
+				when it's called, 
+				then the error is logged and returned`,
+			bm: busMock{
+				expectedError: app.ErrInvalidCursor,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidCursor,
+		},
+		{
+			name: `Given a bus that returns an app.ErrInvalidProductOrder error, 
+				when it's called, 
+				then the error is logged and returned`,
+			bm: busMock{
+				expectedError: app.ErrInvalidProductOrder,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidProductOrder,
+		},
+		{
+			name: `Given an invalid price in the filter, 
+				when it's called, 
+				then the error is logged and returned`,
+			args:             map[string]interface{}{"filter": map[string]interface{}{"minPrice": map[string]interface{}{"amount": "x"}}},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedErrorIs:  app.ErrInvalidProductFilter,
+		},
+		{
+			name: `Given a bus that returns an unexpected error, 
+				when it's called, 
+				then the error is logged and an internal error is returned`,
+			bm: busMock{
+				expectedError: errors.New("connection refused"),
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    errors.New("internal error"),
+		},
+		{
+			name: `Given a bus that returns an empty page, 
+				when it's called, 
+				then an empty connection without cursors is returned`,
+			bm: busMock{
+				expectedResult: app.ProductsConnection{},
+			},
+			lm:               &loggerMock{},
+			expectedResponse: api.ProductConnection{Edges: []api.ProductEdge{}},
+		},
+		{
+			name: `Given a bus that returns a page of products, 
+				when it's called, 
+				then the connection is returned`,
+			bm: busMock{
+				expectedResult: app.ProductsConnection{
+					Edges:    []app.ProductEdge{{Cursor: cursor, Node: product}},
+					PageInfo: app.PageInfo{HasNextPage: true, StartCursor: cursor, EndCursor: cursor},
+				},
+			},
+			lm: &loggerMock{},
+			expectedResponse: api.ProductConnection{
+				Edges:    []api.ProductEdge{{Cursor: cursor, Node: api.NewProduct(product)}},
+				PageInfo: api.PageInfo{HasNextPage: true, StartCursor: &cursor, EndCursor: &cursor},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		args := tc.args
+		if args == nil {
+			args = map[string]interface{}{"first": 1}
+		}
+		pr := api.ProductsConnectionResolver(tc.lm, tc.bm)
+		response, err := pr(graphql.ResolveParams{Args: args})
+		if tc.expectedErrorIs != nil {
+			require.ErrorIs(t, err, tc.expectedErrorIs, tc.name)
+		} else {
+			require.Equal(t, tc.expectedError, err, tc.name)
+		}
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse == nil {
+			require.Nil(t, response, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestSearchProductsResolver(t *testing.T) {
+	price := fixtures.Money("1.1")
+	product := app.Product{ID: uuid.New(), Name: "red shoes", Available: true, Price: &price}
+	cursor := "c1"
+	testCases := []struct {
+		name             string
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+		expectedError    error
+	}{
+		{
+			name: `Given a bus that returns an app.ErrInvalidSearchText error, 
+				when it's called, 
+				then the error is logged and returned`,
+			bm: busMock{
+				expectedError: app.ErrInvalidSearchText,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    app.ErrInvalidSearchText,
+		},
+		{
+			name: `Given a bus that returns an unexpected error, 
+				when it's called, 
+				then the error is logged and an internal error is returned`,
+			bm: busMock{
+				expectedError: errors.New("connection refused"),
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    errors.New("internal error"),
+		},
+		{
+			name: `Given a bus that returns no hits, 
+				when it's called, 
+				then an empty connection without cursors is returned`,
+			bm: busMock{
+				expectedResult: app.ProductsSearchConnection{},
+			},
+			lm:               &loggerMock{},
+			expectedResponse: api.ProductSearchConnection{Edges: []api.ProductSearchEdge{}},
+		},
+		{
+			name: `Given a bus that returns a page of hits, 
+				when it's called, 
+				then the connection is returned`,
+			bm: busMock{
+				expectedResult: app.ProductsSearchConnection{
+					Edges:    []app.ProductSearchEdge{{Cursor: cursor, Node: product, Rank: 0.5, Snippet: "<mark>red</mark> shoes"}},
+					PageInfo: app.PageInfo{HasNextPage: true, StartCursor: cursor, EndCursor: cursor},
+				},
+			},
+			lm: &loggerMock{},
+			expectedResponse: api.ProductSearchConnection{
+				Edges:    []api.ProductSearchEdge{{Cursor: cursor, Node: api.NewProduct(product), Rank: 0.5, Snippet: "<mark>red</mark> shoes"}},
+				PageInfo: api.PageInfo{HasNextPage: true, StartCursor: &cursor, EndCursor: &cursor},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		sr := api.SearchProductsResolver(tc.lm, tc.bm)
+		response, err := sr(graphql.ResolveParams{Args: map[string]interface{}{"text": "red", "first": 1}})
+		require.Equal(t, tc.expectedError, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse == nil {
+			require.Nil(t, response, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
 func TestPurchaseProductResolver(t *testing.T) {
 	randomErr := errors.New("randomErr")
 	testCases := []struct {
@@ -186,9 +522,9 @@ func TestPurchaseProductResolver(t *testing.T) {
 			},
 		},
 		{
-			name: `Given a bus that returns no error, 
+			name: `Given a bus that returns an app.ErrVersionConflict error, 
 				when it's called, 
-				then a success response is returned`,
+				then the error is logged and a retryable response is returned`,
 			params: graphql.ResolveParams{
 				Args: map[string]interface{}{
 					"input": map[string]interface{}{
@@ -196,21 +532,542 @@ func TestPurchaseProductResolver(t *testing.T) {
 					},
 				},
 			},
-			bm: busMock{},
-			lm: &loggerMock{},
+			bm: busMock{
+				expectedError: app.ErrVersionConflict,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
 			expectedResponse: api.PurchaseResponse{
-				Success: true,
+				Success:   false,
+				Error:     "product modified concurrently",
+				Retryable: true,
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		pr := api.PurchaseProductResolver(tc.lm, tc.bm)
-		response, err := pr(tc.params)
-		require.NoError(t, err, tc.name)
-		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
-		if tc.expectedResponse != nil {
-			require.Equal(t, tc.expectedResponse, response, tc.name)
+		{
+			name: `Given a query with an invalid quantity, 
+				when it's called, 
+				then the error is logged and an empty response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID": uuid.New().String(),
+						"quantity":  0,
+					},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.PurchaseResponse{
+				Success: false,
+				Error:   "invalid quantity",
+			},
+		},
+		{
+			name: `Given a bus that returns an domain.ErrInsufficientStock error, 
+				when it's called, 
+				then the error is logged and an empty response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID": uuid.New().String(),
+						"quantity":  2,
+					},
+				},
+			},
+			bm: busMock{
+				expectedError: domain.ErrInsufficientStock,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.PurchaseResponse{
+				Success: false,
+				Error:   "not enough units of productID in stock",
+			},
+		},
+		{
+			name: `Given a bus that returns an domain.ErrProductWithoutPrice error, 
+				when it's called, 
+				then the error is logged and an empty response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID": uuid.New().String(),
+					},
+				},
+			},
+			bm: busMock{
+				expectedError: domain.ErrProductWithoutPrice,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.PurchaseResponse{
+				Success: false,
+				Error:   "productID has no price",
+			},
+		},
+		{
+			name: `Given a bus that returns no error, 
+				when it's called, 
+				then a success response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID": uuid.New().String(),
+					},
+				},
+			},
+			bm: busMock{},
+			lm: &loggerMock{},
+			expectedResponse: api.PurchaseResponse{
+				Success: true,
+			},
+		},
+		{
+			name: `Given a bus that returns an app.ErrIdempotencyKeyReused error, 
+				when it's called, 
+				then the error is logged and an empty response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID":      uuid.New().String(),
+						"idempotencyKey": "key",
+					},
+				},
+			},
+			bm: busMock{
+				expectedError: app.ErrIdempotencyKeyReused,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.PurchaseResponse{
+				Success: false,
+				Error:   "idempotencyKey already used with another input",
+			},
+		},
+		{
+			name: `Given a query with an idempotency key and a bus that returns no error, 
+				when it's called, 
+				then a success response with the order ID derived from the key is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID":      uuid.New().String(),
+						"idempotencyKey": "key",
+					},
+				},
+			},
+			bm: busMock{},
+			lm: &loggerMock{},
+			expectedResponse: api.PurchaseResponse{
+				Success: true,
+				OrderID: app.IDFromIdempotencyKey("", "key").String(),
+			},
+		},
+		{
+			name: `Given a query with a too long idempotency key, 
+				when it's called, 
+				then the error is logged and an error response is returned without dispatching the command`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"productID":      uuid.New().String(),
+						"idempotencyKey": strings.Repeat("k", app.MaxIdempotencyKeyLength+1),
+					},
+				},
+			},
+			bm:               busMock{expectedError: errors.New("")},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.PurchaseResponse{
+				Success: false,
+				Error:   "idempotencyKey must have at most 255 characters",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		pr := api.PurchaseProductResolver(tc.lm, tc.bm)
+		response, err := pr(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse != nil {
+			got := response.(api.PurchaseResponse)
+			if got.Success && tc.expectedResponse.(api.PurchaseResponse).OrderID == "" {
+				// the order ID is generated by the resolver
+				_, err := uuid.Parse(got.OrderID)
+				require.NoError(t, err, tc.name)
+				got.OrderID = ""
+			}
+			require.Equal(t, tc.expectedResponse, got, tc.name)
+		}
+	}
+}
+
+func TestOrdersResolver(t *testing.T) {
+	orders := []app.Order{
+		{ID: uuid.New(), ProductID: uuid.New(), Price: fixtures.Money("1.1"), PurchasedAt: time.Now()},
+		{ID: uuid.New(), ProductID: uuid.New(), Price: fixtures.Money("2.2"), PurchasedAt: time.Now()},
+	}
+	testCases := []struct {
+		name             string
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse []app.Order
+		expectedLogCalls int
+	}{
+		{
+			name: `Given a bus that returns an error, 
+				when it's called, 
+				then the error is logged and an empty response is returned`,
+			bm: busMock{
+				expectedError: errors.New(""),
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+		},
+		{
+			name: `Given a bus that returns a list of orders, 
+				when it's called, 
+				then the the list of orders is returned`,
+			bm: busMock{
+				expectedResult: orders,
+			},
+			lm:               &loggerMock{},
+			expectedResponse: orders,
+		},
+	}
+
+	for _, tc := range testCases {
+		or := api.OrdersResolver(tc.lm, tc.bm)
+		response, err := or(graphql.ResolveParams{})
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse != nil {
+			require.Len(t, response.([]api.Order), len(tc.expectedResponse), tc.name)
+		}
+	}
+}
+
+func TestOrderResolver(t *testing.T) {
+	order := app.Order{ID: uuid.New(), ProductID: uuid.New(), Price: fixtures.Money("1.1"), PurchasedAt: time.Now()}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+	}{
+		{
+			name: `Given a query with an invalid id, 
+				when it's called, 
+				then the error is logged and a null response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": "invalid"},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+		},
+		{
+			name: `Given a bus that returns an app.ErrNotFound error, 
+				when it's called, 
+				then the error is logged and a null response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": order.ID.String()},
+			},
+			bm: busMock{
+				expectedError: app.ErrNotFound,
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+		},
+		{
+			name: `Given a bus that returns an order, 
+				when it's called, 
+				then the order is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"id": order.ID.String()},
+			},
+			bm: busMock{
+				expectedResult: order,
+			},
+			lm:               &loggerMock{},
+			expectedResponse: api.NewOrder(order),
+		},
+	}
+
+	for _, tc := range testCases {
+		or := api.OrderResolver(tc.lm, tc.bm)
+		response, err := or(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		if tc.expectedResponse == nil {
+			require.Nil(t, response, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestRefundPurchaseResolver(t *testing.T) {
+	randomErr := errors.New("randomErr")
+	params := graphql.ResolveParams{
+		Args: map[string]interface{}{
+			"input": map[string]interface{}{
+				"orderID": uuid.New().String(),
+			},
+		},
+	}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse api.RefundResponse
+		expectedLogCalls int
+	}{
+		{
+			name: `Given a query with and invalid orderID, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"orderID": "invalid",
+					},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "invalid order UUID"},
+		},
+		{
+			name: `Given a bus that returns an internal error, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: randomErr},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "internal error"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrProductNotPurchased error, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrProductNotPurchased},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "product has not been purchased"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrProductArchived error, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrProductArchived},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "the product of the order is archived"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrRefundExceedsOrder error, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrRefundExceedsOrder},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "the units of the order have already been refunded"},
+		},
+		{
+			name: `Given a bus that returns an app.ErrNotFound error, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrNotFound},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "orderID not found"},
+		},
+		{
+			name: `Given a bus that returns an app.ErrVersionConflict error, 
+				when it's called, 
+				then the error is logged and a retryable response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrVersionConflict},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.RefundResponse{Error: "product modified concurrently", Retryable: true},
+		},
+		{
+			name: `Given a bus that returns no error, 
+				when it's called, 
+				then a success response is returned`,
+			params:           params,
+			lm:               &loggerMock{},
+			expectedResponse: api.RefundResponse{Success: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		rr := api.RefundPurchaseResolver(tc.lm, tc.bm)
+		response, err := rr(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestCheckoutResolver(t *testing.T) {
+	var (
+		productIDs = []uuid.UUID{uuid.New(), uuid.New()}
+		params     = graphql.ResolveParams{
+			Args: map[string]interface{}{
+				"input": map[string]interface{}{
+					"items": []interface{}{
+						map[string]interface{}{"productID": productIDs[0].String()},
+						map[string]interface{}{"productID": productIDs[1].String(), "quantity": 2},
+					},
+				},
+			},
+		}
+	)
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse api.CheckoutResponse
+		expectedLogCalls int
+	}{
+		{
+			name: `Given a query without items, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"input": map[string]interface{}{}},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.CheckoutResponse{Error: "items field not found"},
+		},
+		{
+			name: `Given a query with an invalid productID, 
+				when it's called, 
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"items": []interface{}{map[string]interface{}{"productID": "invalid"}},
+					},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.CheckoutResponse{Error: "invalid product UUID"},
+		},
+		{
+			name: `Given a bus that returns an app.CheckoutError, 
+				when it's called, 
+				then the error is logged and the failure of each item is returned`,
+			params: params,
+			bm: busMock{
+				expectedError: app.CheckoutError{Failures: []app.CheckoutItemFailure{
+					{ProductID: productIDs[1], Err: domain.ErrInsufficientStock},
+				}},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.CheckoutResponse{
+				Error: "some items can't be purchased",
+				Failures: []api.CheckoutFailure{
+					{ProductID: productIDs[1].String(), Error: "not enough units of productID in stock"},
+				},
+			},
+		},
+		{
+			name: `Given a bus that returns an app.ErrVersionConflict error, 
+				when it's called, 
+				then the error is logged and a retryable response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrVersionConflict},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.CheckoutResponse{Error: "product modified concurrently", Retryable: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		cr := api.CheckoutResolver(tc.lm, tc.bm)
+		response, err := cr(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+
+	t.Run(`Given a bus that returns no error, 
+			when it's called, 
+			then a success response with an order for each item is returned`, func(t *testing.T) {
+		response, err := api.CheckoutResolver(&loggerMock{}, busMock{})(params)
+		require.NoError(t, err)
+		got := response.(api.CheckoutResponse)
+		require.True(t, got.Success)
+		require.Len(t, got.OrderIDs, 2)
+	})
+}
+
+func TestResolversForbiddenError(t *testing.T) {
+	var (
+		ID      = uuid.New().String()
+		product = graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"productID": ID}}}
+	)
+	resolvers := map[string]struct {
+		resolver func(cqrs.Logger, cqrs.Bus) func(graphql.ResolveParams) (interface{}, error)
+		params   graphql.ResolveParams
+	}{
+		"products":       {resolver: api.ProductsResolver},
+		"product":        {resolver: api.ProductResolver, params: graphql.ResolveParams{Args: map[string]interface{}{"id": ID}}},
+		"orders":         {resolver: api.OrdersResolver},
+		"purchase":       {resolver: api.PurchaseProductResolver, params: product},
+		"createProduct":  {resolver: api.CreateProductResolver, params: graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"name": "product"}}}},
+		"archiveProduct": {resolver: api.ArchiveProductResolver, params: product},
+		"reserveProduct": {resolver: api.ReserveProductResolver, params: product},
+		"apiKeys":        {resolver: api.APIKeysResolver},
+		"createAPIKey":   {resolver: api.CreateAPIKeyResolver, params: graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"name": "erp"}}}},
+		"revokeAPIKey":   {resolver: api.RevokeAPIKeyResolver, params: graphql.ResolveParams{Args: map[string]interface{}{"input": map[string]interface{}{"id": ID}}}},
+	}
+	testCases := []struct {
+		name        string
+		err         error
+		expectedErr api.ForbiddenError
+		expectedExt string
+	}{
+		{
+			name: `Given a bus that denies the command or query to an authenticated caller, 
+				when it's called, 
+				then a FORBIDDEN error is returned`,
+			err:         app.ForbiddenError{Name: "name"},
+			expectedErr: api.ForbiddenError{},
+			expectedExt: api.ForbiddenErrorCode,
+		},
+		{
+			name: `Given a bus that denies the command or query to an anonymous caller, 
+				when it's called, 
+				then an UNAUTHENTICATED error is returned`,
+			err:         app.ForbiddenError{Name: "name", Anonymous: true},
+			expectedErr: api.ForbiddenError{Anonymous: true},
+			expectedExt: api.UnauthenticatedErrorCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		for name, r := range resolvers {
+			response, err := r.resolver(&loggerMock{}, busMock{expectedError: tc.err})(r.params)
+			require.Nil(t, response, tc.name, name)
+			require.Equal(t, tc.expectedErr, err, tc.name, name)
+			require.Equal(t, tc.expectedExt, tc.expectedErr.Extensions()["code"], tc.name, name)
 		}
 	}
 }
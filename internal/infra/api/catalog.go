@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// ProductResponse is a DTO
+type ProductResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// ProductID is the ID of the created or modified product
+	ProductID string `json:"productID,omitempty"`
+	// Retryable tells the client that the operation failed because of a concurrent change, so it can be retried
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+var productResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductResponse",
+	Fields: graphql.Fields{
+		"success": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"error": &graphql.Field{
+			Type: graphql.String,
+		},
+		"retryable": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"productID": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var createProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type:        graphql.ID,
+			Description: "When it's not provided, a new one is generated",
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"price": &graphql.InputObjectFieldConfig{
+			Type:        moneyInputType,
+			Description: "When it's not provided, the product can't be purchased until it's priced",
+		},
+		"stock": &graphql.InputObjectFieldConfig{
+			Type:         graphql.Int,
+			DefaultValue: 1,
+		},
+	},
+})
+
+var updateProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"productID": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"price": &graphql.InputObjectFieldConfig{
+			Type: moneyInputType,
+		},
+		"status": &graphql.InputObjectFieldConfig{
+			Type:        productStatusType,
+			Description: "PUBLISHED puts the product on sale, and DRAFT withdraws it from sale. Only products with stock can be put on sale",
+		},
+		"available": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Boolean,
+			Description: "Deprecated: use status. true is the same as PUBLISHED, and false is the same as DRAFT. It's ignored when the status is provided",
+		},
+	},
+})
+
+var archiveProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ArchiveProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"productID": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	},
+})
+
+// CreateProductResolver is a resolver function. When the input is not valid, a ValidationError is returned
+func CreateProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		input, _ := p.Args["input"].(map[string]interface{})
+
+		cmd := app.CreateProductCmd{ID: uuid.New()}
+		if param, ok := input["id"].(string); ok {
+			ID, err := uuid.Parse(param)
+			if err != nil {
+				log.Printf("invalid product UUID\n")
+				return ProductResponse{Success: false, Error: "invalid product UUID"}, nil
+			}
+			cmd.ID = ID
+		}
+		cmd.ProductName, _ = input["name"].(string)
+		if price, ok := input["price"].(map[string]interface{}); ok {
+			m, err := moneyFromInput(price)
+			if err != nil {
+				log.Printf("%s\n", err.Error())
+				return nil, ValidationError{Fields: []FieldError{{Field: "price", Message: err.Error()}}}
+			}
+			cmd.Price = &m
+		}
+		cmd.Stock, _ = input["stock"].(int)
+
+		return dispatchProductCmd(p.Context, log, bus, cmd, cmd.ID)
+	}
+}
+
+// UpdateProductResolver is a resolver function. When the input is not valid, a ValidationError is returned
+func UpdateProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		pID, err := productIDFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return ProductResponse{Success: false, Error: err.Error()}, nil
+		}
+		input, _ := p.Args["input"].(map[string]interface{})
+
+		cmd := app.UpdateProductCmd{ID: pID}
+		if name, ok := input["name"].(string); ok {
+			cmd.ProductName = &name
+		}
+		if price, ok := input["price"].(map[string]interface{}); ok {
+			m, err := moneyFromInput(price)
+			if err != nil {
+				log.Printf("%s\n", err.Error())
+				return nil, ValidationError{Fields: []FieldError{{Field: "price", Message: err.Error()}}}
+			}
+			cmd.Price = &m
+		}
+		if status, ok := input["status"].(domain.Status); ok {
+			cmd.Status = &status
+		} else if available, ok := input["available"].(bool); ok {
+			status := domain.StatusDraft
+			if available {
+				status = domain.StatusPublished
+			}
+			cmd.Status = &status
+		}
+
+		return dispatchProductCmd(p.Context, log, bus, cmd, pID)
+	}
+}
+
+// ArchiveProductResolver is a resolver function
+func ArchiveProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		pID, err := productIDFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return ProductResponse{Success: false, Error: err.Error()}, nil
+		}
+
+		return dispatchProductCmd(p.Context, log, bus, app.ArchiveProductCmd{ID: pID}, pID)
+	}
+}
+
+// dispatchProductCmd dispatches a command that changes a product, and returns the response for the client.
+// When the product is not valid, a ValidationError with all its invalid fields is returned instead.
+func dispatchProductCmd(ctx context.Context, log cqrs.Logger, bus cqrs.Bus, cmd cqrs.Command, pID uuid.UUID) (interface{}, error) {
+	if _, err := bus.Dispatch(ctx, cmd); err != nil {
+		log.Printf("something went wrong when executing the command %s: %s\n", cmd.Name(), err.Error())
+		if ferr, ok := forbiddenError(err); ok {
+			return nil, ferr
+		}
+		var verr domain.ValidationError
+		if errors.As(err, &verr) {
+			return nil, NewValidationError(verr.Violations)
+		}
+		msg, retryable := catalogErrorMessage(err)
+		return ProductResponse{Success: false, Error: msg, Retryable: retryable}, nil
+	}
+	return ProductResponse{Success: true, ProductID: pID.String()}, nil
+}
+
+// catalogErrorMessage returns the message to be sent to the client for an error found changing the catalog,
+// and whether the change can be retried
+func catalogErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return "productID has no stock to be put on sale", false
+	case errors.Is(err, domain.ErrProductArchived):
+		return "productID is archived", false
+	case errors.Is(err, domain.ErrInvalidStatusTransition):
+		return err.Error(), false
+	case errors.Is(err, app.ErrAlreadyExists):
+		return "id already used by another product", false
+	case errors.Is(err, app.ErrNotFound):
+		return "productID not found", false
+	case errors.Is(err, app.ErrVersionConflict):
+		return "product modified concurrently", true
+	default:
+		return "internal error", false
+	}
+}
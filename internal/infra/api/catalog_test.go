@@ -0,0 +1,368 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+	"github.com/theskyinflames/cqrs-eda/pkg/bus"
+)
+
+func TestCreateProductResolver(t *testing.T) {
+	ID := uuid.New()
+	_, priceErr := domain.ParseMoney("1,1", "EUR")
+	nameErr := domain.ValidationError{Entity: "product", Violations: []domain.FieldViolation{
+		{Field: "name", Reason: "it's empty", Err: domain.ErrInvalidProductName},
+		{Field: "stock", Reason: "it's negative: -1", Err: domain.ErrInvalidStock},
+	}}
+	params := graphql.ResolveParams{
+		Args: map[string]interface{}{
+			"input": map[string]interface{}{
+				"id":    ID.String(),
+				"name":  "product",
+				"price": map[string]interface{}{"amount": "1.1", "currency": "EUR"},
+				"stock": 2,
+			},
+		},
+	}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse interface{}
+		expectedLogCalls int
+		expectedError    error
+	}{
+		{
+			name: `Given an input with an invalid id,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{"id": "invalid", "name": "product"},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "invalid product UUID"},
+		},
+		{
+			name: `Given an input with an invalid price,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{
+						"name":  "product",
+						"price": map[string]interface{}{"amount": "1,1", "currency": "EUR"},
+					},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError:    api.ValidationError{Fields: []api.FieldError{{Field: "price", Message: priceErr.Error()}}},
+		},
+		{
+			name: `Given a bus that returns a domain.ValidationError error,
+				when it's called,
+				then the error is logged and a ValidationError with all the invalid fields is returned`,
+			params:           params,
+			bm:               busMock{expectedError: nameErr},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedError: api.ValidationError{Fields: []api.FieldError{
+				{Field: "name", Message: "it's empty"},
+				{Field: "stock", Message: "it's negative: -1"},
+			}},
+		},
+		{
+			name: `Given a bus that returns an app.ErrAlreadyExists error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrAlreadyExists},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "id already used by another product"},
+		},
+		{
+			name: `Given a bus that returns no error,
+				when it's called,
+				then a success response with the product ID is returned`,
+			params:           params,
+			lm:               &loggerMock{},
+			expectedResponse: api.ProductResponse{Success: true, ProductID: ID.String()},
+		},
+	}
+
+	for _, tc := range testCases {
+		cr := api.CreateProductResolver(tc.lm, tc.bm)
+		response, err := cr(tc.params)
+		require.Equal(t, tc.expectedError, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+
+	t.Run(`Given an input without id,
+		when it's called,
+		then a new product ID is returned`, func(t *testing.T) {
+		cr := api.CreateProductResolver(&loggerMock{}, busMock{})
+		response, err := cr(graphql.ResolveParams{
+			Args: map[string]interface{}{"input": map[string]interface{}{"name": "product", "stock": 1}},
+		})
+		require.NoError(t, err)
+		pr, ok := response.(api.ProductResponse)
+		require.True(t, ok)
+		require.True(t, pr.Success)
+		_, err = uuid.Parse(pr.ProductID)
+		require.NoError(t, err)
+	})
+}
+
+func TestUpdateProductResolver(t *testing.T) {
+	ID := uuid.New()
+	params := graphql.ResolveParams{
+		Args: map[string]interface{}{
+			"input": map[string]interface{}{
+				"productID": ID.String(),
+				"name":      "renamed",
+				"available": false,
+			},
+		},
+	}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse api.ProductResponse
+		expectedLogCalls int
+	}{
+		{
+			name: `Given an input with an invalid productID,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{
+					"input": map[string]interface{}{"productID": "invalid"},
+				},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "invalid product UUID"},
+		},
+		{
+			name: `Given a bus that returns an internal error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: errors.New("randomErr")},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "internal error"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrInsufficientStock error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrInsufficientStock},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "productID has no stock to be put on sale"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrProductArchived error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrProductArchived},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "productID is archived"},
+		},
+		{
+			name: `Given a bus that returns an app.ErrVersionConflict error,
+				when it's called,
+				then the error is logged and a retryable response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrVersionConflict},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "product modified concurrently", Retryable: true},
+		},
+		{
+			name: `Given a bus that returns no error,
+				when it's called,
+				then a success response is returned`,
+			params:           params,
+			lm:               &loggerMock{},
+			expectedResponse: api.ProductResponse{Success: true, ProductID: ID.String()},
+		},
+	}
+
+	for _, tc := range testCases {
+		ur := api.UpdateProductResolver(tc.lm, tc.bm)
+		response, err := ur(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+type recordingBusMock struct {
+	ctx        context.Context
+	dispatched []bus.Dispatchable
+}
+
+func (bm *recordingBusMock) Dispatch(ctx context.Context, d bus.Dispatchable) (interface{}, error) {
+	bm.ctx = ctx
+	bm.dispatched = append(bm.dispatched, d)
+	return nil, nil
+}
+
+func TestUpdateProductResolverStatus(t *testing.T) {
+	ID := uuid.New()
+	testCases := []struct {
+		name           string
+		input          map[string]interface{}
+		expectedStatus *domain.Status
+	}{
+		{
+			name: `Given an input without status nor availability, 
+				when it's called, 
+				then the status is not changed`,
+			input: map[string]interface{}{"productID": ID.String(), "name": "renamed"},
+		},
+		{
+			name: `Given an input with a status, 
+				when it's called, 
+				then the product is updated to it`,
+			input:          map[string]interface{}{"productID": ID.String(), "status": domain.StatusDraft},
+			expectedStatus: statusPtr(domain.StatusDraft),
+		},
+		{
+			name: `Given an input of an old client with the availability, 
+				when it's called, 
+				then the product is updated to the matching status`,
+			input:          map[string]interface{}{"productID": ID.String(), "available": true},
+			expectedStatus: statusPtr(domain.StatusPublished),
+		},
+		{
+			name: `Given an input with both a status and the availability, 
+				when it's called, 
+				then the availability is ignored`,
+			input:          map[string]interface{}{"productID": ID.String(), "status": domain.StatusDraft, "available": true},
+			expectedStatus: statusPtr(domain.StatusDraft),
+		},
+	}
+
+	for _, tc := range testCases {
+		bm := &recordingBusMock{}
+		ur := api.UpdateProductResolver(&loggerMock{}, bm)
+		_, err := ur(graphql.ResolveParams{Args: map[string]interface{}{"input": tc.input}})
+		require.NoError(t, err, tc.name)
+		require.Len(t, bm.dispatched, 1, tc.name)
+		cmd, ok := bm.dispatched[0].(app.UpdateProductCmd)
+		require.True(t, ok, tc.name)
+		require.Equal(t, tc.expectedStatus, cmd.Status, tc.name)
+	}
+}
+
+func statusPtr(s domain.Status) *domain.Status {
+	return &s
+}
+
+func TestArchiveProductResolver(t *testing.T) {
+	ID := uuid.New()
+	params := graphql.ResolveParams{
+		Args: map[string]interface{}{
+			"input": map[string]interface{}{"productID": ID.String()},
+		},
+	}
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse api.ProductResponse
+		expectedLogCalls int
+	}{
+		{
+			name: `Given a bus that returns an app.ErrNotFound error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrNotFound},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ProductResponse{Error: "productID not found"},
+		},
+		{
+			name: `Given a bus that returns no error,
+				when it's called,
+				then a success response is returned`,
+			params:           params,
+			lm:               &loggerMock{},
+			expectedResponse: api.ProductResponse{Success: true, ProductID: ID.String()},
+		},
+	}
+
+	for _, tc := range testCases {
+		ar := api.ArchiveProductResolver(tc.lm, tc.bm)
+		response, err := ar(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestCatalogValidationErrors(t *testing.T) {
+	t.Run(`Given a bus that returns a domain.ValidationError error,
+		when a product is created through the GraphQL endpoint,
+		then the response has an error whose extensions have the code and the invalid fields`, func(t *testing.T) {
+		bm := busMock{expectedError: domain.ValidationError{Entity: "product", Violations: []domain.FieldViolation{
+			{Field: "name", Reason: "it's empty", Err: domain.ErrInvalidProductName},
+			{Field: "stock", Reason: "it's negative: -1", Err: domain.ErrInvalidStock},
+		}}}
+		srv := httptest.NewServer(api.GraphqlHandler(&loggerMock{}, bm, app.NewEventsHub(), api.DefaultQueryLimits(), api.OperationRateLimiter{}))
+		defer srv.Close()
+
+		query := `{"query":"mutation {createProduct(input: {name: \"\", stock: -1}) {success productID}}"}`
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(query))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var result struct {
+			Data   map[string]interface{}
+			Errors []struct {
+				Message    string
+				Extensions struct {
+					Code   string
+					Fields []api.FieldError
+				}
+			}
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		require.Nil(t, result.Data["createProduct"])
+		require.Len(t, result.Errors, 1)
+		require.Equal(t, "invalid input: name: it's empty; stock: it's negative: -1", result.Errors[0].Message)
+		require.Equal(t, api.ValidationErrorCode, result.Errors[0].Extensions.Code)
+		require.Equal(t, []api.FieldError{
+			{Field: "name", Message: "it's empty"},
+			{Field: "stock", Message: "it's negative: -1"},
+		}, result.Errors[0].Extensions.Fields)
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"theskyinflames/graphql-challenge/internal/app"
+)
+
+// clientKey identifies the client of the request: by its principal, or by its IP when it's anonymous
+func clientKey(r *http.Request) string {
+	if principal, ok := app.PrincipalFromContext(r.Context()); ok {
+		return "principal:" + principal.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+type clientCtxKey struct{}
+
+// contextWithClient returns a copy of the context that carries the key of the client of the request
+func contextWithClient(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, clientCtxKey{}, clientKey(r))
+}
+
+// clientFromContext returns the key of the client carried by the context. It's empty when there is none
+func clientFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	client, _ := ctx.Value(clientCtxKey{}).(string)
+	return client
+}
@@ -0,0 +1,421 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// QueryTooComplexErrorCode is the code of the extensions of a QueryTooComplexError
+const QueryTooComplexErrorCode = "QUERY_TOO_COMPLEX"
+
+// The limits of a query
+const (
+	DepthLimit   = "depth"
+	FieldsLimit  = "fields"
+	AliasesLimit = "aliases"
+	CostLimit    = "cost"
+)
+
+// QueryLimits are the limits of the queries run by the GraphQL endpoint. They're checked before running them.
+//
+// The cost of a field is its weight plus the cost of its selection. A field has the weight set in Weights for
+// its "Type.field" name or, when it's not set, 1 if it returns an object and 0 if it returns a scalar.
+// The cost of the selection of a list is multiplied by its size: the page size of a paginated field,
+// given by its first or last argument, applies to the lists of its page, and the other lists count as DefaultListSize.
+type QueryLimits struct {
+	MaxDepth        int
+	MaxFields       int
+	MaxAliases      int
+	MaxCost         int
+	DefaultListSize int
+	Weights         map[string]int
+}
+
+// DefaultQueryLimits returns the limits used when they're not configured
+func DefaultQueryLimits() QueryLimits {
+	return QueryLimits{
+		MaxDepth:        10,
+		MaxFields:       200,
+		MaxAliases:      20,
+		MaxCost:         1000,
+		DefaultListSize: app.MaxPageSize,
+		Weights: map[string]int{
+			"Query.searchProducts": 10,
+			"Mutation.checkout":    10,
+		},
+	}
+}
+
+// QueryComplexity is the outcome of the analysis of a query
+type QueryComplexity struct {
+	Depth   int `json:"depth"`
+	Fields  int `json:"fields"`
+	Aliases int `json:"aliases"`
+	Cost    int `json:"cost"`
+}
+
+// ErrCyclicFragment is self-described
+var ErrCyclicFragment = errors.New("cyclic fragment")
+
+// QueryTooComplexError is returned when a query exceeds one of its limits
+type QueryTooComplexError struct {
+	Limit string
+	Max   int
+}
+
+// Error implements the error interface
+func (e QueryTooComplexError) Error() string {
+	return fmt.Sprintf("the query exceeds the max %s of %d", e.Limit, e.Max)
+}
+
+// Extensions implements the gqlerrors.ExtendedError interface
+func (e QueryTooComplexError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": QueryTooComplexErrorCode, "limit": e.Limit, "max": e.Max}
+}
+
+// AnalyzeOperation measures the operation of the document, and checks it against the limits.
+// The documents with cyclic fragments are rejected with ErrCyclicFragment.
+// The fragments are expanded, so their fields are counted each time they're spread.
+// When the depth, the fields or the aliases exceed their limits, the analysis is stopped and
+// an empty QueryComplexity is returned. When only the cost exceeds its limit, the whole QueryComplexity is returned.
+func AnalyzeOperation(
+	schema graphql.Schema,
+	doc *ast.Document,
+	op *ast.OperationDefinition,
+	variables map[string]interface{},
+	limits QueryLimits,
+) (QueryComplexity, error) {
+	if err := CheckFragmentCycles(doc); err != nil {
+		return QueryComplexity{}, err
+	}
+	a := analyzer{
+		schema:    schema,
+		fragments: fragmentsOf(doc),
+		variables: variables,
+		limits:    limits,
+	}
+
+	var root graphql.Type = schema.QueryType()
+	switch op.Operation {
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+
+	cost, err := a.selectionSet(op.SelectionSet, root, limits.DefaultListSize, 1)
+	if err != nil {
+		return QueryComplexity{}, err
+	}
+	a.complexity.Cost = cost
+	if cost > limits.MaxCost {
+		return a.complexity, QueryTooComplexError{Limit: CostLimit, Max: limits.MaxCost}
+	}
+	return a.complexity, nil
+}
+
+// CheckFragmentCycles returns ErrCyclicFragment when a fragment of the document spreads itself.
+// The validation of graphql-go overflows the stack with the cyclic fragments, so the documents
+// must be checked before being validated.
+func CheckFragmentCycles(doc *ast.Document) error {
+	a := analyzer{fragments: fragmentsOf(doc)}
+	for name := range a.fragments {
+		if err := a.checkCycles(name, make(map[string]bool)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fragmentsOf returns the fragments of the document by name
+func fragmentsOf(doc *ast.Document) map[string]*ast.FragmentDefinition {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, d := range doc.Definitions {
+		if f, ok := d.(*ast.FragmentDefinition); ok && f.Name != nil {
+			fragments[f.Name.Value] = f
+		}
+	}
+	return fragments
+}
+
+// operation returns the operation of the document to be run: the one with the given name,
+// or the only one when no name is given. It returns nil when there is not such an operation
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var operations []*ast.OperationDefinition
+	for _, d := range doc.Definitions {
+		if op, ok := d.(*ast.OperationDefinition); ok {
+			operations = append(operations, op)
+		}
+	}
+	for _, op := range operations {
+		if name == "" && len(operations) == 1 {
+			return op
+		}
+		if name != "" && op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return nil
+}
+
+type analyzer struct {
+	schema     graphql.Schema
+	fragments  map[string]*ast.FragmentDefinition
+	variables  map[string]interface{}
+	limits     QueryLimits
+	complexity QueryComplexity
+}
+
+// selectionSet returns the cost of the selection of a field of type t, which is at the given depth.
+// listSize is the size of the lists of the selection
+func (a *analyzer) selectionSet(set *ast.SelectionSet, t graphql.Type, listSize, depth int) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+	cost := 0
+	for _, s := range set.Selections {
+		var (
+			c   int
+			err error
+		)
+		switch s := s.(type) {
+		case *ast.Field:
+			c, err = a.field(s, t, listSize, depth)
+		case *ast.InlineFragment:
+			c, err = a.selectionSet(s.SelectionSet, a.typeCondition(s.TypeCondition, t), listSize, depth)
+		case *ast.FragmentSpread:
+			c, err = a.fragmentSpread(s, t, listSize, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		cost = saturatingAdd(cost, c)
+	}
+	return cost, nil
+}
+
+func (a *analyzer) fragmentSpread(s *ast.FragmentSpread, t graphql.Type, listSize, depth int) (int, error) {
+	if s.Name == nil {
+		return 0, nil
+	}
+	name := s.Name.Value
+	f, ok := a.fragments[name]
+	// The unknown fragments are rejected by the validation of the query
+	if !ok {
+		return 0, nil
+	}
+	return a.selectionSet(f.SelectionSet, a.typeCondition(f.TypeCondition, t), listSize, depth)
+}
+
+// checkCycles returns an error if the fragment spreads itself, directly or through other fragments
+func (a *analyzer) checkCycles(name string, spreading map[string]bool) error {
+	if spreading[name] {
+		return fmt.Errorf("%w: cannot spread fragment %q within itself", ErrCyclicFragment, name)
+	}
+	f, ok := a.fragments[name]
+	if !ok {
+		return nil
+	}
+	spreading[name] = true
+	defer delete(spreading, name)
+	for _, spread := range fragmentSpreads(f.SelectionSet) {
+		if err := a.checkCycles(spread, spreading); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fragmentSpreads returns the names of the fragments spread by the selection, including the nested ones
+func fragmentSpreads(set *ast.SelectionSet) []string {
+	if set == nil {
+		return nil
+	}
+	var names []string
+	for _, s := range set.Selections {
+		switch s := s.(type) {
+		case *ast.Field:
+			names = append(names, fragmentSpreads(s.SelectionSet)...)
+		case *ast.InlineFragment:
+			names = append(names, fragmentSpreads(s.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if s.Name != nil {
+				names = append(names, s.Name.Value)
+			}
+		}
+	}
+	return names
+}
+
+func (a *analyzer) typeCondition(condition *ast.Named, t graphql.Type) graphql.Type {
+	if condition == nil || condition.Name == nil {
+		return t
+	}
+	return a.schema.Type(condition.Name.Value)
+}
+
+func (a *analyzer) field(f *ast.Field, parent graphql.Type, listSize, depth int) (int, error) {
+	if depth > a.limits.MaxDepth {
+		return 0, QueryTooComplexError{Limit: DepthLimit, Max: a.limits.MaxDepth}
+	}
+	if depth > a.complexity.Depth {
+		a.complexity.Depth = depth
+	}
+	a.complexity.Fields++
+	if a.complexity.Fields > a.limits.MaxFields {
+		return 0, QueryTooComplexError{Limit: FieldsLimit, Max: a.limits.MaxFields}
+	}
+	if f.Alias != nil && f.Alias.Value != f.Name.Value {
+		a.complexity.Aliases++
+		if a.complexity.Aliases > a.limits.MaxAliases {
+			return 0, QueryTooComplexError{Limit: AliasesLimit, Max: a.limits.MaxAliases}
+		}
+	}
+
+	var (
+		def     *graphql.FieldDefinition
+		objName string
+	)
+	if fields := fieldsOf(parent); fields != nil {
+		def = fields[f.Name.Value]
+		objName = parent.Name()
+	}
+	if def == nil {
+		def = introspectionField(f.Name.Value)
+	}
+
+	// The unknown fields are measured as objects when they have a selection
+	var t graphql.Type
+	isList, isLeaf := false, f.SelectionSet == nil
+	if def != nil {
+		t, isList, isLeaf = unwrap(def.Type)
+	}
+
+	weight, ok := a.limits.Weights[objName+"."+f.Name.Value]
+	if !ok && !isLeaf {
+		weight = 1
+	}
+
+	multiplier := 1
+	if isList && !isLeaf {
+		multiplier = listSize
+	}
+	childrenListSize := a.limits.DefaultListSize
+	if def != nil && isPaginated(def) {
+		childrenListSize = a.pageSize(f)
+	}
+
+	cost, err := a.selectionSet(f.SelectionSet, t, childrenListSize, depth+1)
+	if err != nil {
+		return 0, err
+	}
+	return saturatingAdd(weight, saturatingMul(multiplier, cost)), nil
+}
+
+// introspectionField returns the definition of an introspection field, since they're not in the fields of the types.
+// It returns nil when it's not an introspection field
+func introspectionField(name string) *graphql.FieldDefinition {
+	switch name {
+	case graphql.SchemaMetaFieldDef.Name:
+		return graphql.SchemaMetaFieldDef
+	case graphql.TypeMetaFieldDef.Name:
+		return graphql.TypeMetaFieldDef
+	case graphql.TypeNameMetaFieldDef.Name:
+		return graphql.TypeNameMetaFieldDef
+	}
+	return nil
+}
+
+// pageSize returns the page size asked by the first or last argument of a paginated field
+func (a *analyzer) pageSize(f *ast.Field) int {
+	for _, arg := range f.Arguments {
+		if arg.Name == nil || (arg.Name.Value != "first" && arg.Name.Value != "last") {
+			continue
+		}
+		size := -1
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			size, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			// The variables decoded from JSON are float64
+			if n, ok := a.variables[v.Name.Value].(float64); ok {
+				size = int(n)
+			}
+		}
+		if size >= 0 {
+			return size
+		}
+	}
+	return app.DefaultPageSize
+}
+
+// isPaginated returns true if the field has a first argument to ask for a page size
+func isPaginated(def *graphql.FieldDefinition) bool {
+	for _, arg := range def.Args {
+		if arg.Name() == "first" {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldsOf returns the fields of an object or interface type
+func fieldsOf(t graphql.Type) graphql.FieldDefinitionMap {
+	switch t := t.(type) {
+	case *graphql.Object:
+		if t != nil {
+			return t.Fields()
+		}
+	case *graphql.Interface:
+		if t != nil {
+			return t.Fields()
+		}
+	}
+	return nil
+}
+
+// unwrap returns the named type of a field type, whether it's a list and whether it's a scalar or an enum
+func unwrap(t graphql.Type) (graphql.Type, bool, bool) {
+	isList := false
+	for {
+		switch w := t.(type) {
+		case *graphql.NonNull:
+			t = w.OfType
+			continue
+		case *graphql.List:
+			isList = true
+			t = w.OfType
+			continue
+		}
+		break
+	}
+	switch t.(type) {
+	case *graphql.Scalar, *graphql.Enum:
+		return t, isList, true
+	}
+	return t, isList, false
+}
+
+// maxCost is the cost that is not exceeded, so a pathological query doesn't overflow it
+const maxCost = math.MaxInt32
+
+func saturatingAdd(a, b int) int {
+	if a+b > maxCost {
+		return maxCost
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > maxCost/a {
+		return maxCost
+	}
+	return a * b
+}
@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+type complexityResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+	Extensions struct {
+		Complexity *api.QueryComplexity `json:"complexity"`
+	} `json:"extensions"`
+}
+
+func TestGraphqlHandlerQueryLimits(t *testing.T) {
+	limits := func(f func(*api.QueryLimits)) api.QueryLimits {
+		l := api.DefaultQueryLimits()
+		if f != nil {
+			f(&l)
+		}
+		return l
+	}
+	const connection = `productsConnection(first: 100) {edges {node {id price {amount}}} pageInfo {hasNextPage}}`
+
+	testCases := []struct {
+		name               string
+		body               string
+		limits             api.QueryLimits
+		expectedComplexity *api.QueryComplexity
+		expectedLimit      string
+	}{
+		{
+			name: `Given a query of a list that is not paginated,
+				when it's run,
+				then its cost counts the list with the default size`,
+			body:               `{"query":"{products {id name price {amount currency}}}"}`,
+			limits:             limits(nil),
+			expectedComplexity: &api.QueryComplexity{Depth: 3, Fields: 6, Cost: 101},
+		},
+		{
+			name: `Given a query of a paginated field,
+				when it's analyzed,
+				then the lists of its page count with the page size of its variable`,
+			body:               `{"query":"query q($n: Int) {searchProducts(text: \"shoes\", first: $n) {edges {node {id}} pageInfo {hasNextPage}}}","variables":{"n":50}}`,
+			limits:             limits(func(l *api.QueryLimits) { l.MaxCost = 60 }),
+			expectedComplexity: &api.QueryComplexity{Depth: 4, Fields: 6, Cost: 62},
+			expectedLimit:      api.CostLimit,
+		},
+		{
+			name: `Given an introspection query,
+				when it's analyzed,
+				then its lists count with the default size`,
+			body:               `{"query":"{__schema {types {name fields {name}}}}"}`,
+			limits:             limits(func(l *api.QueryLimits) { l.MaxCost = 100 }),
+			expectedComplexity: &api.QueryComplexity{Depth: 4, Fields: 5, Cost: 102},
+			expectedLimit:      api.CostLimit,
+		},
+		{
+			name: `Given a query over the max depth,
+				when it's received,
+				then it's rejected`,
+			body:          `{"query":"{` + connection + `}"}`,
+			limits:        limits(func(l *api.QueryLimits) { l.MaxDepth = 4 }),
+			expectedLimit: api.DepthLimit,
+		},
+		{
+			name: `Given a query over the max fields, with fields spread by fragments,
+				when it's received,
+				then it's rejected`,
+			body:          `{"query":"{products {...p}} fragment p on Product {id name}"}`,
+			limits:        limits(func(l *api.QueryLimits) { l.MaxFields = 2 }),
+			expectedLimit: api.FieldsLimit,
+		},
+		{
+			name: `Given a query over the max aliases,
+				when it's received,
+				then it's rejected`,
+			body:          `{"query":"{a: products {id} b: products {id}}"}`,
+			limits:        limits(func(l *api.QueryLimits) { l.MaxAliases = 1 }),
+			expectedLimit: api.AliasesLimit,
+		},
+		{
+			name: `Given a query that asks for many pages with aliases,
+				when it's received,
+				then it's rejected for its cost, which is reported`,
+			body:               `{"query":"{a: ` + connection + ` b: ` + connection + ` c: ` + connection + ` d: ` + connection + ` e: ` + connection + `}"}`,
+			limits:             limits(nil),
+			expectedComplexity: &api.QueryComplexity{Depth: 5, Fields: 40, Aliases: 5, Cost: 1015},
+			expectedLimit:      api.CostLimit,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bm := busMock{expectedResult: []app.Product{}}
+			srv := httptest.NewServer(api.GraphqlHandler(&loggerMock{}, bm, app.NewEventsHub(), tc.limits, api.OperationRateLimiter{}))
+			defer srv.Close()
+
+			resp, err := http.Post(srv.URL, "application/json", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var result complexityResult
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			require.Equal(t, tc.expectedComplexity, result.Extensions.Complexity)
+			if tc.expectedLimit == "" {
+				require.Empty(t, result.Errors)
+				require.NotNil(t, result.Data["products"])
+				return
+			}
+			require.Nil(t, result.Data)
+			require.Len(t, result.Errors, 1)
+			require.Equal(t, api.QueryTooComplexErrorCode, result.Errors[0].Extensions["code"])
+			require.Equal(t, tc.expectedLimit, result.Errors[0].Extensions["limit"])
+		})
+	}
+
+	t.Run(`Given a query with a cyclic fragment,
+		when it's received,
+		then it's rejected`, func(t *testing.T) {
+		srv := httptest.NewServer(api.GraphqlHandler(&loggerMock{}, busMock{}, app.NewEventsHub(), limits(nil), api.OperationRateLimiter{}))
+		defer srv.Close()
+
+		for _, query := range []string{
+			`{"query":"{products {...p}} fragment p on Product {id ...q} fragment q on Product {name ...p}"}`,
+			// The validation checks the fragments that are not spread by the operation too
+			`{"query":"{products {id}} fragment p on Product {id ...p}"}`,
+			// The documents without the operation to be run are validated anyway
+			`{"query":"{products {...p}} fragment p on Product {id ...p}","operation":"unknown"}`,
+		} {
+			resp, err := http.Post(srv.URL, "application/json", strings.NewReader(query))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var result complexityResult
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			require.Nil(t, result.Data)
+			require.Len(t, result.Errors, 1)
+			require.Contains(t, result.Errors[0].Message, api.ErrCyclicFragment.Error())
+		}
+	})
+}
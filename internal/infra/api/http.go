@@ -2,10 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
-	"github.com/graphql-go/graphql"
 	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
 )
 
@@ -14,7 +15,7 @@ import (
 curl --request POST \
   --url http://localhost:8080/graphql \
   --header 'Content-Type: application/json' \
-  --data '{"query":"{products {id name available price}}"}'
+  --data '{"query":"{products {id name available price {amount currency}}}"}'
 
 
 -- purchase a product
@@ -24,14 +25,25 @@ curl --request POST \
        --data '{"query":"mutation {purchase_product(input: {productID: \"ec92361c-3e36-4371-b040-28f608cbe8c6\"}) {success error }}"}'
 */
 
+// maxRequestBodySize is the maximum size of the body of a request, so a client can't exhaust the memory of the service
+const maxRequestBodySize = 1 << 20
+
 type postData struct {
 	Query     string                 `json:"query"`
 	Operation string                 `json:"operation"`
 	Variables map[string]interface{} `json:"variables"`
 }
 
-// GraphqlHandler is the HTTP handler for the GraphQL endpoint
-func GraphqlHandler(log cqrs.Logger, bus cqrs.Bus) http.HandlerFunc {
+// GraphqlHandler is the HTTP handler for the GraphQL endpoint. The operations of a client over its rate limit are rejected
+// with a 429 status. The queries that exceed the limits are rejected before running them, and the complexity
+// of the others is returned in the extensions of the response
+func GraphqlHandler(
+	log cqrs.Logger,
+	bus cqrs.Bus,
+	subscriber EventsSubscriber,
+	limits QueryLimits,
+	rateLimiter OperationRateLimiter,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		/*
 			// If it's needed, HTTP headers can be passed to the resolver function in the context
@@ -40,23 +52,39 @@ func GraphqlHandler(log cqrs.Logger, bus cqrs.Bus) http.HandlerFunc {
 		*/
 
 		var p postData
-		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&p); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
 			w.WriteHeader(400)
 			return
 		}
-		schema, err := schema(log, bus)
+
+		// The clients are rate limited after they're authenticated, so each principal has its own budget
+		ctx := contextWithClient(r.Context(), r)
+		if decision := rateLimiter.take(ctx, p.Query, p.Operation); decision != nil {
+			if !decision.Allowed {
+				rateLimited(w, decision.RetryAfter)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		}
+
+		schema, err := schema(log, bus, subscriber)
 		if err != nil {
 			log.Printf(fmt.Sprintf("building GraphQL schema: %s\n", err.Error()))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		result := graphql.Do(graphql.Params{
-			Context:        r.Context(),
-			Schema:         schema,
-			RequestString:  p.Query,
-			VariableValues: p.Variables,
-			OperationName:  p.Operation,
-		})
+
+		req := operationRequest{Query: p.Query, OperationName: p.Operation, Variables: p.Variables}
+		results, rejected := runOperation(ctx, schema, req, limits)
+		result := rejected
+		if result == nil {
+			result = <-results
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(result); err != nil {
@@ -0,0 +1,28 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphqlHandlerBodySize(t *testing.T) {
+	t.Run(`Given a request whose body is larger than 1MiB,
+		when it's received,
+		then it's rejected with a 413 without running it`, func(t *testing.T) {
+		bm := &recordingBusMock{}
+		h := api.GraphqlHandler(&loggerMock{}, bm, app.NewEventsHub(), api.DefaultQueryLimits(), api.OperationRateLimiter{})
+
+		body := `{"query":"{products {id}}","variables":{"padding":"` + strings.Repeat("a", 1<<20) + `"}}`
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		require.Empty(t, bm.dispatched)
+	})
+}
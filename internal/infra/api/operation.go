@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// operationRequest is a GraphQL operation sent by a client, whatever the transport it's sent through
+type operationRequest struct {
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+}
+
+// runOperation checks the operation and runs it when it doesn't exceed the limits, so the operations are checked
+// the same way whatever the transport they're sent through.
+// When the operation is rejected before running it, the result with its errors is returned and the channel is nil.
+// Otherwise, the results are sent to the channel, which is closed when the operation finishes: the queries and the mutations
+// have a single result, and the subscriptions one per event until the context is done.
+// The complexity of the operation is returned in the extensions of its results.
+func runOperation(
+	ctx context.Context,
+	schema graphql.Schema,
+	req operationRequest,
+	limits QueryLimits,
+) (<-chan *graphql.Result, *graphql.Result) {
+	doc, op, complexity, errs := analyzeOperation(schema, req, limits)
+	if len(errs) > 0 {
+		result := &graphql.Result{Errors: errs}
+		if complexity != (QueryComplexity{}) {
+			result.Extensions = map[string]interface{}{"complexity": complexity}
+		}
+		return nil, result
+	}
+
+	params := graphql.Params{
+		Context:        ctx,
+		Schema:         schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+	}
+	withComplexity := func(result *graphql.Result) *graphql.Result {
+		if result.Extensions == nil {
+			result.Extensions = make(map[string]interface{})
+		}
+		result.Extensions["complexity"] = complexity
+		return result
+	}
+
+	if op.Operation != ast.OperationTypeSubscription {
+		results := make(chan *graphql.Result, 1)
+		results <- withComplexity(graphql.Execute(graphql.ExecuteParams{
+			Schema:        schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       ctx,
+		}))
+		close(results)
+		return results, nil
+	}
+
+	results := make(chan *graphql.Result)
+	go func() {
+		defer close(results)
+		// The results are read until the channel is closed, so the goroutine that sends them doesn't leak
+		for result := range graphql.Subscribe(params) {
+			select {
+			case results <- withComplexity(result):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results, nil
+}
+
+// analyzeOperation parses and validates the document, and checks its operation against the limits.
+// The cyclic fragments are rejected before the validation, since it overflows the stack with them,
+// and the limits are checked before it too, so a pathological operation is rejected as soon as possible.
+// When only the cost exceeds its limit, the complexity is returned with the error.
+func analyzeOperation(
+	schema graphql.Schema,
+	req operationRequest,
+	limits QueryLimits,
+) (*ast.Document, *ast.OperationDefinition, QueryComplexity, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, nil, QueryComplexity{}, gqlerrors.FormatErrors(err)
+	}
+	if err := CheckFragmentCycles(doc); err != nil {
+		return nil, nil, QueryComplexity{}, gqlerrors.FormatErrors(err)
+	}
+
+	op := operation(doc, req.OperationName)
+	if op == nil {
+		// The errors of the document are reported before the missing operation, like graphql-go does
+		if result := graphql.ValidateDocument(&schema, doc, nil); !result.IsValid {
+			return nil, nil, QueryComplexity{}, result.Errors
+		}
+		return nil, nil, QueryComplexity{}, gqlerrors.FormatErrors(operationError(doc, req.OperationName))
+	}
+
+	complexity, err := AnalyzeOperation(schema, doc, op, req.Variables, limits)
+	if err != nil {
+		return nil, nil, complexity, gqlerrors.FormatErrors(gqlerrors.NewError(err.Error(), nil, "", nil, nil, err))
+	}
+
+	if result := graphql.ValidateDocument(&schema, doc, nil); !result.IsValid {
+		return nil, nil, complexity, result.Errors
+	}
+	return doc, op, complexity, nil
+}
+
+// operationError is the error of a document without the operation to be run, with the same message graphql-go gives
+func operationError(doc *ast.Document, name string) error {
+	var operations int
+	for _, d := range doc.Definitions {
+		if _, ok := d.(*ast.OperationDefinition); ok {
+			operations++
+		}
+	}
+	switch {
+	case name != "":
+		return fmt.Errorf(`Unknown operation named "%v".`, name)
+	case operations == 0:
+		return errors.New("Must provide an operation.")
+	default:
+		return errors.New("Must provide operation name if query contains multiple operations.")
+	}
+}
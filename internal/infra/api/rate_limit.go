@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// RateLimitedErrorCode is the code of the extensions of the error returned when a client exceeds its rate limit
+const RateLimitedErrorCode = "RATE_LIMITED"
+
+// RateLimits are the budgets of each client for the GraphQL operations
+type RateLimits struct {
+	Queries   app.RateLimit
+	Mutations app.RateLimit
+}
+
+// OperationRateLimiter limits the GraphQL operations of each client with a token bucket, whatever the transport
+// they're sent through. The clients are identified by their principal, or by their IP when they're anonymous.
+// The queries and the mutations have their own buckets, and the subscriptions take a token of the queries one.
+// The zero value doesn't limit any operation.
+type OperationRateLimiter struct {
+	log     cqrs.Logger
+	limiter app.RateLimiter
+	limits  RateLimits
+}
+
+// NewOperationRateLimiter is a constructor
+func NewOperationRateLimiter(log cqrs.Logger, limiter app.RateLimiter, limits RateLimits) OperationRateLimiter {
+	return OperationRateLimiter{log: log, limiter: limiter, limits: limits}
+}
+
+// take takes a token of the client of the context for the operation to be run.
+// It returns nil when the operation is not limited. When the limiter fails, the operation is not limited,
+// since it's better than rejecting every operation.
+func (l OperationRateLimiter) take(ctx context.Context, query, operationName string) *app.RateLimitDecision {
+	if l.limiter == nil {
+		return nil
+	}
+
+	key, limit := "query:", l.limits.Queries
+	if isMutation(query, operationName) {
+		key, limit = "mutation:", l.limits.Mutations
+	}
+	key += clientFromContext(ctx)
+
+	decision, err := l.limiter.Take(ctx, key, limit, time.Now())
+	if err != nil {
+		l.log.Printf("something went wrong when rate limiting %s: %s\n", key, err.Error())
+		return nil
+	}
+	return &decision
+}
+
+// isMutation returns true if the operation to be run is a mutation.
+// The operations that can't be parsed are counted as queries, since they'll be rejected anyway
+func isMutation(query, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	op := operation(doc, operationName)
+	return op != nil && op.Operation == ast.OperationTypeMutation
+}
+
+// rateLimitedError is the error of an operation of a client that has exceeded its rate limit
+func rateLimitedError(retryAfter time.Duration) gqlerrors.FormattedError {
+	return gqlerrors.FormattedError{
+		Message:    "rate limit exceeded",
+		Extensions: map[string]interface{}{"code": RateLimitedErrorCode, "retryAfter": retryAfterSeconds(retryAfter)},
+	}
+}
+
+// retryAfterSeconds rounds up the time to wait for a token, so a client never retries too early
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Max(1, math.Ceil(retryAfter.Seconds())))
+}
+
+// rateLimited rejects a request of a client that has exceeded its rate limit
+func rateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []gqlerrors.FormattedError{rateLimitedError(retryAfter)},
+	})
+}
@@ -0,0 +1,148 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Take(context.Context, string, app.RateLimit, time.Time) (app.RateLimitDecision, error) {
+	return app.RateLimitDecision{}, errors.New("")
+}
+
+type rateLimitedErrors struct {
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func TestGraphqlHandlerRateLimit(t *testing.T) {
+	const (
+		query    = `{"query":"{products {id}}"}`
+		mutation = `{"query":"mutation {purchase_product(input: {productID: \"id\"}) {success}}"}`
+		named    = `{"query":"query q {products {id}} mutation m {purchase_product(input: {productID: \"id\"}) {success}}","operation":"m"}`
+	)
+	limits := api.RateLimits{
+		Queries:   app.RateLimit{Requests: 2, Per: time.Minute},
+		Mutations: app.RateLimit{Requests: 1, Per: time.Minute},
+	}
+
+	handler := func(lm *loggerMock, limiter app.RateLimiter) http.Handler {
+		rl := api.NewOperationRateLimiter(lm, limiter, limits)
+		return api.GraphqlHandler(&loggerMock{}, busMock{expectedResult: []app.Product{}}, app.NewEventsHub(), api.DefaultQueryLimits(), rl)
+	}
+	do := func(h http.Handler, body string, principal *app.Principal, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		if principal != nil {
+			req = req.WithContext(app.ContextWithPrincipal(req.Context(), *principal))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run(`Given a client that has spent its mutations budget,
+		when it sends another mutation,
+		then it's rejected with a 429 and its queries are still served`, func(t *testing.T) {
+		h := handler(&loggerMock{}, app.NewMemoryRateLimiter())
+
+		rec := do(h, mutation, nil, "10.0.0.1:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+		rec = do(h, named, nil, "10.0.0.1:4321")
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		require.Equal(t, "60", rec.Header().Get("Retry-After"))
+		var body rateLimitedErrors
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.Len(t, body.Errors, 1)
+		require.Equal(t, api.RateLimitedErrorCode, body.Errors[0].Extensions["code"])
+		require.Equal(t, float64(60), body.Errors[0].Extensions["retryAfter"])
+
+		rec = do(h, query, nil, "10.0.0.1:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"data":{"products":[]},"extensions":{"complexity":{"depth":2,"fields":2,"aliases":0,"cost":1}}}`, rec.Body.String())
+	})
+
+	t.Run(`Given two clients behind the same IP with their own principals,
+		when one of them spends its budget,
+		then the other one is still served`, func(t *testing.T) {
+		h := handler(&loggerMock{}, app.NewMemoryRateLimiter())
+		alice, bob := &app.Principal{ID: "alice"}, &app.Principal{ID: "apikey:bob"}
+
+		require.Equal(t, http.StatusOK, do(h, mutation, alice, "10.0.0.1:1234").Code)
+		require.Equal(t, http.StatusTooManyRequests, do(h, mutation, alice, "10.0.0.2:1234").Code)
+		require.Equal(t, http.StatusOK, do(h, mutation, bob, "10.0.0.1:1234").Code)
+		require.Equal(t, http.StatusOK, do(h, mutation, nil, "10.0.0.1:1234").Code)
+	})
+
+	t.Run(`Given a limiter that fails,
+		when a request is received,
+		then it's served and the error is logged`, func(t *testing.T) {
+		lm := &loggerMock{}
+		require.Equal(t, http.StatusOK, do(handler(lm, failingLimiter{}), mutation, nil, "10.0.0.1:1234").Code)
+		require.Equal(t, 1, lm.calls)
+	})
+}
+
+func TestSubscriptionsHandlerRateLimit(t *testing.T) {
+	t.Run(`Given a client that has spent its mutations budget over HTTP,
+		when it sends another mutation over WebSocket,
+		then it's rejected with a rate limited error and its queries are still run`, func(t *testing.T) {
+		var (
+			lm      = &loggerMock{}
+			limiter = app.NewMemoryRateLimiter()
+			rl      = api.NewOperationRateLimiter(lm, limiter, api.RateLimits{
+				Queries:   app.RateLimit{Requests: 1, Per: time.Minute},
+				Mutations: app.RateLimit{Requests: 1, Per: time.Minute},
+			})
+			bm = busMock{expectedResult: []app.Product{}}
+		)
+
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"mutation {purchase_product(input: {productID: \"id\"}) {success}}"}`))
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		api.GraphqlHandler(lm, bm, app.NewEventsHub(), api.DefaultQueryLimits(), rl).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		conn := dial(t, api.SubscriptionsHandler(lm, bm, app.NewEventsHub(), api.DefaultQueryLimits(), rl))
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"mutation {purchase_product(input: {productID: \"id\"}) {success}}"}}`)
+		msg := receive(t, conn)
+		require.Equal(t, "error", msg.Type)
+		require.Equal(t, "1", msg.ID)
+		var errs []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		}
+		require.NoError(t, json.Unmarshal(msg.Payload, &errs))
+		require.Len(t, errs, 1)
+		require.Equal(t, api.RateLimitedErrorCode, errs[0].Extensions["code"])
+		require.Equal(t, float64(60), errs[0].Extensions["retryAfter"])
+
+		send(t, conn, `{"id":"2","type":"subscribe","payload":{"query":"{products {id}}"}}`)
+		msg = receive(t, conn)
+		require.Equal(t, "next", msg.Type)
+		require.Equal(t, "2", msg.ID)
+		require.Equal(t, wsMessage{ID: "2", Type: "complete"}, receive(t, conn))
+
+		send(t, conn, `{"id":"3","type":"subscribe","payload":{"query":"{products {id}}"}}`)
+		msg = receive(t, conn)
+		require.Equal(t, "error", msg.Type)
+		require.Equal(t, "3", msg.ID)
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/graphql-go/graphql"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// ReservationResponse is a DTO
+type ReservationResponse struct {
+	Success   bool   `json:"success,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+	ProductID string `json:"productID,omitempty"`
+	// ExpiresAt is the time the hold expires, in RFC 3339 format
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+var reservationResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ReservationResponse",
+	Fields: graphql.Fields{
+		"success": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"error": &graphql.Field{
+			Type: graphql.String,
+		},
+		"retryable": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"productID": &graphql.Field{
+			Type: graphql.String,
+		},
+		"expiresAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The time the hold expires, in RFC 3339 format",
+		},
+	},
+})
+
+var reserveProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ReserveProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"productID": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"holder": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+			Description: "The session token the product is held for. It's needed to purchase the product during the hold. " +
+				"It's ignored when the buyer is authenticated, since the product is held for them",
+		},
+	},
+})
+
+// ReserveProductResolver is a resolver function
+func ReserveProductResolver(log cqrs.Logger, bus cqrs.Bus) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		pID, err := productIDFromInput(p)
+		if err != nil {
+			log.Printf("%s\n", err.Error())
+			return ReservationResponse{Success: false, Error: err.Error()}, nil
+		}
+
+		cmd := app.ReserveProductCmd{ID: pID, Holder: holderFromInput(p), BuyerID: buyerIDFromContext(p)}
+		result, err := bus.Dispatch(p.Context, cmd)
+		if err != nil {
+			log.Printf("something went wrong when executing the command %s: %s\n", cmd.Name(), err.Error())
+			if ferr, ok := forbiddenError(err); ok {
+				return nil, ferr
+			}
+			msg, retryable := reservationErrorMessage(err)
+			return ReservationResponse{Success: false, Error: msg, Retryable: retryable}, nil
+		}
+
+		response := ReservationResponse{Success: true, ProductID: pID.String()}
+		evs, _ := result.([]events.Event)
+		for _, ev := range evs {
+			if reserved, ok := ev.(domain.ProductReservedEvent); ok {
+				response.ExpiresAt = reserved.ExpiresAt.Format(time.RFC3339)
+			}
+		}
+		return response, nil
+	}
+}
+
+// reservationErrorMessage returns the message to be sent to the client for an error found reserving a product,
+// and whether the reservation can be retried
+func reservationErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidHolder):
+		return "holder is required", false
+	case errors.Is(err, domain.ErrNotUniqueProduct):
+		return "only unique products can be reserved", false
+	case errors.Is(err, domain.ErrProductArchived):
+		return "productID is archived", false
+	default:
+		return purchaseErrorMessage(err)
+	}
+}
@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveProductResolver(t *testing.T) {
+	var (
+		p         = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+		expiresAt = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		params    = graphql.ResolveParams{
+			Args: map[string]interface{}{
+				"input": map[string]interface{}{"productID": p.ID().String(), "holder": "session-1"},
+			},
+		}
+	)
+	require.NoError(t, p.Reserve(*fixtures.Reservation(domain.SessionHolder("session-1"), expiresAt)))
+
+	testCases := []struct {
+		name             string
+		params           graphql.ResolveParams
+		bm               busMock
+		lm               *loggerMock
+		expectedResponse api.ReservationResponse
+		expectedLogCalls int
+	}{
+		{
+			name: `Given an invalid product ID,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params: graphql.ResolveParams{
+				Args: map[string]interface{}{"input": map[string]interface{}{"productID": "invalid", "holder": "session-1"}},
+			},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ReservationResponse{Error: "invalid product UUID"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrProductReserved error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrProductReserved},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ReservationResponse{Error: "productID reserved by another buyer"},
+		},
+		{
+			name: `Given a bus that returns a domain.ErrNotUniqueProduct error,
+				when it's called,
+				then the error is logged and an error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: domain.ErrNotUniqueProduct},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ReservationResponse{Error: "only unique products can be reserved"},
+		},
+		{
+			name: `Given a bus that returns an app.ErrVersionConflict error,
+				when it's called,
+				then the error is logged and a retryable error response is returned`,
+			params:           params,
+			bm:               busMock{expectedError: app.ErrVersionConflict},
+			lm:               &loggerMock{},
+			expectedLogCalls: 1,
+			expectedResponse: api.ReservationResponse{Error: "product modified concurrently", Retryable: true},
+		},
+		{
+			name: `Given a bus that returns the reservation event,
+				when it's called,
+				then a success response with the expiration of the hold is returned`,
+			params:           params,
+			bm:               busMock{expectedResult: p.Events()},
+			lm:               &loggerMock{},
+			expectedResponse: api.ReservationResponse{Success: true, ProductID: p.ID().String(), ExpiresAt: "2030-01-02T03:04:05Z"},
+		},
+	}
+
+	for _, tc := range testCases {
+		rr := api.ReserveProductResolver(tc.lm, tc.bm)
+		response, err := rr(tc.params)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expectedLogCalls, tc.lm.calls, tc.name)
+		require.Equal(t, tc.expectedResponse, response, tc.name)
+	}
+}
+
+func TestReserveProductResolverBuyer(t *testing.T) {
+	bm := &recordingBusMock{}
+	rr := api.ReserveProductResolver(&loggerMock{}, bm)
+	ID := uuid.New()
+	_, err := rr(graphql.ResolveParams{
+		Context: app.ContextWithPrincipal(context.Background(), app.Principal{ID: "buyer"}),
+		Args:    map[string]interface{}{"input": map[string]interface{}{"productID": ID.String(), "holder": "another buyer"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, bm.dispatched, 1)
+	require.Equal(t, app.ReserveProductCmd{ID: ID, Holder: "another buyer", BuyerID: "buyer"}, bm.dispatched[0])
+}
+
+func TestPurchaseProductResolverHolder(t *testing.T) {
+	bm := &recordingBusMock{}
+	pr := api.PurchaseProductResolver(&loggerMock{}, bm)
+	ID := uuid.New()
+	_, err := pr(graphql.ResolveParams{
+		Args: map[string]interface{}{"input": map[string]interface{}{"productID": ID.String(), "holder": "session-1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, bm.dispatched, 1)
+	require.Equal(t, "session-1", bm.dispatched[0].(app.PurchaseProductCmd).Holder)
+}
+
+func TestPurchaseProductResolverBuyer(t *testing.T) {
+	t.Run(`Given an authenticated buyer, when a product is purchased, then the command carries the buyer ID and the request context`, func(t *testing.T) {
+		bm := &recordingBusMock{}
+		pr := api.PurchaseProductResolver(&loggerMock{}, bm)
+		ctx := app.ContextWithPrincipal(context.Background(), app.Principal{ID: "buyer"})
+		_, err := pr(graphql.ResolveParams{
+			Context: ctx,
+			Args:    map[string]interface{}{"input": map[string]interface{}{"productID": uuid.New().String()}},
+		})
+		require.NoError(t, err)
+		require.Len(t, bm.dispatched, 1)
+		require.Equal(t, "buyer", bm.dispatched[0].(app.PurchaseProductCmd).BuyerID)
+		require.Equal(t, ctx, bm.ctx)
+	})
+
+	t.Run(`Given an anonymous caller, when a product is purchased, then the command has no buyer ID`, func(t *testing.T) {
+		bm := &recordingBusMock{}
+		pr := api.PurchaseProductResolver(&loggerMock{}, bm)
+		_, err := pr(graphql.ResolveParams{
+			Context: context.Background(),
+			Args:    map[string]interface{}{"input": map[string]interface{}{"productID": uuid.New().String()}},
+		})
+		require.NoError(t, err)
+		require.Len(t, bm.dispatched, 1)
+		require.Empty(t, bm.dispatched[0].(app.PurchaseProductCmd).BuyerID)
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// sseKeepAliveInterval is how often a comment is sent to keep the stream open when there are no events
+const sseKeepAliveInterval = 15 * time.Second
+
+// EventMessage is a DTO. It's the data of the messages of the events stream
+type EventMessage struct {
+	Name        string          `json:"name"`
+	AggregateID string          `json:"aggregateID"`
+	Timestamp   string          `json:"timestamp"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// privateEventFields are the fields of the payloads of the events that are not streamed.
+// The holder of a reservation is only a hash, but an unsalted one, so the known buyers could be matched against it
+var privateEventFields = []string{"Holder"}
+
+// NewEventMessage builds an event message DTO. The private fields are removed from the payload
+func NewEventMessage(pe app.PublishedEvent) (EventMessage, error) {
+	payload, err := app.EncodeEvent(pe.Event)
+	if err != nil {
+		return EventMessage{}, err
+	}
+	payload, err = publicPayload(payload)
+	if err != nil {
+		return EventMessage{}, fmt.Errorf("event %s: %w", pe.Event.Name(), err)
+	}
+	return EventMessage{
+		Name:        pe.Event.Name(),
+		AggregateID: pe.Event.AggregateID().String(),
+		Timestamp:   pe.OccurredAt.Format(time.RFC3339Nano),
+		Payload:     payload,
+	}, nil
+}
+
+// publicPayload returns the payload of an event without its private fields
+func publicPayload(payload []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for _, f := range privateEventFields {
+		delete(fields, f)
+	}
+	return json.Marshal(fields)
+}
+
+// EventsStreamHandler is the HTTP handler for the Server-Sent Events stream of the domain events.
+// The events can be filtered with the name query param, which can be repeated or have several names separated by commas.
+// A client resumes the stream by sending the ID of the last event it received in the Last-Event-ID header or in the lastEventID query param.
+func EventsStreamHandler(log cqrs.Logger, subscriber EventsSubscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		var names []string
+		for _, v := range r.URL.Query()["name"] {
+			for _, n := range strings.Split(v, ",") {
+				if n = strings.TrimSpace(n); n != "" {
+					names = append(names, n)
+				}
+			}
+		}
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("lastEventID")
+		}
+
+		evs := subscriber.Subscribe(r.Context(), lastEventID, names...)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case pe, ok := <-evs:
+				if !ok {
+					return
+				}
+				msg, err := NewEventMessage(pe)
+				if err != nil {
+					log.Printf("encoding event %s: %s\n", pe.ID, err.Error())
+					continue
+				}
+				data, err := json.Marshal(msg)
+				if err != nil {
+					log.Printf("encoding event %s: %s\n", pe.ID, err.Error())
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", pe.ID, msg.Name, data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
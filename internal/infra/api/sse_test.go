@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sseMessage struct {
+	ID    string
+	Event string
+	Data  api.EventMessage
+}
+
+// readSSEMessage reads the next message of the stream, skipping the comments
+func readSSEMessage(t *testing.T, r *bufio.Reader) sseMessage {
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && msg.ID != "":
+			return msg
+		case strings.HasPrefix(line, "id: "):
+			msg.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			msg.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg.Data))
+		}
+	}
+}
+
+func TestEventsStreamHandler(t *testing.T) {
+	t.Run(`Given some published events, 
+		when a client resumes the stream filtering by name, 
+		then it receives the events with that name published after the last one it received`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub       = app.NewEventsHub()
+			published = hub.Subscribe(ctx, "")
+			p         = fixtures.Product{}.Build()
+		)
+		hub.Publish(domain.NewProductPurchasedEvent(p, 1))
+		hub.Publish(domain.NewProductRefundedEvent(p, 1))
+		hub.Publish(domain.NewProductPurchasedEvent(p, 2))
+		first := <-published
+
+		srv := httptest.NewServer(api.EventsStreamHandler(&loggerMock{}, hub))
+		defer srv.Close()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?name="+domain.ProductPurchasedEventName, nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", first.ID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		msg := readSSEMessage(t, bufio.NewReader(resp.Body))
+		require.Equal(t, domain.ProductPurchasedEventName, msg.Event)
+		require.Equal(t, domain.ProductPurchasedEventName, msg.Data.Name)
+		require.Equal(t, p.ID().String(), msg.Data.AggregateID)
+		_, err = time.Parse(time.RFC3339Nano, msg.Data.Timestamp)
+		require.NoError(t, err)
+		require.Contains(t, string(msg.Data.Payload), `"Quantity":2`)
+	})
+
+	t.Run(`Given a client of the stream, 
+		when an event is published, 
+		then it receives it`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		hub := app.NewEventsHub()
+		srv := httptest.NewServer(api.EventsStreamHandler(&loggerMock{}, hub))
+		defer srv.Close()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// The headers are sent once the client is subscribed
+		ev := domain.NewProductRefundedEvent(fixtures.Product{}.Build(), 1)
+		hub.Publish(ev)
+
+		msg := readSSEMessage(t, bufio.NewReader(resp.Body))
+		require.Equal(t, domain.ProductRefundedEventName, msg.Event)
+		require.Equal(t, ev.AggregateID().String(), msg.Data.AggregateID)
+		require.NotEmpty(t, msg.ID)
+	})
+
+	t.Run(`Given a client of the stream, 
+		when a reservation event is published, 
+		then it receives it without the holder`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		hub := app.NewEventsHub()
+		srv := httptest.NewServer(api.EventsStreamHandler(&loggerMock{}, hub))
+		defer srv.Close()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		r := fixtures.Reservation(domain.BuyerHolder("buyer1"), time.Now().Add(time.Hour))
+		hub.Publish(domain.NewProductReservedEvent(fixtures.Product{}.Build(), *r))
+
+		msg := readSSEMessage(t, bufio.NewReader(resp.Body))
+		require.Equal(t, domain.ProductReservedEventName, msg.Event)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(msg.Data.Payload, &payload))
+		require.NotContains(t, payload, "Holder")
+		require.Contains(t, payload, "ExpiresAt")
+	})
+}
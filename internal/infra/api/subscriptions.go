@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// EventsSubscriber gives the domain events published by the service
+type EventsSubscriber interface {
+	// Subscribe returns a channel that receives the events with one of the given names. It's closed when the context is done.
+	// If the ID of the last event received is given, the events published after it are received first.
+	Subscribe(ctx context.Context, lastEventID string, names ...string) <-chan app.PublishedEvent
+}
+
+// ProductPurchased is a DTO
+type ProductPurchased struct {
+	ProductID string `json:"productID"`
+	Quantity  int    `json:"quantity"`
+	Stock     int    `json:"stock"`
+}
+
+var productPurchasedType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductPurchased",
+	Fields: graphql.Fields{
+		"productID": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"quantity": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"stock": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+	},
+})
+
+// ProductAvailability is a DTO
+type ProductAvailability struct {
+	ProductID string `json:"productID"`
+	Available bool   `json:"available"`
+	Stock     int    `json:"stock"`
+}
+
+var productAvailabilityType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductAvailability",
+	Fields: graphql.Fields{
+		"productID": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"available": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"stock": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+	},
+})
+
+func subscriptionType(subscriber EventsSubscriber) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"productPurchased": &graphql.Field{
+				Type:      graphql.NewNonNull(productPurchasedType),
+				Subscribe: ProductPurchasedSubscriber(subscriber),
+				Resolve:   sourceResolver,
+			},
+			"productAvailabilityChanged": &graphql.Field{
+				Type: graphql.NewNonNull(productAvailabilityType),
+				Args: graphql.FieldConfigArgument{
+					"productID": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Subscribe: ProductAvailabilityChangedSubscriber(subscriber),
+				Resolve:   sourceResolver,
+			},
+		},
+	})
+}
+
+// sourceResolver resolves a subscription field with the value sent by its subscriber
+func sourceResolver(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+// ProductPurchasedSubscriber is a subscriber function. It sends a ProductPurchased for each purchase
+func ProductPurchasedSubscriber(subscriber EventsSubscriber) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return subscribe(p.Context, subscriber, func(ev events.Event) (interface{}, bool) {
+			e, ok := ev.(domain.ProductPurchasedEvent)
+			if !ok {
+				return nil, false
+			}
+			return ProductPurchased{ProductID: e.AggregateID().String(), Quantity: e.Quantity, Stock: e.Stock}, true
+		}, domain.ProductPurchasedEventName), nil
+	}
+}
+
+// ProductAvailabilityChangedSubscriber is a subscriber function.
+// It sends a ProductAvailability each time the given product becomes available or not available.
+func ProductAvailabilityChangedSubscriber(subscriber EventsSubscriber) func(p graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		arg, _ := p.Args["productID"].(string)
+		productID, err := uuid.Parse(arg)
+		if err != nil {
+			return nil, errors.New("invalid product UUID")
+		}
+
+		return subscribe(p.Context, subscriber, func(ev events.Event) (interface{}, bool) {
+			if ev.AggregateID() != productID {
+				return nil, false
+			}
+			switch e := ev.(type) {
+			case domain.ProductPurchasedEvent:
+				// The product is not available anymore when its last units are purchased
+				return ProductAvailability{ProductID: arg, Available: false, Stock: e.Stock}, e.Stock == 0
+			case domain.ProductRefundedEvent:
+				// The product is available again when it was sold out and it's put back on sale, like the queries derive it from its status
+				available := e.Status == domain.StatusPublished
+				return ProductAvailability{ProductID: arg, Available: available, Stock: e.Stock}, available && e.Stock == e.Quantity
+			case domain.ProductPublishedEvent:
+				return ProductAvailability{ProductID: arg, Available: true, Stock: e.Stock}, true
+			case domain.ProductUnpublishedEvent:
+				// The product was on sale unless it was sold
+				return ProductAvailability{ProductID: arg, Available: false, Stock: e.Stock}, e.Stock > 0
+			case domain.ProductArchivedEvent:
+				return ProductAvailability{ProductID: arg, Available: false, Stock: e.Stock}, e.From == domain.StatusPublished
+			case domain.ProductReservedEvent:
+				// It's sent again when the hold is renewed
+				return ProductAvailability{ProductID: arg, Available: false, Stock: e.Stock}, true
+			case domain.ProductReservationExpiredEvent:
+				return ProductAvailability{ProductID: arg, Available: true, Stock: e.Stock}, true
+			default:
+				return nil, false
+			}
+		}, domain.ProductPurchasedEventName, domain.ProductRefundedEventName,
+			domain.ProductPublishedEventName, domain.ProductUnpublishedEventName, domain.ProductArchivedEventName,
+			domain.ProductReservedEventName, domain.ProductReservationExpiredEventName), nil
+	}
+}
+
+// subscribe returns the channel a subscription field is resolved from. It sends the events mapped by fn,
+// skipping the ones fn returns false for. The channel is closed when the context is done.
+func subscribe(ctx context.Context, subscriber EventsSubscriber, fn func(events.Event) (interface{}, bool), names ...string) chan interface{} {
+	evs := subscriber.Subscribe(ctx, "", names...)
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		for pe := range evs {
+			v, ok := fn(pe.Event)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
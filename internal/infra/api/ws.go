@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/theskyinflames/cqrs-eda/pkg/cqrs"
+)
+
+// graphqlTransportWS is the WebSocket subprotocol spoken by the subscriptions endpoint.
+// It's described here: https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const graphqlTransportWS = "graphql-transport-ws"
+
+// connectionInitTimeout is the time a client has to send the connection_init message once connected
+const connectionInitTimeout = 10 * time.Second
+
+// Message types of the graphql-transport-ws protocol
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes of the graphql-transport-ws protocol
+const (
+	closeBadRequest             = 4400
+	closeUnauthorized           = 4401
+	closeSubprotocolNotAccepted = 4406
+	closeInitTimeout            = 4408
+	closeSubscriberExists       = 4409
+	closeTooManyInits           = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type subscribePayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{graphqlTransportWS},
+	// Like the CORS policy of the service, any origin is allowed
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// SubscriptionsHandler is the HTTP handler for the GraphQL over WebSocket endpoint.
+// Besides subscriptions, queries and mutations can also be sent through it. Each operation takes a token
+// of the rate limit of the client, and it's checked against the limits, like the ones sent to the HTTP endpoint.
+func SubscriptionsHandler(
+	log cqrs.Logger,
+	bus cqrs.Bus,
+	subscriber EventsSubscriber,
+	limits QueryLimits,
+	rateLimiter OperationRateLimiter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema, err := schema(log, bus, subscriber)
+		if err != nil {
+			log.Printf(fmt.Sprintf("building GraphQL schema: %s\n", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// The upgrader has already answered the request with an error
+			log.Printf("upgrading to WebSocket: %s\n", err.Error())
+			return
+		}
+		// Like the bodies of the HTTP requests, the messages are limited, so a client can't exhaust the memory of the service
+		conn.SetReadLimit(maxRequestBodySize)
+
+		s := &wsSession{
+			log:         log,
+			schema:      schema,
+			limits:      limits,
+			rateLimiter: rateLimiter,
+			conn:        conn,
+			ops:         make(map[string]context.CancelFunc),
+		}
+		if conn.Subprotocol() != graphqlTransportWS {
+			s.close(closeSubprotocolNotAccepted, "Subprotocol not acceptable")
+			return
+		}
+		s.run(contextWithClient(r.Context(), r))
+	}
+}
+
+// wsSession is a graphql-transport-ws connection with a client
+type wsSession struct {
+	log         cqrs.Logger
+	schema      graphql.Schema
+	limits      QueryLimits
+	rateLimiter OperationRateLimiter
+	conn        *websocket.Conn
+
+	// writeMux serializes the writes, because the operations are run concurrently
+	writeMux sync.Mutex
+
+	mux          sync.Mutex
+	initReceived bool
+	acked        bool
+	// ops has the operations in course by ID. They're cancelled when the client completes them
+	ops map[string]context.CancelFunc
+}
+
+func (s *wsSession) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // when the connection finishes, all its operations are cancelled
+	defer s.conn.Close()
+
+	initTimer := time.AfterFunc(connectionInitTimeout, func() {
+		s.mux.Lock()
+		acked := s.acked
+		s.mux.Unlock()
+		if !acked {
+			s.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.close(closeBadRequest, "Invalid message received")
+			return
+		}
+		if !s.handle(ctx, msg) {
+			return
+		}
+	}
+}
+
+// handle handles a message received from the client. It returns false when the connection has been closed
+func (s *wsSession) handle(ctx context.Context, msg wsMessage) bool {
+	switch msg.Type {
+	case msgConnectionInit:
+		s.mux.Lock()
+		alreadyReceived := s.initReceived
+		s.initReceived, s.acked = true, true
+		s.mux.Unlock()
+		if alreadyReceived {
+			s.close(closeTooManyInits, "Too many initialisation requests")
+			return false
+		}
+		s.write(wsMessage{Type: msgConnectionAck})
+	case msgPing:
+		s.write(wsMessage{Type: msgPong})
+	case msgPong:
+	case msgSubscribe:
+		return s.subscribe(ctx, msg)
+	case msgComplete:
+		s.mux.Lock()
+		cancel, ok := s.ops[msg.ID]
+		delete(s.ops, msg.ID)
+		s.mux.Unlock()
+		if ok {
+			cancel()
+		}
+	default:
+		s.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+	return true
+}
+
+func (s *wsSession) subscribe(ctx context.Context, msg wsMessage) bool {
+	var payload subscribePayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+		s.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+
+	s.mux.Lock()
+	if !s.acked {
+		s.mux.Unlock()
+		s.close(closeUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, ok := s.ops[msg.ID]; ok {
+		s.mux.Unlock()
+		s.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	s.ops[msg.ID] = cancel
+	s.mux.Unlock()
+
+	go s.execute(opCtx, msg.ID, payload)
+	return true
+}
+
+// execute runs an operation and sends its results to the client. The operations rejected before running them
+// get an error message
+func (s *wsSession) execute(ctx context.Context, id string, payload subscribePayload) {
+	if decision := s.rateLimiter.take(ctx, payload.Query, payload.OperationName); decision != nil && !decision.Allowed {
+		s.finish(id)
+		s.send(id, msgError, []gqlerrors.FormattedError{rateLimitedError(decision.RetryAfter)})
+		return
+	}
+
+	req := operationRequest{Query: payload.Query, OperationName: payload.OperationName, Variables: payload.Variables}
+	results, rejected := runOperation(ctx, s.schema, req, s.limits)
+	if rejected != nil {
+		s.finish(id)
+		s.send(id, msgError, rejected.Errors)
+		return
+	}
+	for result := range results {
+		if ctx.Err() == nil {
+			s.send(id, msgNext, result)
+		}
+	}
+
+	// The operation is completed by the server only when the client hasn't completed it before
+	if s.finish(id) {
+		s.write(wsMessage{ID: id, Type: msgComplete})
+	}
+}
+
+// finish removes an operation in course. It returns false if it had already been removed
+func (s *wsSession) finish(id string) bool {
+	s.mux.Lock()
+	cancel, ok := s.ops[id]
+	delete(s.ops, id)
+	s.mux.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// send writes a message of an operation with the given payload
+func (s *wsSession) send(id, msgType string, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		s.log.Printf("encoding WebSocket message: %s\n", err.Error())
+		return
+	}
+	s.write(wsMessage{ID: id, Type: msgType, Payload: b})
+}
+
+func (s *wsSession) write(msg wsMessage) {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	if err := s.conn.WriteJSON(msg); err != nil {
+		s.log.Printf("writing WebSocket message: %s\n", err.Error())
+	}
+}
+
+func (s *wsSession) close(code int, reason string) {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	_ = s.conn.Close()
+}
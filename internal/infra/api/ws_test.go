@@ -0,0 +1,331 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+	"theskyinflames/graphql-challenge/internal/infra/api"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func dialSubscriptions(t *testing.T, hub app.EventsHub, bm busMock) *websocket.Conn {
+	return dial(t, api.SubscriptionsHandler(&loggerMock{}, bm, hub, api.DefaultQueryLimits(), api.OperationRateLimiter{}))
+}
+
+func dial(t *testing.T, h http.Handler) *websocket.Conn {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg string) {
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+}
+
+func receive(t *testing.T, conn *websocket.Conn) wsMessage {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var msg wsMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func initConnection(t *testing.T, conn *websocket.Conn) {
+	send(t, conn, `{"type":"connection_init"}`)
+	require.Equal(t, "connection_ack", receive(t, conn).Type)
+}
+
+func TestSubscriptionsHandler(t *testing.T) {
+	t.Run(`Given a connected client, 
+		when it sends a ping, 
+		then a pong is received`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"type":"ping"}`)
+		require.Equal(t, "pong", receive(t, conn).Type)
+	})
+
+	t.Run(`Given a client that has not initialised the connection, 
+		when it subscribes, 
+		then the connection is closed as unauthorized`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription {productPurchased {productID}}"}}`)
+		_, _, err := conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, 4401), err)
+	})
+
+	t.Run(`Given a connected client, 
+		when it initialises the connection twice, 
+		then the connection is closed`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"type":"connection_init"}`)
+		_, _, err := conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, 4429), err)
+	})
+
+	t.Run(`Given a connected client, 
+		when it sends a message bigger than the body of a request can be, 
+		then the connection is closed as too big`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"type":"ping","payload":{"padding":"`+strings.Repeat("a", 1<<20)+`"}}`)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, _, err := conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), err)
+	})
+
+	t.Run(`Given a connected client, 
+		when it sends an invalid operation, 
+		then an error is received`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription {unknown}"}}`)
+		msg := receive(t, conn)
+		require.Equal(t, "error", msg.Type)
+		require.Equal(t, "1", msg.ID)
+	})
+
+	t.Run(`Given a connected client, 
+		when it sends an operation with a cyclic fragment, 
+		then an error is received and the connection is kept`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"{products {...A}} fragment A on Product {...B} fragment B on Product {...A}"}}`)
+		msg := receive(t, conn)
+		require.Equal(t, "error", msg.Type)
+		require.Equal(t, "1", msg.ID)
+		require.Contains(t, string(msg.Payload), "cyclic fragment")
+
+		send(t, conn, `{"type":"ping"}`)
+		require.Equal(t, "pong", receive(t, conn).Type)
+	})
+
+	t.Run(`Given a connected client, 
+		when it sends a query, 
+		then its result is received and the operation is completed`, func(t *testing.T) {
+		conn := dialSubscriptions(t, app.NewEventsHub(), busMock{expectedResult: []app.Product{}})
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"{products {id}}"}}`)
+		msg := receive(t, conn)
+		require.Equal(t, "next", msg.Type)
+		require.JSONEq(t, `{"data":{"products":[]},"extensions":{"complexity":{"depth":2,"fields":2,"aliases":0,"cost":1}}}`, string(msg.Payload))
+		require.Equal(t, wsMessage{ID: "1", Type: "complete"}, receive(t, conn))
+	})
+
+	t.Run(`Given a connected client,
+		when it sends an operation over the limits,
+		then it's rejected with an error and the connection is kept`, func(t *testing.T) {
+		limits := api.DefaultQueryLimits()
+		limits.MaxDepth = 1
+		conn := dial(t, api.SubscriptionsHandler(&loggerMock{}, busMock{}, app.NewEventsHub(), limits, api.OperationRateLimiter{}))
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription {productPurchased {productID}}"}}`)
+		msg := receive(t, conn)
+		require.Equal(t, "error", msg.Type)
+		require.Equal(t, "1", msg.ID)
+		var errs []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		}
+		require.NoError(t, json.Unmarshal(msg.Payload, &errs))
+		require.Len(t, errs, 1)
+		require.Equal(t, api.QueryTooComplexErrorCode, errs[0].Extensions["code"])
+		require.Equal(t, api.DepthLimit, errs[0].Extensions["limit"])
+
+		send(t, conn, `{"type":"ping"}`)
+		require.Equal(t, "pong", receive(t, conn).Type)
+	})
+
+	t.Run(`Given a client subscribed to the purchases, 
+		when a product is purchased, 
+		then the purchase is received`, func(t *testing.T) {
+		hub := app.NewEventsHub()
+		conn := dialSubscriptions(t, hub, busMock{})
+		initConnection(t, conn)
+		send(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription {productPurchased {productID quantity stock}}"}}`)
+
+		p := fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(3)}.Build()
+		ev := domain.NewProductPurchasedEvent(p, 2)
+		msgs := make(chan wsMessage)
+		go func() {
+			var msg wsMessage
+			_ = conn.ReadJSON(&msg)
+			msgs <- msg
+		}()
+		// The event is published until the subscription is registered
+		var msg wsMessage
+		require.Eventually(t, func() bool {
+			hub.Publish(ev)
+			select {
+			case msg = <-msgs:
+				return true
+			default:
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.Equal(t, "next", msg.Type)
+		require.Equal(t, "1", msg.ID)
+		require.JSONEq(t, `{"data":{"productPurchased":{"productID":"`+p.ID().String()+`","quantity":2,"stock":1}},"extensions":{"complexity":{"depth":2,"fields":4,"aliases":0,"cost":1}}}`, string(msg.Payload))
+
+		send(t, conn, `{"id":"1","type":"complete"}`)
+		send(t, conn, `{"type":"ping"}`)
+		for {
+			// A result sent before the subscription was completed may still be received
+			if msg := receive(t, conn); msg.Type == "pong" {
+				break
+			}
+		}
+	})
+}
+
+func TestProductAvailabilityChangedSubscriber(t *testing.T) {
+	t.Run(`Given an invalid product ID, 
+		when it's subscribed, 
+		then an error is returned`, func(t *testing.T) {
+		_, err := api.ProductAvailabilityChangedSubscriber(app.NewEventsHub())(graphql.ResolveParams{
+			Context: context.Background(),
+			Args:    map[string]interface{}{"productID": "invalid"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run(`Given a subscription to the availability of a product, 
+		when its stock or its availability changes, 
+		then only the changes of its availability are received`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub   = app.NewEventsHub()
+			p     = fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(2)}.Build()
+			other = fixtures.Product{Available: helpers.BoolPtr(true), Stock: helpers.IntPtr(1)}.Build()
+		)
+		sub, err := api.ProductAvailabilityChangedSubscriber(hub)(graphql.ResolveParams{
+			Context: ctx,
+			Args:    map[string]interface{}{"productID": p.ID().String()},
+		})
+		require.NoError(t, err)
+
+		hub.Publish(domain.NewProductPurchasedEvent(other, 1))
+		require.NoError(t, p.Purchase(1))
+		require.NoError(t, p.Purchase(1))
+		require.NoError(t, p.Refund(2))
+		require.NoError(t, p.Unpublish())
+		for _, ev := range p.Events() {
+			hub.Publish(ev)
+		}
+
+		received := sub.(chan interface{})
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: false, Stock: 0}, <-received)
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: true, Stock: 2}, <-received)
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: false, Stock: 2}, <-received)
+	})
+
+	t.Run(`Given a subscription to the availability of a sold out product withdrawn from sale, 
+		when it's refunded, 
+		then no change of its availability is received`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub   = app.NewEventsHub()
+			draft = domain.StatusDraft
+			p     = fixtures.Product{Status: &draft, Stock: helpers.IntPtr(0), Sold: helpers.IntPtr(1)}.Build()
+		)
+		sub, err := api.ProductAvailabilityChangedSubscriber(hub)(graphql.ResolveParams{
+			Context: ctx,
+			Args:    map[string]interface{}{"productID": p.ID().String()},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, p.Refund(1))
+		require.NoError(t, p.Publish())
+		require.NoError(t, p.Purchase(1))
+		for _, ev := range p.Events() {
+			hub.Publish(ev)
+		}
+
+		// Only the publication and the purchase are received
+		received := sub.(chan interface{})
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: true, Stock: 1}, <-received)
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: false, Stock: 0}, <-received)
+	})
+
+	t.Run(`Given a subscription to the availability of a product, 
+		when it's published and archived, 
+		then both changes of its availability are received`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub = app.NewEventsHub()
+			p   = fixtures.Product{Available: helpers.BoolPtr(false), Stock: helpers.IntPtr(1)}.Build()
+		)
+		sub, err := api.ProductAvailabilityChangedSubscriber(hub)(graphql.ResolveParams{
+			Context: ctx,
+			Args:    map[string]interface{}{"productID": p.ID().String()},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, p.Publish())
+		require.NoError(t, p.Archive())
+		for _, ev := range p.Events() {
+			hub.Publish(ev)
+		}
+
+		received := sub.(chan interface{})
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: true, Stock: 1}, <-received)
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: false, Stock: 1}, <-received)
+	})
+
+	t.Run(`Given a subscription to the availability of a product, 
+		when it's reserved and its hold expires, 
+		then both changes of its availability are received`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			hub = app.NewEventsHub()
+			p   = fixtures.Product{Available: helpers.BoolPtr(true)}.Build()
+			now = time.Now()
+		)
+		sub, err := api.ProductAvailabilityChangedSubscriber(hub)(graphql.ResolveParams{
+			Context: ctx,
+			Args:    map[string]interface{}{"productID": p.ID().String()},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, p.Reserve(*fixtures.Reservation("buyer", now)))
+		require.NoError(t, p.ExpireReservation(now))
+		for _, ev := range p.Events() {
+			hub.Publish(ev)
+		}
+
+		received := sub.(chan interface{})
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: false, Stock: 1}, <-received)
+		require.Equal(t, api.ProductAvailability{ProductID: p.ID().String(), Available: true, Stock: 1}, <-received)
+	})
+}
@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKeysRepository is a repository
+type APIKeysRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeysRepository is a constructor
+func NewAPIKeysRepository(db *sql.DB) APIKeysRepository {
+	return APIKeysRepository{db: db}
+}
+
+const apiKeysSelect = "SELECT id,name,key_hash,scopes,expires_at,last_used_at,revoked_at,created_at FROM api_keys"
+
+// lastUsedPrecision is how old the recorded last use of a key must be to record a new one
+const lastUsedPrecision = time.Minute
+
+// FindByID is a finder
+func (kr APIKeysRepository) FindByID(ctx context.Context, ID uuid.UUID) (domain.APIKey, error) {
+	return kr.findOne(ctx, " WHERE id=$1", ID)
+}
+
+// FindByHash is a finder
+func (kr APIKeysRepository) FindByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	return kr.findOne(ctx, " WHERE key_hash=$1", hash)
+}
+
+func (kr APIKeysRepository) findOne(ctx context.Context, where string, arg interface{}) (domain.APIKey, error) {
+	k, err := scanAPIKey(conn(ctx, kr.db).QueryRowContext(ctx, apiKeysSelect+where, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.APIKey{}, app.ErrNotFound
+		}
+		return domain.APIKey{}, err
+	}
+	return k, nil
+}
+
+// FindAll is a finder. The most recent keys come first
+func (kr APIKeysRepository) FindAll(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := conn(ctx, kr.db).QueryContext(ctx, apiKeysSelect+" ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Insert persists a new API key
+func (kr APIKeysRepository) Insert(ctx context.Context, k domain.APIKey) error {
+	_, err := conn(ctx, kr.db).ExecContext(ctx,
+		`INSERT INTO api_keys (id, name, key_hash, scopes, expires_at, revoked_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		k.ID(), k.Name(), k.Hash(), pq.StringArray(k.Scopes()), k.ExpiresAt(), k.RevokedAt(), k.CreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert api key: %w", err)
+	}
+	return nil
+}
+
+// Update updates the fields of the key that can be changed. The last use is recorded by TouchLastUsed
+func (kr APIKeysRepository) Update(ctx context.Context, k domain.APIKey) error {
+	result, err := conn(ctx, kr.db).ExecContext(ctx,
+		"UPDATE api_keys SET name=$1, scopes=$2, expires_at=$3, revoked_at=$4 WHERE id=$5",
+		k.Name(), pq.StringArray(k.Scopes()), k.ExpiresAt(), k.RevokedAt(), k.ID(),
+	)
+	if err != nil {
+		return fmt.Errorf("update api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update api key: rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records the last use of the key, when the previous one is older than lastUsedPrecision
+func (kr APIKeysRepository) TouchLastUsed(ctx context.Context, ID uuid.UUID, now time.Time) error {
+	_, err := conn(ctx, kr.db).ExecContext(ctx,
+		"UPDATE api_keys SET last_used_at=$1 WHERE id=$2 AND (last_used_at IS NULL OR last_used_at < $3)",
+		now, ID, now.Add(-lastUsedPrecision),
+	)
+	if err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(s scanner) (domain.APIKey, error) {
+	var (
+		id         uuid.UUID
+		name       string
+		hash       string
+		scopes     pq.StringArray
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+		createdAt  time.Time
+	)
+	if err := s.Scan(&id, &name, &hash, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &createdAt); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	var k domain.APIKey
+	k.Hydrate(id, name, hash, scopes, nullTime(expiresAt), nullTime(lastUsedAt), nullTime(revokedAt), createdAt.UTC())
+	return k, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
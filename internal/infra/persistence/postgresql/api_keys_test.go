@@ -0,0 +1,61 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestAPIKeys() {
+	t := suite.T()
+
+	var (
+		ctx       = context.Background()
+		kr        = postgresql.NewAPIKeysRepository(suite.db)
+		now       = time.Now().UTC().Truncate(time.Microsecond)
+		expiresAt = now.Add(time.Hour)
+		hash      = app.HashAPIKey(uuid.New().String())
+	)
+	k, err := domain.NewAPIKey(uuid.New(), "erp", hash, []string{app.RoleMerchandiser, app.RoleAdmin}, &expiresAt, now)
+	require.NoError(t, err)
+	require.NoError(t, kr.Insert(ctx, k))
+
+	found, err := kr.FindByHash(ctx, hash)
+	require.NoError(t, err)
+	require.Equal(t, k.ID(), found.ID())
+	require.Equal(t, "erp", found.Name())
+	require.Equal(t, k.Scopes(), found.Scopes())
+	require.True(t, expiresAt.Equal(*found.ExpiresAt()))
+	require.Nil(t, found.LastUsedAt())
+	require.Nil(t, found.RevokedAt())
+
+	// The last use is only recorded when the previous one is older than a minute
+	require.NoError(t, kr.TouchLastUsed(ctx, k.ID(), now))
+	require.NoError(t, kr.TouchLastUsed(ctx, k.ID(), now.Add(time.Second)))
+	found, err = kr.FindByID(ctx, k.ID())
+	require.NoError(t, err)
+	require.True(t, now.Equal(*found.LastUsedAt()))
+
+	require.NoError(t, found.Revoke(now))
+	require.NoError(t, kr.Update(ctx, found))
+	found, err = kr.FindByID(ctx, k.ID())
+	require.NoError(t, err)
+	require.True(t, now.Equal(*found.RevokedAt()))
+
+	all, err := kr.FindAll(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	_, err = kr.FindByHash(ctx, app.HashAPIKey("unknown"))
+	require.ErrorIs(t, err, app.ErrNotFound)
+	require.ErrorIs(t, kr.Update(ctx, domain.APIKey{}), app.ErrNotFound)
+}
@@ -0,0 +1,69 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestCatalogChanges() {
+	t := suite.T()
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": postgresql.NewEventSourcedProductsRepository(suite.db),
+	}
+	for name, pr := range repositories {
+		price := fixtures.Money("3.3")
+		p, err := domain.NewProduct(uuid.New(), "catalog product", &price, 2)
+		require.NoError(t, err, name)
+		require.NoError(t, pr.Insert(context.Background(), p), name)
+
+		// The ID of a product can't be reused
+		require.ErrorIs(t, pr.Insert(context.Background(), p), app.ErrAlreadyExists, name)
+
+		found, err := pr.FindByID(context.Background(), p.ID())
+		require.NoError(t, err, name)
+		require.NoError(t, found.Rename("renamed product"), name)
+		require.NoError(t, found.ChangePrice(fixtures.Money("4.4")), name)
+		require.NoError(t, found.Unpublish(), name)
+		require.NoError(t, pr.Update(context.Background(), found), name)
+
+		found, err = pr.FindByID(context.Background(), p.ID())
+		require.NoError(t, err, name)
+		require.Equal(t, "renamed product", found.Name(), name)
+		foundPrice, priced := found.Price()
+		require.True(t, priced, name)
+		require.Equal(t, fixtures.Money("4.4"), foundPrice, name)
+		require.Equal(t, domain.StatusDraft, found.Status(), name)
+		require.False(t, found.IsAvailable(), name)
+		require.Equal(t, 2, found.Stock(), name)
+
+		// An update on a stale version is rejected
+		stale, err := pr.FindByID(context.Background(), p.ID())
+		require.NoError(t, err, name)
+		require.NoError(t, found.Rename("again"), name)
+		require.NoError(t, pr.Update(context.Background(), found), name)
+		require.NoError(t, stale.Rename("stale"), name)
+		require.ErrorIs(t, pr.Update(context.Background(), stale), app.ErrVersionConflict, name)
+
+		found, err = pr.FindByID(context.Background(), p.ID())
+		require.NoError(t, err, name)
+		require.NoError(t, found.Archive(), name)
+		require.NoError(t, pr.Update(context.Background(), found), name)
+
+		found, err = pr.FindByID(context.Background(), p.ID())
+		require.NoError(t, err, name)
+		require.Equal(t, domain.StatusArchived, found.Status(), name)
+		require.False(t, found.IsAvailable(), name)
+	}
+}
@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+
+	"theskyinflames/graphql-challenge/internal/app"
+
+	"github.com/lib/pq"
+)
+
+// productOrderColumns maps the fields the products can be sorted by to the columns of the products table.
+// Only these columns are interpolated in the queries, the values of the filter are always passed as args.
+var productOrderColumns = map[app.ProductOrderField]string{
+	"":                         "id",
+	app.ProductOrderByID:       "id",
+	app.ProductOrderByName:     "name",
+	app.ProductOrderByPrice:    "price",
+	app.ProductOrderByQuantity: "stock",
+}
+
+// filterClauses returns the WHERE clause of a query on the products table, the terms of its ORDER BY clause, and their args.
+// The ID is the tiebreaker, so the products are always returned in the same order.
+func filterClauses(filter app.ProductFilter, order app.ProductOrder) (string, string, []interface{}, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Available != nil {
+		conditions = append(conditions, "available = "+arg(*filter.Available))
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, fmt.Sprintf("currency = %s AND price >= %s",
+			arg(filter.MinPrice.Currency()), arg(filter.MinPrice.Amount())))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, fmt.Sprintf("currency = %s AND price <= %s",
+			arg(filter.MaxPrice.Currency()), arg(filter.MaxPrice.Amount())))
+	}
+	if filter.NameContains != "" {
+		conditions = append(conditions, fmt.Sprintf(`name ILIKE %s ESCAPE '\'`, arg("%"+escapeLike(filter.NameContains)+"%")))
+	}
+	if filter.IDs != nil {
+		ids := make([]string, 0, len(filter.IDs))
+		for _, ID := range filter.IDs {
+			ids = append(ids, ID.String())
+		}
+		conditions = append(conditions, fmt.Sprintf("id = ANY(%s::uuid[])", arg(pq.StringArray(ids))))
+	}
+
+	var where string
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	column, ok := productOrderColumns[order.Field]
+	if !ok {
+		return "", "", nil, fmt.Errorf("%w: unknown field %q", app.ErrInvalidProductOrder, order.Field)
+	}
+
+	return where, orderTerms(column, order.Direction == app.OrderDesc, false), args, nil
+}
+
+// orderTerms returns the terms of the ORDER BY clause that sorts the products by the column, with the products
+// without value last and the ID as the tiebreaker. When it's reversed, the products are sorted the other way around.
+func orderTerms(column string, desc, reversed bool) string {
+	direction := func(desc bool) string {
+		if desc {
+			return "DESC"
+		}
+		return "ASC"
+	}
+	if column == "id" {
+		return "id " + direction(desc != reversed)
+	}
+	nulls := "LAST"
+	if reversed {
+		nulls = "FIRST"
+	}
+	return fmt.Sprintf("%s %s NULLS %s, id %s", column, direction(desc != reversed), nulls, direction(reversed))
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so they're matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
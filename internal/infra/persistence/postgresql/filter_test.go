@@ -0,0 +1,102 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestFindMatching() {
+	t := suite.T()
+
+	// The products are inserted through the event-sourced repository, so they're in both stores.
+	// Their names have a token that no other product has, so the filter only matches them.
+	var (
+		es       = postgresql.NewEventSourcedProductsRepository(suite.db)
+		token    = uuid.NewString()[:8]
+		cheap    = fixtures.NewProduct("Cheap_"+token, "1.5", 3)
+		pricey   = fixtures.NewProduct("Pricey_"+token, "9.99", 1)
+		soldOut  = fixtures.NewProduct("SoldOut_"+token, "5", 1)
+		minPrice = fixtures.Money("1.5")
+		maxPrice = fixtures.Money("5")
+	)
+	for _, p := range []domain.Product{cheap, pricey, soldOut} {
+		require.NoError(t, es.Insert(context.Background(), p))
+	}
+	found, err := es.FindByID(context.Background(), soldOut.ID())
+	require.NoError(t, err)
+	require.NoError(t, found.Purchase(1))
+	require.NoError(t, es.UpdateStock(context.Background(), found))
+
+	ids := func(products []domain.Product) []uuid.UUID {
+		var ids []uuid.UUID
+		for _, p := range products {
+			ids = append(ids, p.ID())
+		}
+		return ids
+	}
+
+	testCases := []struct {
+		name     string
+		filter   app.ProductFilter
+		order    app.ProductOrder
+		expected []uuid.UUID
+	}{
+		{
+			name:     "available products sorted by price",
+			filter:   app.ProductFilter{NameContains: token, Available: helpers.BoolPtr(true)},
+			order:    app.ProductOrder{Field: app.ProductOrderByPrice},
+			expected: []uuid.UUID{cheap.ID(), pricey.ID()},
+		},
+		{
+			name:     "products in a range of prices sorted by price in descending order",
+			filter:   app.ProductFilter{NameContains: token, MinPrice: &minPrice, MaxPrice: &maxPrice},
+			order:    app.ProductOrder{Field: app.ProductOrderByPrice, Direction: app.OrderDesc},
+			expected: []uuid.UUID{soldOut.ID(), cheap.ID()},
+		},
+		{
+			name:     "products whose name contains a token, ignoring the case, sorted by quantity",
+			filter:   app.ProductFilter{NameContains: "pricey_" + token},
+			order:    app.ProductOrder{Field: app.ProductOrderByQuantity},
+			expected: []uuid.UUID{pricey.ID()},
+		},
+		{
+			name:     "the wildcards of the name are matched literally",
+			filter:   app.ProductFilter{NameContains: "%" + token},
+			expected: nil,
+		},
+		{
+			name:     "products by ID sorted by name",
+			filter:   app.ProductFilter{IDs: []uuid.UUID{soldOut.ID(), pricey.ID()}},
+			order:    app.ProductOrder{Field: app.ProductOrderByName},
+			expected: []uuid.UUID{pricey.ID(), soldOut.ID()},
+		},
+		{
+			name:     "an empty list of IDs matches no product",
+			filter:   app.ProductFilter{IDs: []uuid.UUID{}},
+			expected: nil,
+		},
+	}
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": es,
+	}
+	for name, pr := range repositories {
+		for _, tc := range testCases {
+			found, err := pr.FindMatching(context.Background(), tc.filter, tc.order)
+			require.NoError(t, err, name, tc.name)
+			require.Equal(t, tc.expected, ids(found), name, tc.name)
+		}
+	}
+}
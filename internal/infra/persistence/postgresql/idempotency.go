@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"theskyinflames/graphql-challenge/internal/app"
+)
+
+// IdempotencyStore is a repository
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+// NewIdempotencyStore is a constructor
+func NewIdempotencyStore(db *sql.DB) IdempotencyStore {
+	return IdempotencyStore{db: db}
+}
+
+// Reserve stores the record if its key is not stored yet in its scope. It returns false if the key was already stored.
+// If the key is being reserved by another transaction in course, it waits until that transaction finishes.
+func (is IdempotencyStore) Reserve(ctx context.Context, r app.IdempotencyRecord) (bool, error) {
+	result, err := conn(ctx, is.db).ExecContext(ctx,
+		"INSERT INTO idempotency_keys (scope, key, command_name, fingerprint) VALUES ($1, $2, $3, $4) ON CONFLICT (scope, key) DO NOTHING",
+		r.Scope, r.Key, r.CommandName, r.Fingerprint,
+	)
+	if err != nil {
+		return false, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("reserve idempotency key: rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// FindByKey is a finder
+func (is IdempotencyStore) FindByKey(ctx context.Context, scope, key string) (app.IdempotencyRecord, error) {
+	var r app.IdempotencyRecord
+	err := conn(ctx, is.db).QueryRowContext(ctx,
+		"SELECT scope,key,command_name,fingerprint FROM idempotency_keys WHERE scope=$1 AND key=$2", scope, key,
+	).Scan(&r.Scope, &r.Key, &r.CommandName, &r.Fingerprint)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return app.IdempotencyRecord{}, app.ErrNotFound
+		}
+		return app.IdempotencyRecord{}, err
+	}
+	return r, nil
+}
@@ -0,0 +1,64 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestReserveIdempotencyKey() {
+	t := suite.T()
+
+	var (
+		is = postgresql.NewIdempotencyStore(suite.db)
+		r  = app.IdempotencyRecord{Scope: "principal:buyer", Key: uuid.New().String(), CommandName: app.PurchaseProductName, Fingerprint: "fingerprint"}
+	)
+	reserved, err := is.Reserve(context.Background(), r)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	reserved, err = is.Reserve(context.Background(), app.IdempotencyRecord{Scope: r.Scope, Key: r.Key, CommandName: r.CommandName, Fingerprint: "another"})
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	// The same key is another key for another client
+	reserved, err = is.Reserve(context.Background(), app.IdempotencyRecord{Scope: "ip:10.0.0.1", Key: r.Key, CommandName: r.CommandName, Fingerprint: "another"})
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	found, err := is.FindByKey(context.Background(), r.Scope, r.Key)
+	require.NoError(t, err)
+	require.Equal(t, r, found)
+
+	_, err = is.FindByKey(context.Background(), r.Scope, uuid.New().String())
+	require.ErrorIs(t, err, app.ErrNotFound)
+}
+
+func (suite *PostgreSQLTestSuite) TestReserveIdempotencyKeyRollback() {
+	t := suite.T()
+
+	var (
+		is        = postgresql.NewIdempotencyStore(suite.db)
+		r         = app.IdempotencyRecord{Key: uuid.New().String(), CommandName: app.PurchaseProductName, Fingerprint: "fingerprint"}
+		randomErr = errors.New("")
+	)
+	err := postgresql.NewTransactor(suite.db).WithinTx(context.Background(), func(ctx context.Context) error {
+		reserved, err := is.Reserve(ctx, r)
+		require.NoError(t, err)
+		require.True(t, reserved)
+		return randomErr
+	})
+	require.ErrorIs(t, err, randomErr)
+
+	// The key is released when the command fails, so it can be retried
+	_, err = is.FindByKey(context.Background(), r.Scope, r.Key)
+	require.ErrorIs(t, err, app.ErrNotFound)
+}
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN if not exists version INTEGER NOT NULL DEFAULT 1;
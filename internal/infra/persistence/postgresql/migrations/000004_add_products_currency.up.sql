@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN if not exists currency CHAR(3) NOT NULL DEFAULT 'EUR';
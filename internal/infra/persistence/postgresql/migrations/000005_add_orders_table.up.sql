@@ -0,0 +1,8 @@
+CREATE TABLE if not exists orders (
+	id uuid NOT NULL,
+	product_id uuid NOT NULL REFERENCES products (id),
+	price numeric(15,6) NOT NULL,
+	currency CHAR(3) NOT NULL,
+	purchased_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (id)
+);
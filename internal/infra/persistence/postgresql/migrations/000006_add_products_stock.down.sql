@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN if exists quantity;
+ALTER TABLE products DROP COLUMN if exists sold;
+ALTER TABLE products DROP COLUMN if exists stock;
@@ -0,0 +1,9 @@
+ALTER TABLE products ADD COLUMN if not exists stock INTEGER NOT NULL DEFAULT 1 CHECK (stock >= 0);
+ALTER TABLE products ADD COLUMN if not exists sold INTEGER NOT NULL DEFAULT 0 CHECK (sold >= 0);
+
+-- The existing products are unique products
+UPDATE products SET
+	stock = CASE WHEN available THEN 1 ELSE 0 END,
+	sold = CASE WHEN available THEN 0 ELSE 1 END;
+
+ALTER TABLE orders ADD COLUMN if not exists quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0);
@@ -0,0 +1 @@
+DROP TABLE if exists idempotency_keys;
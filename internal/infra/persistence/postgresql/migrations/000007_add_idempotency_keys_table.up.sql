@@ -0,0 +1,7 @@
+CREATE TABLE if not exists idempotency_keys (
+	key VARCHAR(255) NOT NULL,
+	command_name VARCHAR(100) NOT NULL,
+	fingerprint TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (key)
+);
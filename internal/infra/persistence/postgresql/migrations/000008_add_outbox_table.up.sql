@@ -0,0 +1,11 @@
+CREATE TABLE if not exists outbox (
+	id uuid NOT NULL,
+	seq BIGSERIAL NOT NULL,
+	aggregate_id uuid NOT NULL,
+	event_name VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	delivered_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (id)
+);
+CREATE INDEX if not exists outbox_undelivered_idx ON outbox (seq) WHERE delivered_at IS NULL;
@@ -0,0 +1,24 @@
+CREATE TABLE if not exists product_events (
+	product_id uuid NOT NULL,
+	version INTEGER NOT NULL CHECK (version > 0),
+	event_name VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL,
+	occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (product_id, version)
+);
+
+-- The history of the existing products starts with their current state
+INSERT INTO product_events (product_id, version, event_name, payload)
+SELECT id, 1, 'product.created', jsonb_build_object(
+	'ID', gen_random_uuid(),
+	'ProductName', name,
+	'Price', CASE WHEN price IS NULL THEN NULL ELSE jsonb_build_object('amount', price::text, 'currency', currency) END,
+	'Stock', stock + sold
+)
+FROM products
+ON CONFLICT DO NOTHING;
+
+INSERT INTO product_events (product_id, version, event_name, payload)
+SELECT id, 2, 'product.purchased', jsonb_build_object('ID', gen_random_uuid(), 'Quantity', sold)
+FROM products WHERE sold > 0
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP INDEX if exists products_search_idx;
+ALTER TABLE products DROP COLUMN if exists search;
@@ -0,0 +1,5 @@
+-- The search document of a product. It's generated by Postgres, so it's always up to date with the product.
+ALTER TABLE products ADD COLUMN if not exists search tsvector
+	GENERATED ALWAYS AS (to_tsvector('english', coalesce(name, ''))) STORED;
+
+CREATE INDEX if not exists products_search_idx ON products USING GIN (search);
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN if not exists archived BOOLEAN NOT NULL DEFAULT false;
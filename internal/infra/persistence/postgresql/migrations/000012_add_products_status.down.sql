@@ -0,0 +1,8 @@
+ALTER TABLE products ADD COLUMN if not exists archived BOOLEAN NOT NULL DEFAULT false;
+UPDATE products SET archived = status = 'archived';
+
+ALTER TABLE products ALTER COLUMN available DROP EXPRESSION;
+ALTER TABLE products ALTER COLUMN available SET DEFAULT false;
+
+ALTER TABLE products DROP COLUMN if exists status;
+DROP TYPE if exists product_status;
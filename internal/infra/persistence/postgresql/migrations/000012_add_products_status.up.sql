@@ -0,0 +1,16 @@
+CREATE TYPE product_status AS ENUM ('draft', 'published', 'reserved', 'sold', 'archived');
+
+ALTER TABLE products ADD COLUMN if not exists status product_status NOT NULL DEFAULT 'draft';
+
+-- The products not available are sold when they have no stock left, and withdrawn from sale otherwise
+UPDATE products SET status = (CASE
+	WHEN archived THEN 'archived'
+	WHEN available THEN 'published'
+	WHEN stock = 0 THEN 'sold'
+	ELSE 'draft'
+END)::product_status;
+
+-- available is kept for the queries that don't know the status, but it's derived from it
+ALTER TABLE products DROP COLUMN if exists available;
+ALTER TABLE products ADD COLUMN available BOOLEAN GENERATED ALWAYS AS (status = 'published') STORED;
+ALTER TABLE products DROP COLUMN if exists archived;
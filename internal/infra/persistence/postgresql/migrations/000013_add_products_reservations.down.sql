@@ -0,0 +1,7 @@
+DROP INDEX if exists products_reserved_until_idx;
+
+-- The products can't be held anymore, so the reserved ones are put back on sale
+UPDATE products SET status = 'published' WHERE status = 'reserved';
+
+ALTER TABLE products DROP COLUMN if exists reserved_until;
+ALTER TABLE products DROP COLUMN if exists reserved_by;
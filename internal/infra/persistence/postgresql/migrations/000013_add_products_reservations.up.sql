@@ -0,0 +1,6 @@
+-- The hold of a reserved product. reserved_by is the hash of the buyer or session token the product is held for
+ALTER TABLE products ADD COLUMN if not exists reserved_by VARCHAR(64);
+ALTER TABLE products ADD COLUMN if not exists reserved_until TIMESTAMP WITH TIME ZONE;
+
+-- The sweeper looks for the expired reservations
+CREATE INDEX if not exists products_reserved_until_idx ON products (reserved_until) WHERE status = 'reserved';
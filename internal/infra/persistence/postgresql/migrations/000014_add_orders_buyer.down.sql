@@ -0,0 +1,2 @@
+DROP INDEX if exists orders_buyer_id_idx;
+ALTER TABLE orders DROP COLUMN if exists buyer_id;
@@ -0,0 +1,4 @@
+-- The ID of the authenticated buyer who purchased the product. It's NULL for the anonymous purchases
+ALTER TABLE orders ADD COLUMN if not exists buyer_id VARCHAR(255);
+
+CREATE INDEX if not exists orders_buyer_id_idx ON orders (buyer_id);
@@ -0,0 +1,14 @@
+-- The keys of the machine clients. Only the SHA-256 hash of each key is stored
+CREATE TABLE if not exists api_keys (
+	id uuid NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	key_hash CHAR(64) NOT NULL,
+	scopes TEXT[] NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE,
+	last_used_at TIMESTAMP WITH TIME ZONE,
+	revoked_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX if not exists api_keys_key_hash_idx ON api_keys (key_hash);
@@ -0,0 +1 @@
+DROP TABLE if exists rate_limit_buckets;
@@ -0,0 +1,10 @@
+-- The token buckets of the rate limiter, shared by all the replicas of the service
+CREATE TABLE if not exists rate_limit_buckets (
+	key VARCHAR(300) NOT NULL,
+	tokens DOUBLE PRECISION NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	full_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (key)
+);
+
+CREATE INDEX if not exists rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
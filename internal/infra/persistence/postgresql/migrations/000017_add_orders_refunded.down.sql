@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP CONSTRAINT if exists orders_refunded_check;
+ALTER TABLE orders DROP COLUMN if exists refunded_at;
+ALTER TABLE orders DROP COLUMN if exists refunded;
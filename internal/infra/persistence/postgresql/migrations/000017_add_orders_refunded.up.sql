@@ -0,0 +1,4 @@
+-- The units of each order that have been refunded, so they can't be refunded twice
+ALTER TABLE orders ADD COLUMN if not exists refunded INT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN if not exists refunded_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD CONSTRAINT orders_refunded_check CHECK (refunded >= 0 AND refunded <= quantity);
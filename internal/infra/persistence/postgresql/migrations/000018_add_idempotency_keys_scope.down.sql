@@ -0,0 +1,4 @@
+DELETE FROM idempotency_keys WHERE scope <> '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT if exists idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+ALTER TABLE idempotency_keys DROP COLUMN if exists scope;
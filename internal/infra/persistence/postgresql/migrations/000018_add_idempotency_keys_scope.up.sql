@@ -0,0 +1,5 @@
+-- The idempotency keys are scoped by client, so the same key sent by two clients is two different keys.
+-- The keys stored before are kept in the empty scope
+ALTER TABLE idempotency_keys ADD COLUMN if not exists scope VARCHAR(300) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT if exists idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (scope, key);
@@ -0,0 +1 @@
+DROP INDEX if exists outbox_seq_idx;
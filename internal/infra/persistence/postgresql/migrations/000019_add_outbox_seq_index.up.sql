@@ -0,0 +1,2 @@
+-- The event streams are resumed from a position of the outbox
+CREATE UNIQUE INDEX if not exists outbox_seq_idx ON outbox (seq);
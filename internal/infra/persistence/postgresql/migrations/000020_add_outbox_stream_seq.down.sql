@@ -0,0 +1,2 @@
+DROP INDEX if exists outbox_stream_seq_idx;
+ALTER TABLE outbox DROP COLUMN if exists stream_seq;
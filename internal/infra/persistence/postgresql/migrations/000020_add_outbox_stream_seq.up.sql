@@ -0,0 +1,6 @@
+-- The position of a message in the stream of the delivered ones. It's given when the message is delivered,
+-- one transaction at a time, so the positions are committed in order, unlike the seq given when it's stored
+ALTER TABLE outbox ADD COLUMN if not exists stream_seq BIGINT;
+-- The streams resumed from the seq of a message delivered before keep resuming from the same position
+UPDATE outbox SET stream_seq = seq WHERE delivered_at IS NOT NULL;
+CREATE UNIQUE INDEX if not exists outbox_stream_seq_idx ON outbox (stream_seq);
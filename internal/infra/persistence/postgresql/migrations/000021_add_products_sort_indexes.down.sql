@@ -0,0 +1,3 @@
+DROP INDEX if exists products_stock_id_idx;
+DROP INDEX if exists products_price_id_idx;
+DROP INDEX if exists products_name_id_idx;
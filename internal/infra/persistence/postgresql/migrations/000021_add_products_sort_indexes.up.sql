@@ -0,0 +1,4 @@
+-- The pages of the products sorted by a field are found from their cursors through these indexes, with the ID as the tiebreaker
+CREATE INDEX if not exists products_name_id_idx ON products (name, id);
+CREATE INDEX if not exists products_price_id_idx ON products (price, id);
+CREATE INDEX if not exists products_stock_id_idx ON products (stock, id);
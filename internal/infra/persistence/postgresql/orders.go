@@ -0,0 +1,129 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// OrdersRepository is a repository
+type OrdersRepository struct {
+	db *sql.DB
+}
+
+// NewOrdersRepository is a constructor
+func NewOrdersRepository(db *sql.DB) OrdersRepository {
+	return OrdersRepository{db: db}
+}
+
+const ordersSelect = "SELECT id,product_id,buyer_id,price,currency,quantity,purchased_at,refunded,refunded_at FROM orders"
+
+// FindByID is a finder
+func (or OrdersRepository) FindByID(ctx context.Context, ID uuid.UUID) (domain.Order, error) {
+	o, err := scanOrder(conn(ctx, or.db).QueryRowContext(ctx, ordersSelect+" WHERE id=$1", ID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Order{}, app.ErrNotFound
+		}
+		return domain.Order{}, err
+	}
+	return o, nil
+}
+
+// FindAll is a finder. The most recent orders come first
+func (or OrdersRepository) FindAll(ctx context.Context) ([]domain.Order, error) {
+	rows, err := conn(ctx, or.db).QueryContext(ctx, ordersSelect+" ORDER BY purchased_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Insert persists a new order
+func (or OrdersRepository) Insert(ctx context.Context, o domain.Order) error {
+	// The anonymous purchases have no buyer
+	buyerID := sql.NullString{String: o.BuyerID(), Valid: o.BuyerID() != ""}
+	_, err := conn(ctx, or.db).ExecContext(ctx,
+		"INSERT INTO orders (id, product_id, buyer_id, price, currency, quantity, purchased_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		o.ID(), o.ProductID(), buyerID, o.Price().Amount(), o.Price().Currency(), o.Quantity(), o.PurchasedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert order: %w", err)
+	}
+	return nil
+}
+
+// RecordRefund implements the app.OrdersRepository interface
+func (or OrdersRepository) RecordRefund(ctx context.Context, ID uuid.UUID, quantity int, refundedAt time.Time) error {
+	result, err := conn(ctx, or.db).ExecContext(ctx,
+		"UPDATE orders SET refunded=refunded+$1, refunded_at=$2 WHERE id=$3 AND refunded+$1 <= quantity",
+		quantity, refundedAt, ID,
+	)
+	if err != nil {
+		return fmt.Errorf("record order refund: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("record order refund: rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		if _, err := or.FindByID(ctx, ID); err != nil {
+			return err
+		}
+		return domain.ErrRefundExceedsOrder
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(s scanner) (domain.Order, error) {
+	var (
+		id          uuid.UUID
+		productID   uuid.UUID
+		buyerID     sql.NullString
+		amount      string
+		currency    string
+		quantity    int
+		purchasedAt time.Time
+		refunded    int
+		refundedAt  sql.NullTime
+	)
+	if err := s.Scan(&id, &productID, &buyerID, &amount, &currency, &quantity, &purchasedAt, &refunded, &refundedAt); err != nil {
+		return domain.Order{}, err
+	}
+
+	price, err := domain.ParseMoney(amount, currency)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("order price: %w", err)
+	}
+
+	var o domain.Order
+	o.Hydrate(id, productID, buyerID.String, price, quantity, purchasedAt, refunded, nullTime(refundedAt))
+	return o, nil
+}
@@ -0,0 +1,140 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/helpers"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestInsertOrder() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	productID := uuid.New()
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status) VALUES ($1, $2, $3, $4)",
+		productID,
+		"product55",
+		"10.99",
+		domain.StatusDraft,
+	)
+	require.NoError(t, err)
+
+	var (
+		or          = postgresql.NewOrdersRepository(suite.db)
+		purchasedAt = time.Now().UTC().Truncate(time.Microsecond)
+		order       = domain.NewOrder(uuid.New(), productID, "buyer", fixtures.Money("10.99"), 2, purchasedAt)
+	)
+	require.NoError(t, or.Insert(context.Background(), order))
+
+	found, err := or.FindByID(context.Background(), order.ID())
+	require.NoError(t, err)
+	require.Equal(t, productID, found.ProductID())
+	require.Equal(t, "buyer", found.BuyerID())
+	require.True(t, order.Price().Equal(found.Price()))
+	require.True(t, purchasedAt.Equal(found.PurchasedAt()))
+
+	all, err := or.FindAll(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	_, err = or.FindByID(context.Background(), uuid.New())
+	require.ErrorIs(t, err, app.ErrNotFound)
+
+	// The refunded units can't exceed the units of the order
+	refundedAt := purchasedAt.Add(time.Minute)
+	require.NoError(t, or.RecordRefund(context.Background(), order.ID(), 1, refundedAt))
+	require.ErrorIs(t, or.RecordRefund(context.Background(), order.ID(), 2, refundedAt), domain.ErrRefundExceedsOrder)
+	require.NoError(t, or.RecordRefund(context.Background(), order.ID(), 1, refundedAt))
+	require.ErrorIs(t, or.RecordRefund(context.Background(), order.ID(), 1, refundedAt), domain.ErrRefundExceedsOrder)
+	require.ErrorIs(t, or.RecordRefund(context.Background(), uuid.New(), 1, refundedAt), app.ErrNotFound)
+
+	found, err = or.FindByID(context.Background(), order.ID())
+	require.NoError(t, err)
+	require.Equal(t, 2, found.Refunded())
+	require.True(t, refundedAt.Equal(*found.RefundedAt()))
+}
+
+func (suite *PostgreSQLTestSuite) TestTransactorRollback() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		id    = uuid.New()
+		name  = "product66"
+		price = "1.1"
+	)
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status) VALUES ($1, $2, $3, $4)",
+		id,
+		name,
+		price,
+		domain.StatusPublished,
+	)
+	require.NoError(t, err)
+
+	var (
+		pr           = postgresql.NewProductsRepository(suite.db)
+		productPrice = fixtures.Money(price)
+		p            = fixtures.Product{ID: &id, Name: &name, Available: helpers.BoolPtr(false), Price: &productPrice}.Build()
+		randomErr    = errors.New("")
+	)
+	err = postgresql.NewTransactor(suite.db).WithinTx(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, pr.UpdateStock(ctx, p))
+		return randomErr
+	})
+	require.ErrorIs(t, err, randomErr)
+
+	found, err := pr.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, found.IsAvailable())
+}
+
+func (suite *PostgreSQLTestSuite) TestCheckoutRollback() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		availableID = uuid.New()
+		purchasedID = uuid.New()
+	)
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status, stock, sold) VALUES ($1, $2, $3, 'published', 1, 0), ($4, $5, $6, 'sold', 0, 1)",
+		availableID, "product88", "1.1",
+		purchasedID, "product99", "2.2",
+	)
+	require.NoError(t, err)
+
+	var (
+		pr = postgresql.NewProductsRepository(suite.db)
+		ch = app.NewCheckout(pr, postgresql.NewOrdersRepository(suite.db), postgresql.NewTransactor(suite.db))
+	)
+	_, err = ch.Handle(context.Background(), app.CheckoutCmd{Items: []app.CheckoutItem{
+		{ProductID: availableID},
+		{ProductID: purchasedID},
+	}})
+	var checkoutErr app.CheckoutError
+	require.ErrorAs(t, err, &checkoutErr)
+	require.Len(t, checkoutErr.Failures, 1)
+	require.ErrorIs(t, checkoutErr.Failures[0].Err, domain.ErrProductPurchased)
+
+	found, err := pr.FindByID(context.Background(), availableID)
+	require.NoError(t, err)
+	require.True(t, found.IsAvailable())
+
+	var orders int
+	require.NoError(t, suite.db.QueryRow("SELECT count(*) FROM orders WHERE product_id=$1", availableID).Scan(&orders))
+	require.Zero(t, orders)
+}
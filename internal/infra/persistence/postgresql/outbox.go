@@ -0,0 +1,113 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"theskyinflames/graphql-challenge/internal/app"
+)
+
+// Outbox is a repository
+type Outbox struct {
+	db *sql.DB
+}
+
+// NewOutbox is a constructor
+func NewOutbox(db *sql.DB) Outbox {
+	return Outbox{db: db}
+}
+
+// Save persists the messages
+func (ob Outbox) Save(ctx context.Context, msgs []app.OutboxMessage) error {
+	for _, m := range msgs {
+		_, err := conn(ctx, ob.db).ExecContext(ctx,
+			"INSERT INTO outbox (id, aggregate_id, event_name, payload) VALUES ($1, $2, $3, $4)",
+			m.ID, m.AggregateID, m.EventName, m.Payload,
+		)
+		if err != nil {
+			return fmt.Errorf("insert outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+const outboxSelect = "SELECT id,aggregate_id,event_name,payload,stream_seq,created_at FROM outbox"
+
+// outboxDeliveryLock is the key of the advisory lock held by the transaction that delivers the messages
+const outboxDeliveryLock = 1000
+
+// Deliver puts the oldest messages not delivered yet in the stream, in the same order they were saved.
+// The transaction in course holds a lock while it delivers them, so the positions in the stream are given
+// by one transaction at a time, and they're committed in order. When another transaction holds it, nothing is delivered.
+func (ob Outbox) Deliver(ctx context.Context, limit int) (int, error) {
+	var locked bool
+	err := conn(ctx, ob.db).QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxDeliveryLock).Scan(&locked)
+	if err != nil {
+		return 0, fmt.Errorf("deliver outbox messages: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	result, err := conn(ctx, ob.db).ExecContext(ctx,
+		`WITH pending AS (
+			SELECT id, row_number() OVER (ORDER BY seq) AS n FROM outbox WHERE delivered_at IS NULL ORDER BY seq LIMIT $1
+		), last AS (
+			SELECT coalesce(max(stream_seq), 0) AS stream_seq FROM outbox
+		)
+		UPDATE outbox SET stream_seq = last.stream_seq + pending.n, delivered_at = now()
+		FROM pending, last WHERE outbox.id = pending.id`,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deliver outbox messages: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deliver outbox messages: rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// FindAfter is a finder. The messages are returned in the order they were delivered
+func (ob Outbox) FindAfter(ctx context.Context, seq int64, limit int) ([]app.OutboxMessage, error) {
+	rows, err := conn(ctx, ob.db).QueryContext(ctx,
+		outboxSelect+" WHERE stream_seq > $1 ORDER BY stream_seq LIMIT $2",
+		seq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows)
+}
+
+// LastSeq is a finder
+func (ob Outbox) LastSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := conn(ctx, ob.db).QueryRowContext(ctx, "SELECT coalesce(max(stream_seq), 0) FROM outbox").Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("find last outbox message: %w", err)
+	}
+	return seq, nil
+}
+
+func scanOutboxMessages(rows *sql.Rows) ([]app.OutboxMessage, error) {
+	defer rows.Close()
+
+	var msgs []app.OutboxMessage
+	for rows.Next() {
+		var m app.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.AggregateID, &m.EventName, &m.Payload, &m.Seq, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,107 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestOutbox() {
+	t := suite.T()
+
+	p := fixtures.Product{}.Build()
+	purchased, err := app.NewOutboxMessage(domain.NewProductPurchasedEvent(p, 1))
+	require.NoError(t, err)
+	refunded, err := app.NewOutboxMessage(domain.NewProductRefundedEvent(p, 1))
+	require.NoError(t, err)
+
+	var (
+		ob = postgresql.NewOutbox(suite.db)
+		tx = postgresql.NewTransactor(suite.db)
+	)
+	last, err := ob.LastSeq(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, ob.Save(context.Background(), []app.OutboxMessage{purchased, refunded}))
+
+	// The messages are not in the stream until they're delivered
+	msgs, err := ob.FindAfter(context.Background(), last, 1000)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	// While another transaction is delivering the messages, none is delivered
+	err = tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		_, err := ob.Deliver(ctx, 0)
+		require.NoError(t, err)
+
+		delivered, err := ob.Deliver(context.Background(), 1000)
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		delivered, err := ob.Deliver(ctx, 1000)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, delivered, 2)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The delivered messages are found after a position, in the order they were saved
+	msgs, err = ob.FindAfter(context.Background(), last, 1000)
+	require.NoError(t, err)
+	var found []app.OutboxMessage
+	for _, m := range msgs {
+		if m.ID == purchased.ID || m.ID == refunded.ID {
+			found = append(found, m)
+		}
+	}
+	require.Len(t, found, 2)
+	require.Equal(t, purchased.ID, found[0].ID)
+	require.Equal(t, refunded.ID, found[1].ID)
+	require.Greater(t, found[0].Seq, last)
+	require.Greater(t, found[1].Seq, found[0].Seq)
+	require.False(t, found[0].CreatedAt.IsZero())
+
+	after, err := ob.FindAfter(context.Background(), found[0].Seq, 1)
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	require.Equal(t, found[1].Seq, after[0].Seq)
+
+	last, err = ob.LastSeq(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, last, found[1].Seq)
+}
+
+func (suite *PostgreSQLTestSuite) TestOutboxRollback() {
+	t := suite.T()
+
+	m, err := app.NewOutboxMessage(domain.NewProductPurchasedEvent(fixtures.Product{}.Build(), 1))
+	require.NoError(t, err)
+
+	var (
+		ob        = postgresql.NewOutbox(suite.db)
+		randomErr = errors.New("")
+	)
+	err = postgresql.NewTransactor(suite.db).WithinTx(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, ob.Save(ctx, []app.OutboxMessage{m}))
+		return randomErr
+	})
+	require.ErrorIs(t, err, randomErr)
+
+	// The events of a failed command are not stored
+	var stored bool
+	err = suite.db.QueryRow("SELECT EXISTS (SELECT 1 FROM outbox WHERE id=$1)", m.ID).Scan(&stored)
+	require.NoError(t, err)
+	require.False(t, stored)
+}
@@ -0,0 +1,81 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+
+	"theskyinflames/graphql-challenge/internal/app"
+)
+
+// pageClauses returns the WHERE clause and the ORDER BY and LIMIT clauses of a keyset pagination on the products
+// that match the filter of the request, sorted by its order, and their args.
+// The products after and before the cursors are selected by their values of the sort column and their IDs, so the page
+// is found through the index of the column and the ID no matter how far it is from the first one.
+// When the page is taken from the end, the rows are sorted in reverse order, so they must be reversed.
+func pageClauses(req app.ProductsPageRequest) (string, string, []interface{}, error) {
+	where, _, args, err := filterClauses(req.Filter, req.Order)
+	if err != nil {
+		return "", "", nil, err
+	}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var (
+		column     = productOrderColumns[req.Order.Field]
+		desc       = req.Order.Direction == app.OrderDesc
+		conditions []string
+	)
+	if req.After != nil {
+		conditions = append(conditions, keysetCondition(column, desc, *req.After, true, arg))
+	}
+	if req.Before != nil {
+		conditions = append(conditions, keysetCondition(column, desc, *req.Before, false, arg))
+	}
+	if len(conditions) > 0 {
+		if where == "" {
+			where = " WHERE "
+		} else {
+			where += " AND "
+		}
+		where += strings.Join(conditions, " AND ")
+	}
+
+	orderBy := fmt.Sprintf(" ORDER BY %s LIMIT %s", orderTerms(column, desc, req.FromEnd), arg(req.Limit))
+	return where, orderBy, args, nil
+}
+
+// keysetCondition returns the condition of the products sorted after the cursor, or before it when after is false.
+// The products are sorted by the column, with the products without value last, and then by ID.
+func keysetCondition(column string, desc bool, c app.ProductCursor, after bool, arg func(interface{}) string) string {
+	greater, less := ">", "<"
+	if desc {
+		greater, less = less, greater
+	}
+	ID := arg(c.ID)
+	if column == "id" {
+		if after {
+			return fmt.Sprintf("id %s %s", greater, ID)
+		}
+		return fmt.Sprintf("id %s %s", less, ID)
+	}
+
+	if c.Key == nil {
+		if after {
+			return fmt.Sprintf("(%s IS NULL AND id > %s)", column, ID)
+		}
+		return fmt.Sprintf("(%s IS NOT NULL OR id < %s)", column, ID)
+	}
+	key := arg(*c.Key)
+	if after {
+		return fmt.Sprintf("(%[1]s IS NULL OR %[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND id > %[4]s))", column, greater, key, ID)
+	}
+	return fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND id < %[4]s))", column, less, key, ID)
+}
+
+func reverse[T any](s []T) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
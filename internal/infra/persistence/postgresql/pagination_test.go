@@ -0,0 +1,126 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestFindPage() {
+	t := suite.T()
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": postgresql.NewEventSourcedProductsRepository(suite.db),
+	}
+	for name, pr := range repositories {
+		all, err := pr.FindAll(context.Background())
+		require.NoError(t, err, name)
+		require.True(t, len(all) > 2, name)
+
+		first, err := pr.FindPage(context.Background(), app.ProductsPageRequest{Limit: 2})
+		require.NoError(t, err, name)
+		require.Len(t, first, 2, name)
+		requireAscending(suite, first)
+
+		next, err := pr.FindPage(context.Background(), app.ProductsPageRequest{After: &app.ProductCursor{ID: first[1].ID()}, Limit: 2})
+		require.NoError(t, err, name)
+		require.NotEmpty(t, next, name)
+		require.True(t, next[0].ID().String() > first[1].ID().String(), name)
+		requireAscending(suite, next)
+
+		previous, err := pr.FindPage(context.Background(), app.ProductsPageRequest{Before: &app.ProductCursor{ID: next[0].ID()}, Limit: 1, FromEnd: true})
+		require.NoError(t, err, name)
+		require.Len(t, previous, 1, name)
+		require.Equal(t, first[1].ID(), previous[0].ID(), name)
+	}
+}
+
+func (suite *PostgreSQLTestSuite) TestFindPageSorted() {
+	t := suite.T()
+
+	// The products have a token in their names that no other product has, so the filter only matches them.
+	// Two of them have the same price, and one of them has no price, so it's sorted last
+	var (
+		es    = postgresql.NewEventSourcedProductsRepository(suite.db)
+		token = uuid.NewString()[:8]
+	)
+	unpriced, err := domain.NewProduct(uuid.New(), "Unpriced_"+token, nil, 1)
+	require.NoError(t, err)
+	for _, p := range []domain.Product{
+		fixtures.NewProduct("A_"+token, "2", 1),
+		fixtures.NewProduct("B_"+token, "2", 1),
+		fixtures.NewProduct("C_"+token, "1", 1),
+		unpriced,
+	} {
+		require.NoError(t, es.Insert(context.Background(), p))
+	}
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": es,
+	}
+	for name, pr := range repositories {
+		for _, order := range []app.ProductOrder{
+			{Field: app.ProductOrderByPrice, Direction: app.OrderDesc},
+			{Field: app.ProductOrderByPrice},
+			{Field: app.ProductOrderByName},
+			{Field: app.ProductOrderByID, Direction: app.OrderDesc},
+		} {
+			filter := app.ProductFilter{NameContains: token}
+			all, err := pr.FindMatching(context.Background(), filter, order)
+			require.NoError(t, err, name)
+			require.Len(t, all, 4, name)
+
+			// The pages of one product walk the products in the same order, forward and backward
+			var (
+				forward  []domain.Product
+				backward []domain.Product
+				after    *app.ProductCursor
+				before   *app.ProductCursor
+			)
+			for i := 0; i < len(all)+1; i++ {
+				page, err := pr.FindPage(context.Background(), app.ProductsPageRequest{Filter: filter, Order: order, After: after, Limit: 1})
+				require.NoError(t, err, name)
+				if len(page) == 0 {
+					break
+				}
+				forward = append(forward, page...)
+				c := app.NewProductCursor(page[0], order.Field)
+				after = &c
+
+				page, err = pr.FindPage(context.Background(), app.ProductsPageRequest{Filter: filter, Order: order, Before: before, Limit: 1, FromEnd: true})
+				require.NoError(t, err, name)
+				require.Len(t, page, 1, name)
+				backward = append([]domain.Product{page[0]}, backward...)
+				c = app.NewProductCursor(page[0], order.Field)
+				before = &c
+			}
+			require.Equal(t, ids(all), ids(forward), name, order)
+			require.Equal(t, ids(all), ids(backward), name, order)
+		}
+	}
+}
+
+func ids(products []domain.Product) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, p := range products {
+		ids = append(ids, p.ID())
+	}
+	return ids
+}
+
+func requireAscending(suite *PostgreSQLTestSuite, products []domain.Product) {
+	for i := 1; i < len(products); i++ {
+		require.True(suite.T(), products[i-1].ID().String() < products[i].ID().String())
+	}
+}
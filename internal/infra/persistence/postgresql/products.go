@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"theskyinflames/graphql-challenge/internal/app"
 	"theskyinflames/graphql-challenge/internal/domain"
@@ -22,71 +23,109 @@ func NewProductsRepository(db *sql.DB) ProductsRepository {
 	return ProductsRepository{db: db}
 }
 
+const productsSelect = "SELECT id,name,status,price,currency,reserved_by,reserved_until,stock,sold,version FROM products"
+
 // FindByID is a finder
 func (pr ProductsRepository) FindByID(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
-	stmt, err := pr.db.Prepare("SELECT id,name,available,price FROM products WHERE id=$1")
+	p, err := scanProduct(conn(ctx, pr.db).QueryRowContext(ctx, productsSelect+" WHERE id=$1", ID))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, app.ErrNotFound
+		}
 		return domain.Product{}, err
 	}
+	return p, nil
+}
 
-	var (
-		foundID   uuid.UUID
-		name      string
-		available bool
-		optPrice  sql.NullFloat64
-	)
-
-	err = stmt.QueryRow(ID).Scan(&foundID, &name, &available, &optPrice)
+// FindByIDForUpdate is a finder. The row of the product is locked until the transaction in course ends
+func (pr ProductsRepository) FindByIDForUpdate(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
+	p, err := scanProduct(conn(ctx, pr.db).QueryRowContext(ctx, productsSelect+" WHERE id=$1 FOR UPDATE", ID))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Product{}, app.ErrNotFound
 		}
 		return domain.Product{}, err
 	}
-
-	var p domain.Product
-	p.Hydrate(foundID, name, available, price(optPrice))
 	return p, nil
 }
 
 // FindAll is a finder
 func (pr ProductsRepository) FindAll(ctx context.Context) ([]domain.Product, error) {
-	rows, err := pr.db.QueryContext(ctx, "SELECT id,name,available,price FROM products")
+	rows, err := conn(ctx, pr.db).QueryContext(ctx, productsSelect)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []domain.Product
-
-	for rows.Next() {
-		var (
-			foundID   uuid.UUID
-			name      string
-			available bool
-			optPrice  sql.NullFloat64
-		)
-
-		err = rows.Scan(&foundID, &name, &available, &optPrice)
-		if err != nil {
-			return nil, err
-		}
+	return scanProducts(rows)
+}
 
-		var p domain.Product
-		p.Hydrate(foundID, name, available, price(optPrice))
-		products = append(products, p)
+// FindMatching is a finder
+func (pr ProductsRepository) FindMatching(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error) {
+	where, orderBy, args, err := filterClauses(filter, order)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, pr.db).QueryContext(ctx, productsSelect+where+" ORDER BY "+orderBy, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	return scanProducts(rows)
+}
 
-	if err = rows.Err(); err != nil {
+// Search is a finder. The products are ranked by the full text search of their names
+func (pr ProductsRepository) Search(ctx context.Context, req app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+	return searchProducts(ctx, pr.db, req, pr.FindMatching)
+}
+
+// FindPage is a finder. It's a keyset pagination on the sort key, so the page is found through the indexes
+// no matter how far it is from the first one.
+func (pr ProductsRepository) FindPage(ctx context.Context, req app.ProductsPageRequest) ([]domain.Product, error) {
+	where, orderBy, args, err := pageClauses(req)
+	if err != nil {
 		return nil, err
 	}
+	rows, err := conn(ctx, pr.db).QueryContext(ctx, productsSelect+where+orderBy, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	products, err := scanProducts(rows)
+	if err != nil {
+		return nil, err
+	}
+	if req.FromEnd {
+		reverse(products)
+	}
 	return products, nil
 }
 
-// UpdateAvailable updates the available field of the product
-func (pr ProductsRepository) UpdateAvailable(ctx context.Context, p domain.Product) error {
-	result, err := pr.db.Exec("UPDATE products set available=$1 WHERE ID=$2", p.IsAvailable(), p.ID())
+// FindExpiredReservations is a finder. The products whose hold expired first are returned first
+func (pr ProductsRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]domain.Product, error) {
+	rows, err := conn(ctx, pr.db).QueryContext(ctx, productsSelect+expiredReservationsWhere+" ORDER BY reserved_until LIMIT $2", now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProducts(rows)
+}
+
+const expiredReservationsWhere = " WHERE status='reserved' AND reserved_until <= $1"
+
+// UpdateStock updates the availability and the stock of the product.
+// It's a conditional update that is only applied when the stored version is the one the product was read with.
+// So when several buyers try to purchase the same product at the same time, only one of them wins.
+func (pr ProductsRepository) UpdateStock(ctx context.Context, p domain.Product) error {
+	reservedBy, reservedUntil := reservationColumns(p)
+	result, err := conn(ctx, pr.db).ExecContext(ctx,
+		`UPDATE products SET status=$1, reserved_by=$2, reserved_until=$3, stock=$4, sold=$5, version=version+1
+		WHERE id=$6 AND version=$7`,
+		p.Status(), reservedBy, reservedUntil, p.Stock(), p.Sold(), p.ID(), p.Version(),
+	)
 	if err != nil {
 		return fmt.Errorf("update product: %w", err)
 	}
@@ -96,16 +135,154 @@ func (pr ProductsRepository) UpdateAvailable(ctx context.Context, p domain.Produ
 		return fmt.Errorf("update product: rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("update product: %w", errors.New("not found"))
+		return pr.updateNotApplied(ctx, p)
 	}
 
 	return nil
 }
 
-func price(opt sql.NullFloat64) float64 {
-	price := 0.0
-	if opt.Valid {
-		price = opt.Float64
+// Insert stores a new product. Its version is the first one
+func (pr ProductsRepository) Insert(ctx context.Context, p domain.Product) error {
+	amount, currency := priceColumns(p)
+	reservedBy, reservedUntil := reservationColumns(p)
+	result, err := conn(ctx, pr.db).ExecContext(ctx,
+		`INSERT INTO products (id, name, status, price, currency, reserved_by, reserved_until, stock, sold, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1)
+		ON CONFLICT (id) DO NOTHING`,
+		p.ID(), p.Name(), p.Status(), amount, currency, reservedBy, reservedUntil, p.Stock(), p.Sold(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("insert product: rows affected: %w", err)
 	}
-	return price
+	if rowsAffected == 0 {
+		return fmt.Errorf("insert product: %w", app.ErrAlreadyExists)
+	}
+	return nil
+}
+
+// Update updates all the fields of the product. As UpdateStock, it's a conditional update on the version of the product
+func (pr ProductsRepository) Update(ctx context.Context, p domain.Product) error {
+	amount, currency := priceColumns(p)
+	reservedBy, reservedUntil := reservationColumns(p)
+	result, err := conn(ctx, pr.db).ExecContext(ctx,
+		`UPDATE products SET name=$1, status=$2, price=$3, currency=$4, reserved_by=$5, reserved_until=$6,
+		stock=$7, sold=$8, version=version+1
+		WHERE id=$9 AND version=$10`,
+		p.Name(), p.Status(), amount, currency, reservedBy, reservedUntil, p.Stock(), p.Sold(), p.ID(), p.Version(),
+	)
+	if err != nil {
+		return fmt.Errorf("update product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update product: rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return pr.updateNotApplied(ctx, p)
+	}
+
+	return nil
+}
+
+// updateNotApplied finds out why a conditional update has not been applied
+func (pr ProductsRepository) updateNotApplied(ctx context.Context, p domain.Product) error {
+	var found bool
+	err := conn(ctx, pr.db).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id=$1)", p.ID()).Scan(&found)
+	if err != nil {
+		return fmt.Errorf("update product: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("update product: %w", app.ErrNotFound)
+	}
+	return fmt.Errorf("update product: %w", app.ErrVersionConflict)
+}
+
+// priceColumns returns the price and currency columns of the product. The price is NULL when the product has no price
+func priceColumns(p domain.Product) (sql.NullString, string) {
+	price, ok := p.Price()
+	if !ok {
+		return sql.NullString{}, domain.DefaultCurrency
+	}
+	return sql.NullString{String: price.Amount(), Valid: true}, price.Currency()
+}
+
+// reservationColumns returns the holder and expiry columns of the product. They're NULL when the product is not reserved
+func reservationColumns(p domain.Product) (sql.NullString, sql.NullTime) {
+	r, ok := p.Reservation()
+	if !ok {
+		return sql.NullString{}, sql.NullTime{}
+	}
+	return sql.NullString{String: r.Holder(), Valid: true}, sql.NullTime{Time: r.ExpiresAt(), Valid: true}
+}
+
+// reservation returns a nil reservation when it's NULL in DB
+func reservation(reservedBy sql.NullString, reservedUntil sql.NullTime) *domain.Reservation {
+	if !reservedBy.Valid || !reservedUntil.Valid {
+		return nil
+	}
+	var r domain.Reservation
+	r.Hydrate(reservedBy.String, reservedUntil.Time)
+	return &r
+}
+
+// price returns a nil price when it's NULL in DB
+func price(opt sql.NullString, currency string) (*domain.Money, error) {
+	if !opt.Valid {
+		return nil, nil
+	}
+	m, err := domain.ParseMoney(opt.String, currency)
+	if err != nil {
+		return nil, fmt.Errorf("product price: %w", err)
+	}
+	return &m, nil
+}
+
+func scanProducts(rows *sql.Rows) ([]domain.Product, error) {
+	var products []domain.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func scanProduct(s scanner) (domain.Product, error) {
+	var (
+		id            uuid.UUID
+		name          string
+		status        domain.Status
+		optPrice      sql.NullString
+		currency      string
+		reservedBy    sql.NullString
+		reservedUntil sql.NullTime
+		stock         int
+		sold          int
+		version       int
+	)
+	if err := s.Scan(&id, &name, &status, &optPrice, &currency, &reservedBy, &reservedUntil, &stock, &sold, &version); err != nil {
+		return domain.Product{}, err
+	}
+
+	productPrice, err := price(optPrice, currency)
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	var p domain.Product
+	p.Hydrate(id, name, status, productPrice, reservation(reservedBy, reservedUntil), stock, sold, version)
+	return p, nil
 }
@@ -0,0 +1,301 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/theskyinflames/cqrs-eda/pkg/events"
+)
+
+// EventSourcedProductsRepository is a repository. It implements the app.ProductsRepository interface
+// by storing each product as an append-only history of domain events.
+// The products table is kept as a projection of those histories, and the lists of products are read from it,
+// so they don't replay the history of each product they return.
+// The version of the projection of a product is the number of events of its history. It's checked each time
+// a product is replayed, so a history that doesn't rebuild its projection is reported instead of being served.
+type EventSourcedProductsRepository struct {
+	db         *sql.DB
+	tx         Transactor
+	projection ProductsRepository
+}
+
+// NewEventSourcedProductsRepository is a constructor
+func NewEventSourcedProductsRepository(db *sql.DB) EventSourcedProductsRepository {
+	return EventSourcedProductsRepository{db: db, tx: NewTransactor(db), projection: NewProductsRepository(db)}
+}
+
+// FindByID is a finder. It rebuilds the product by replaying its history
+func (pr EventSourcedProductsRepository) FindByID(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
+	rows, err := conn(ctx, pr.db).QueryContext(ctx,
+		"SELECT e.product_id,e.event_name,e.payload,p.version FROM product_events e JOIN products p ON p.id = e.product_id "+
+			"WHERE e.product_id=$1 ORDER BY e.version", ID,
+	)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	defer rows.Close()
+
+	products, err := replayProducts(rows)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if len(products) == 0 {
+		return domain.Product{}, app.ErrNotFound
+	}
+	return products[0], nil
+}
+
+// FindByIDForUpdate is a finder. The row of the product in the projection is locked until the transaction
+// in course ends, so the events of the concurrent changes are not appended meanwhile
+func (pr EventSourcedProductsRepository) FindByIDForUpdate(ctx context.Context, ID uuid.UUID) (domain.Product, error) {
+	var locked uuid.UUID
+	err := conn(ctx, pr.db).QueryRowContext(ctx, "SELECT id FROM products WHERE id=$1 FOR UPDATE", ID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, app.ErrNotFound
+		}
+		return domain.Product{}, err
+	}
+	return pr.FindByID(ctx, ID)
+}
+
+// FindAll is a finder. The products are read from the projection
+func (pr EventSourcedProductsRepository) FindAll(ctx context.Context) ([]domain.Product, error) {
+	return pr.projection.FindAll(ctx)
+}
+
+// FindMatching is a finder. The products are read from the projection
+func (pr EventSourcedProductsRepository) FindMatching(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error) {
+	return pr.projection.FindMatching(ctx, filter, order)
+}
+
+// Search is a finder. The products are ranked by the full text search of their names
+func (pr EventSourcedProductsRepository) Search(ctx context.Context, req app.ProductsSearchRequest) ([]app.ProductSearchHit, error) {
+	return searchProducts(ctx, pr.db, req, pr.FindMatching)
+}
+
+// FindPage is a finder. The products are read from the projection
+func (pr EventSourcedProductsRepository) FindPage(ctx context.Context, req app.ProductsPageRequest) ([]domain.Product, error) {
+	return pr.projection.FindPage(ctx, req)
+}
+
+// FindExpiredReservations is a finder. The products are read from the projection, whose version is the one
+// their events are appended after, so a hold released meanwhile is reported as a version conflict when it's expired
+func (pr EventSourcedProductsRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]domain.Product, error) {
+	return pr.projection.FindExpiredReservations(ctx, now, limit)
+}
+
+// UpdateStock appends the events recorded by the product to its history, and updates its projection.
+// The events are appended after the version the product was read with. If another event has already been
+// appended there, the product has been modified concurrently and ErrVersionConflict is returned.
+func (pr EventSourcedProductsRepository) UpdateStock(ctx context.Context, p domain.Product) error {
+	if p.Version() == 0 {
+		return fmt.Errorf("update product: %w", app.ErrNotFound)
+	}
+	return pr.tx.WithinTx(ctx, func(ctx context.Context) error {
+		version, err := pr.append(ctx, p.Version(), p.Events())
+		if err != nil {
+			return fmt.Errorf("update product: %w", err)
+		}
+		return pr.project(ctx, p, version)
+	})
+}
+
+// Update is the same as UpdateStock, since the events recorded by the product are what has changed
+func (pr EventSourcedProductsRepository) Update(ctx context.Context, p domain.Product) error {
+	return pr.UpdateStock(ctx, p)
+}
+
+// Insert stores the history of a new product. If there is already a history for it, ErrAlreadyExists is returned
+func (pr EventSourcedProductsRepository) Insert(ctx context.Context, p domain.Product) error {
+	return pr.tx.WithinTx(ctx, func(ctx context.Context) error {
+		version, err := pr.append(ctx, 0, p.Events())
+		if errors.Is(err, app.ErrVersionConflict) {
+			err = app.ErrAlreadyExists
+		}
+		if err != nil {
+			return fmt.Errorf("insert product: %w", err)
+		}
+		return pr.project(ctx, p, version)
+	})
+}
+
+// RebuildProjection replays the history of all the products and overwrites the products table with the result.
+// The histories are synced first, so the products stored without their events are kept as they are
+func (pr EventSourcedProductsRepository) RebuildProjection(ctx context.Context) error {
+	return pr.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := pr.SyncHistories(ctx); err != nil {
+			return err
+		}
+		rows, err := conn(ctx, pr.db).QueryContext(ctx,
+			"SELECT e.product_id,e.event_name,e.payload,p.version FROM product_events e JOIN products p ON p.id = e.product_id "+
+				"ORDER BY e.product_id, e.version",
+		)
+		if err != nil {
+			return err
+		}
+		products, err := replayProducts(rows)
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			if err := pr.project(ctx, p, p.Version()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SyncHistories appends a snapshot of the current state of their projection to the history of the products whose
+// projection is not at the version of their history. These are the products stored by the state-based repository,
+// which doesn't record their events, like the ones changed before switching to this repository.
+// The events already recorded are kept, and the history goes on from the snapshot.
+// It returns the number of histories synced.
+func (pr EventSourcedProductsRepository) SyncHistories(ctx context.Context) (int, error) {
+	var synced int
+	err := pr.tx.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, pr.db).QueryContext(ctx,
+			productsSelect+" WHERE version <> (SELECT count(*) FROM product_events WHERE product_id = products.id) FOR UPDATE",
+		)
+		if err != nil {
+			return fmt.Errorf("sync histories: %w", err)
+		}
+		products, err := scanProducts(rows)
+		_ = rows.Close()
+		if err != nil {
+			return fmt.Errorf("sync histories: %w", err)
+		}
+
+		for _, p := range products {
+			var version int
+			err := conn(ctx, pr.db).QueryRowContext(ctx, "SELECT count(*) FROM product_events WHERE product_id=$1", p.ID()).Scan(&version)
+			if err != nil {
+				return fmt.Errorf("sync histories: %w", err)
+			}
+			version, err = pr.append(ctx, version, []events.Event{domain.NewProductSnapshotTakenEvent(p)})
+			if err != nil {
+				return fmt.Errorf("sync histories: %w", err)
+			}
+			if err := pr.project(ctx, p, version); err != nil {
+				return err
+			}
+		}
+		synced = len(products)
+		return nil
+	})
+	return synced, err
+}
+
+// append appends the events to a history whose last version is the given one. It returns the new last version
+func (pr EventSourcedProductsRepository) append(ctx context.Context, version int, evs []events.Event) (int, error) {
+	for _, ev := range evs {
+		payload, err := app.EncodeEvent(ev)
+		if err != nil {
+			return 0, err
+		}
+
+		version++
+		// The conflict is not raised as an error to not abort the transaction, so the caller can retry within it
+		result, err := conn(ctx, pr.db).ExecContext(ctx,
+			"INSERT INTO product_events (product_id, version, event_name, payload) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+			ev.AggregateID(), version, ev.Name(), payload,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("append event %s: %w", ev.Name(), err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("append event %s: rows affected: %w", ev.Name(), err)
+		}
+		if rowsAffected == 0 {
+			return 0, app.ErrVersionConflict
+		}
+	}
+	return version, nil
+}
+
+// project stores the current state of the product in the products table
+func (pr EventSourcedProductsRepository) project(ctx context.Context, p domain.Product, version int) error {
+	amount, currency := priceColumns(p)
+	reservedBy, reservedUntil := reservationColumns(p)
+	_, err := conn(ctx, pr.db).ExecContext(ctx,
+		`INSERT INTO products (id, name, status, price, currency, reserved_by, reserved_until, stock, sold, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET name=$2, status=$3, price=$4, currency=$5, reserved_by=$6, reserved_until=$7,
+		stock=$8, sold=$9, version=$10`,
+		p.ID(), p.Name(), p.Status(), amount, currency, reservedBy, reservedUntil, p.Stock(), p.Sold(), version,
+	)
+	if err != nil {
+		return fmt.Errorf("project product: %w", err)
+	}
+	return nil
+}
+
+// replayProducts rebuilds the products from rows of events ordered by product and version.
+// Each row has the version of the projection of its product, which must be the number of events of its history.
+func replayProducts(rows *sql.Rows) ([]domain.Product, error) {
+	var (
+		products         []domain.Product
+		history          []events.Event
+		current          uuid.UUID
+		projectedVersion int
+	)
+	flush := func() error {
+		if len(history) == 0 {
+			return nil
+		}
+		p, err := domain.ReplayProduct(current, history)
+		if err != nil {
+			return err
+		}
+		if p.Version() != projectedVersion {
+			return fmt.Errorf("%w: product %s has %d events, but its projection is at version %d",
+				domain.ErrInvalidHistory, current, p.Version(), projectedVersion)
+		}
+		products = append(products, p)
+		history = nil
+		return nil
+	}
+
+	for rows.Next() {
+		var (
+			productID uuid.UUID
+			name      string
+			payload   []byte
+		)
+		if err := rows.Scan(&productID, &name, &payload, &projectedVersion); err != nil {
+			return nil, err
+		}
+		if productID != current {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = productID
+		}
+
+		ev, err := app.DecodeEvent(name, productID, payload)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
@@ -0,0 +1,167 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestEventSourcedProducts() {
+	t := suite.T()
+
+	var (
+		pr = postgresql.NewEventSourcedProductsRepository(suite.db)
+		p  = fixtures.NewProduct("product77", "7.7", 2)
+	)
+	require.NoError(t, pr.Insert(context.Background(), p))
+
+	found, err := pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, "product77", found.Name())
+	require.Equal(t, 2, found.Stock())
+	require.Equal(t, 1, found.Version())
+
+	require.NoError(t, found.Purchase(2))
+	require.NoError(t, pr.UpdateStock(context.Background(), found))
+
+	found, err = pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, 0, found.Stock())
+	require.Equal(t, 2, found.Sold())
+	require.False(t, found.IsAvailable())
+	require.Equal(t, 2, found.Version())
+
+	// The products table is kept as a projection of the history
+	projected, err := postgresql.NewProductsRepository(suite.db).FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, found.Stock(), projected.Stock())
+	require.Equal(t, found.Sold(), projected.Sold())
+	require.Equal(t, found.Version(), projected.Version())
+
+	all, err := pr.FindAll(context.Background())
+	require.NoError(t, err)
+	var inAll bool
+	for _, a := range all {
+		if a.ID() == p.ID() {
+			inAll = true
+			require.Equal(t, found.Version(), a.Version())
+		}
+	}
+	require.True(t, inAll)
+
+	_, err = pr.FindByID(context.Background(), uuid.New())
+	require.ErrorIs(t, err, app.ErrNotFound)
+}
+
+func (suite *PostgreSQLTestSuite) TestEventSourcedProductsWithStaleVersion() {
+	t := suite.T()
+
+	var (
+		pr = postgresql.NewEventSourcedProductsRepository(suite.db)
+		p  = fixtures.NewProduct("product88", "8.8", 2)
+	)
+	require.NoError(t, pr.Insert(context.Background(), p))
+
+	first, err := pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	second, err := pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+
+	require.NoError(t, first.Purchase(1))
+	require.NoError(t, pr.UpdateStock(context.Background(), first))
+
+	require.NoError(t, second.Purchase(1))
+	require.ErrorIs(t, pr.UpdateStock(context.Background(), second), app.ErrVersionConflict)
+
+	found, err := pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, 1, found.Stock())
+}
+
+func (suite *PostgreSQLTestSuite) TestEventSourcedProductsRebuildProjection() {
+	t := suite.T()
+
+	var (
+		pr = postgresql.NewEventSourcedProductsRepository(suite.db)
+		p  = fixtures.NewProduct("product99", "9.9", 3)
+	)
+	require.NoError(t, pr.Insert(context.Background(), p))
+
+	// The projection is modified out of the history
+	_, err := suite.db.Exec("UPDATE products SET stock=0, status='sold' WHERE id=$1", p.ID())
+	require.NoError(t, err)
+
+	require.NoError(t, pr.RebuildProjection(context.Background()))
+
+	projected, err := postgresql.NewProductsRepository(suite.db).FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, 3, projected.Stock())
+	require.True(t, projected.IsAvailable())
+}
+
+func (suite *PostgreSQLTestSuite) TestEventSourcedProductsSyncHistories() {
+	t := suite.T()
+
+	var (
+		pr    = postgresql.NewEventSourcedProductsRepository(suite.db)
+		state = postgresql.NewProductsRepository(suite.db)
+		p     = fixtures.NewProduct("product111", "1.1", 3)
+	)
+	require.NoError(t, pr.Insert(context.Background(), p))
+
+	// The product is changed by the state-based repository, which doesn't record its events
+	found, err := state.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.NoError(t, found.Purchase(1))
+	require.NoError(t, state.UpdateStock(context.Background(), found))
+	found, err = state.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.NoError(t, found.Unpublish())
+	require.NoError(t, state.Update(context.Background(), found))
+
+	// Its history doesn't rebuild its projection anymore, so it's not served
+	_, err = pr.FindByID(context.Background(), p.ID())
+	require.ErrorIs(t, err, domain.ErrInvalidHistory)
+
+	synced, err := pr.SyncHistories(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, synced, 1)
+
+	replayed, err := pr.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusDraft, replayed.Status())
+	require.Equal(t, 2, replayed.Stock())
+	require.Equal(t, 1, replayed.Sold())
+
+	projected, err := state.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	require.Equal(t, replayed.Version(), projected.Version())
+
+	// The events already recorded are kept, and the snapshot is appended after them
+	var names []string
+	rows, err := suite.db.Query("SELECT event_name FROM product_events WHERE product_id=$1 ORDER BY version", p.ID())
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.Equal(t, []string{domain.ProductCreatedEventName, domain.ProductSnapshotTakenEventName}, names)
+	require.Equal(t, len(names), replayed.Version())
+
+	// The histories in sync are kept
+	synced, err = pr.SyncHistories(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, synced)
+}
@@ -6,7 +6,11 @@ package postgresql_test
 import (
 	"context"
 	"database/sql"
+	"sync"
 	"testing"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
 	"theskyinflames/graphql-challenge/internal/fixtures"
 	"theskyinflames/graphql-challenge/internal/helpers"
 	"theskyinflames/graphql-challenge/internal/infra/persistence"
@@ -52,17 +56,17 @@ func (suite *PostgreSQLTestSuite) TestFindByID() {
 
 	// Insert fixture data into DB
 	var (
-		id        = uuid.New()
-		name      = "product1"
-		available = true
-		price     = 1.1
+		id     = uuid.New()
+		name   = "product1"
+		status = domain.StatusPublished
+		price  = "1.1"
 	)
 	_, err := suite.db.Exec(
-		"INSERT INTO products (id, name, price, available) VALUES ($1, $2, $3, $4)",
+		"INSERT INTO products (id, name, price, status) VALUES ($1, $2, $3, $4)",
 		id,
 		name,
 		price,
-		available,
+		status,
 	)
 	require.NoError(t, err)
 
@@ -71,7 +75,31 @@ func (suite *PostgreSQLTestSuite) TestFindByID() {
 	require.NoError(t, err)
 
 	require.Equal(t, id, found.ID())
-	require.Equal(t, available, found.IsAvailable())
+	require.Equal(t, status, found.Status())
+	require.True(t, found.IsAvailable())
+	foundPrice, priced := found.Price()
+	require.True(t, priced)
+	require.Equal(t, "1.10", foundPrice.Amount())
+	require.Equal(t, domain.DefaultCurrency, foundPrice.Currency())
+}
+
+func (suite *PostgreSQLTestSuite) TestFindByIDWithoutPrice() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		id   = uuid.New()
+		name = "product11"
+	)
+	_, err := suite.db.Exec("INSERT INTO products (id, name, price, status) VALUES ($1, $2, NULL, 'published')", id, name)
+	require.NoError(t, err)
+
+	pr := postgresql.NewProductsRepository(suite.db)
+	found, err := pr.FindByID(context.Background(), id)
+	require.NoError(t, err)
+
+	_, priced := found.Price()
+	require.False(t, priced)
 }
 
 func (suite *PostgreSQLTestSuite) TestFindAll() {
@@ -83,31 +111,197 @@ func (suite *PostgreSQLTestSuite) TestFindAll() {
 	require.True(t, len(found) > 0)
 }
 
-func (suite *PostgreSQLTestSuite) TestUpdateAvailable() {
+func (suite *PostgreSQLTestSuite) TestFindAllWithInvalidProduct() {
+	t := suite.T()
+
+	// A product stored before its name was validated
+	id := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO products (id, name, price, status) VALUES ($1, '', NULL, 'draft')", id)
+	require.NoError(t, err)
+
+	pr := postgresql.NewProductsRepository(suite.db)
+	found, err := pr.FindAll(context.Background())
+	require.NoError(t, err)
+
+	var loaded bool
+	for _, p := range found {
+		loaded = loaded || p.ID() == id
+	}
+	require.True(t, loaded)
+}
+
+func (suite *PostgreSQLTestSuite) TestUpdateStock() {
 	t := suite.T()
 
 	// Insert fixture data into DB
 	var (
-		id        = uuid.New()
-		name      = "product22"
-		available = false
-		price     = 1.1
+		id     = uuid.New()
+		name   = "product22"
+		status = domain.StatusDraft
+		price  = "1.1"
 	)
 	_, err := suite.db.Exec(
-		"INSERT INTO products (id, name, price, available) VALUES ($1, $2, $3, $4)",
+		"INSERT INTO products (id, name, price, status) VALUES ($1, $2, $3, $4)",
 		id,
 		name,
 		price,
-		available,
+		status,
 	)
 	require.NoError(t, err)
 
-	p := fixtures.Product{ID: &id, Name: &name, Available: helpers.BoolPtr(true), Price: &price}.Build()
+	productPrice := fixtures.Money(price)
+	p := fixtures.Product{ID: &id, Name: &name, Available: helpers.BoolPtr(true), Price: &productPrice}.Build()
 	pr := postgresql.NewProductsRepository(suite.db)
-	require.NoError(t, pr.UpdateAvailable(context.Background(), p))
+	require.NoError(t, pr.UpdateStock(context.Background(), p))
 
 	found, err := pr.FindByID(context.Background(), id)
 	require.NoError(t, err)
 
 	require.True(t, found.IsAvailable())
 }
+
+func (suite *PostgreSQLTestSuite) TestUpdateStockWithStaleVersion() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		id      = uuid.New()
+		name    = "product44"
+		status  = domain.StatusDraft
+		price   = "1.1"
+		version = 2
+	)
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status, version) VALUES ($1, $2, $3, $4, $5)",
+		id,
+		name,
+		price,
+		status,
+		version,
+	)
+	require.NoError(t, err)
+
+	productPrice := fixtures.Money(price)
+	p := fixtures.Product{ID: &id, Name: &name, Available: helpers.BoolPtr(true), Price: &productPrice, Version: helpers.IntPtr(1)}.Build()
+	pr := postgresql.NewProductsRepository(suite.db)
+	require.ErrorIs(t, pr.UpdateStock(context.Background(), p), app.ErrVersionConflict)
+
+	found, err := pr.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, found.IsAvailable())
+	require.Equal(t, version, found.Version())
+}
+
+func (suite *PostgreSQLTestSuite) TestConcurrentPurchases() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		id     = uuid.New()
+		name   = "product33"
+		status = domain.StatusPublished
+		price  = "1.1"
+	)
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status) VALUES ($1, $2, $3, $4)",
+		id,
+		name,
+		price,
+		status,
+	)
+	require.NoError(t, err)
+
+	const buyers = 50
+	var (
+		ch = app.NewPurchaseProduct(
+			postgresql.NewProductsRepository(suite.db),
+			postgresql.NewOrdersRepository(suite.db),
+			postgresql.NewTransactor(suite.db),
+		)
+		wg   sync.WaitGroup
+		errs = make(chan error, buyers)
+	)
+	wg.Add(buyers)
+	for i := 0; i < buyers; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := ch.Handle(context.Background(), app.PurchaseProductCmd{ID: id})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, domain.ErrProductPurchased)
+	}
+	require.Equal(t, 1, succeeded)
+
+	var orders int
+	require.NoError(t, suite.db.QueryRow("SELECT count(*) FROM orders WHERE product_id=$1", id).Scan(&orders))
+	require.Equal(t, 1, orders)
+}
+
+func (suite *PostgreSQLTestSuite) TestConcurrentPurchasesWithStock() {
+	t := suite.T()
+
+	// Insert fixture data into DB
+	var (
+		id    = uuid.New()
+		stock = 5
+	)
+	_, err := suite.db.Exec(
+		"INSERT INTO products (id, name, price, status, stock) VALUES ($1, $2, $3, $4, $5)",
+		id,
+		"product77",
+		"1.1",
+		domain.StatusPublished,
+		stock,
+	)
+	require.NoError(t, err)
+
+	const buyers = 20
+	var (
+		pr = postgresql.NewProductsRepository(suite.db)
+		ch = app.NewPurchaseProduct(
+			pr,
+			postgresql.NewOrdersRepository(suite.db),
+			postgresql.NewTransactor(suite.db),
+		)
+		wg   sync.WaitGroup
+		errs = make(chan error, buyers)
+	)
+	wg.Add(buyers)
+	for i := 0; i < buyers; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := ch.Handle(context.Background(), app.PurchaseProductCmd{ID: id, Quantity: 1})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded, failed int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		failed++
+		require.ErrorIs(t, err, domain.ErrProductPurchased)
+	}
+
+	// The purchases are serialized, so the whole stock is sold and never oversold
+	found, err := pr.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, stock, succeeded)
+	require.Equal(t, buyers-stock, failed)
+	require.Zero(t, found.Stock())
+	require.False(t, found.IsAvailable())
+}
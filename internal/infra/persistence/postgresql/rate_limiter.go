@@ -0,0 +1,87 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+)
+
+// rateLimitPruneInterval is how often the full buckets are deleted
+const rateLimitPruneInterval = time.Minute
+
+// RateLimiter implements the app.RateLimiter interface. The buckets are shared by all the replicas of the service.
+// The buckets that are full again are deleted, since they're the same as a new one.
+type RateLimiter struct {
+	db *sql.DB
+	tx Transactor
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// NewRateLimiter is a constructor
+func NewRateLimiter(db *sql.DB) *RateLimiter {
+	return &RateLimiter{db: db, tx: NewTransactor(db)}
+}
+
+// Take implements the app.RateLimiter interface. The bucket is locked while the token is taken,
+// so the concurrent requests of a client are serialized. The given time is only used to know when to prune:
+// the bucket is refilled with the clock of the DB, so the replicas whose clocks drift share the same time.
+func (rl *RateLimiter) Take(ctx context.Context, key string, limit app.RateLimit, now time.Time) (app.RateLimitDecision, error) {
+	rl.prune(ctx, now)
+
+	var decision app.RateLimitDecision
+	err := rl.tx.WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, rl.db)
+		if _, err := db.ExecContext(ctx,
+			"INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at) VALUES ($1, $2, clock_timestamp(), clock_timestamp()) ON CONFLICT (key) DO NOTHING",
+			key, float64(limit.Requests),
+		); err != nil {
+			return fmt.Errorf("insert rate limit bucket: %w", err)
+		}
+
+		var bucket app.TokenBucket
+		if err := db.QueryRowContext(ctx,
+			"SELECT tokens, updated_at FROM rate_limit_buckets WHERE key=$1 FOR UPDATE", key,
+		).Scan(&bucket.Tokens, &bucket.UpdatedAt); err != nil {
+			return fmt.Errorf("select rate limit bucket: %w", err)
+		}
+
+		// The clock is read once the bucket is locked, so the time of the requests of a client always moves forward
+		var dbNow time.Time
+		if err := db.QueryRowContext(ctx, "SELECT clock_timestamp()").Scan(&dbNow); err != nil {
+			return fmt.Errorf("read DB clock: %w", err)
+		}
+
+		bucket, decision = bucket.Take(limit, dbNow)
+		if _, err := db.ExecContext(ctx,
+			"UPDATE rate_limit_buckets SET tokens=$1, updated_at=$2, full_at=$3 WHERE key=$4",
+			bucket.Tokens, bucket.UpdatedAt, bucket.FullAt(limit), key,
+		); err != nil {
+			return fmt.Errorf("update rate limit bucket: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return app.RateLimitDecision{}, err
+	}
+	return decision, nil
+}
+
+// prune deletes the full buckets once each rateLimitPruneInterval at most. It's best effort,
+// so an error only delays the deletion until the next time
+func (rl *RateLimiter) prune(ctx context.Context, now time.Time) {
+	rl.mu.Lock()
+	if now.Sub(rl.prunedAt) < rateLimitPruneInterval {
+		rl.mu.Unlock()
+		return
+	}
+	rl.prunedAt = now
+	rl.mu.Unlock()
+
+	_, _ = rl.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE full_at <= clock_timestamp()")
+}
@@ -0,0 +1,55 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestRateLimiter() {
+	t := suite.T()
+
+	var (
+		ctx   = context.Background()
+		rl    = postgresql.NewRateLimiter(suite.db)
+		key   = "mutation:ip:" + uuid.New().String()
+		limit = app.RateLimit{Requests: 2, Per: 2 * time.Second}
+		now   = time.Now()
+	)
+	for i := 0; i < limit.Requests; i++ {
+		decision, err := rl.Take(ctx, key, limit, now)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+		require.Equal(t, limit.Requests-1-i, decision.Remaining)
+	}
+
+	decision, err := rl.Take(ctx, key, limit, now)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.InDelta(t, time.Second, decision.RetryAfter, float64(200*time.Millisecond))
+
+	// The clock of the DB is used, so a replica whose clock is ahead doesn't refill the bucket
+	decision, err = postgresql.NewRateLimiter(suite.db).Take(ctx, key, limit, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+
+	// Another replica shares the bucket
+	time.Sleep(decision.RetryAfter)
+	decision, err = postgresql.NewRateLimiter(suite.db).Take(ctx, key, limit, time.Now())
+	require.NoError(t, err)
+	require.True(t, decision.Allowed)
+	require.Equal(t, 0, decision.Remaining)
+
+	// Another client has its own bucket
+	decision, err = rl.Take(ctx, "mutation:ip:"+uuid.New().String(), limit, now)
+	require.NoError(t, err)
+	require.True(t, decision.Allowed)
+}
@@ -0,0 +1,73 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+	"time"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestReservations() {
+	t := suite.T()
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": postgresql.NewEventSourcedProductsRepository(suite.db),
+	}
+	for name, pr := range repositories {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+
+		expired := fixtures.NewProduct("expired hold", "3.3", 1)
+		require.NoError(t, expired.Reserve(*fixtures.Reservation("buyer", now.Add(-time.Minute))), name)
+		held := fixtures.NewProduct("held", "3.3", 1)
+		require.NoError(t, held.Reserve(*fixtures.Reservation("buyer", now.Add(time.Hour))), name)
+		for _, p := range []domain.Product{expired, held} {
+			require.NoError(t, pr.Insert(context.Background(), p), name)
+		}
+
+		found, err := pr.FindByID(context.Background(), held.ID())
+		require.NoError(t, err, name)
+		require.Equal(t, domain.StatusReserved, found.Status(), name)
+		r, ok := found.Reservation()
+		require.True(t, ok, name)
+		require.True(t, r.IsHeldBy("buyer"), name)
+		require.True(t, now.Add(time.Hour).Equal(r.ExpiresAt()), name)
+
+		products, err := pr.FindExpiredReservations(context.Background(), now, 100)
+		require.NoError(t, err, name)
+		require.Contains(t, productIDs(products), expired.ID(), name)
+		require.NotContains(t, productIDs(products), held.ID(), name)
+
+		found, err = pr.FindByID(context.Background(), expired.ID())
+		require.NoError(t, err, name)
+		require.NoError(t, found.ExpireReservation(now), name)
+		require.NoError(t, pr.Update(context.Background(), found), name)
+
+		found, err = pr.FindByID(context.Background(), expired.ID())
+		require.NoError(t, err, name)
+		require.Equal(t, domain.StatusPublished, found.Status(), name)
+		_, ok = found.Reservation()
+		require.False(t, ok, name)
+
+		products, err = pr.FindExpiredReservations(context.Background(), now, 100)
+		require.NoError(t, err, name)
+		require.NotContains(t, productIDs(products), expired.ID(), name)
+	}
+}
+
+func productIDs(products []domain.Product) []uuid.UUID {
+	IDs := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		IDs[i] = p.ID()
+	}
+	return IDs
+}
@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// searchQuery ranks the products whose search column matches the text. The text can use the web search syntax.
+// The whole name is highlighted, since it's short. It's HTML-escaped before, so the snippet is safe HTML:
+// the escaped characters are parsed as entities, which are copied as they are, and the matching words are still highlighted.
+const searchQuery = "SELECT id, ts_rank(search, query), " +
+	"ts_headline('english', " + escapedName + ", query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') " +
+	"FROM products, websearch_to_tsquery('english', $1) query WHERE search @@ query " +
+	"ORDER BY 2 DESC, id OFFSET $2 LIMIT $3"
+
+// escapedName is the name of the product with the characters that are special in HTML escaped. The ampersand goes first,
+// so the entities of the other ones are not escaped again
+const escapedName = `replace(replace(replace(replace(name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`
+
+type productFinder func(ctx context.Context, filter app.ProductFilter, order app.ProductOrder) ([]domain.Product, error)
+
+// searchProducts finds the products that match the search in the products table, and then loads them with the finder.
+// It's shared by the repositories because the products table is the projection of the event-sourced one.
+func searchProducts(ctx context.Context, db *sql.DB, req app.ProductsSearchRequest, find productFinder) ([]app.ProductSearchHit, error) {
+	rows, err := conn(ctx, db).QueryContext(ctx, searchQuery, req.Text, req.Offset, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		ids  []uuid.UUID
+		hits []app.ProductSearchHit
+	)
+	for rows.Next() {
+		var (
+			ID  uuid.UUID
+			hit app.ProductSearchHit
+		)
+		if err := rows.Scan(&ID, &hit.Rank, &hit.Snippet); err != nil {
+			return nil, err
+		}
+		ids = append(ids, ID)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	products, err := find(ctx, app.ProductFilter{IDs: ids}, app.ProductOrder{})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]domain.Product, len(products))
+	for _, p := range products {
+		byID[p.ID()] = p
+	}
+
+	// The hits keep the order of the ranking. A product removed since it was found is skipped.
+	found := hits[:0]
+	for i, hit := range hits {
+		p, ok := byID[ids[i]]
+		if !ok {
+			continue
+		}
+		hit.Product = p
+		found = append(found, hit)
+	}
+	return found, nil
+}
@@ -0,0 +1,72 @@
+//go:build test_db
+// +build test_db
+
+package postgresql_test
+
+import (
+	"context"
+
+	"theskyinflames/graphql-challenge/internal/app"
+	"theskyinflames/graphql-challenge/internal/domain"
+	"theskyinflames/graphql-challenge/internal/fixtures"
+	"theskyinflames/graphql-challenge/internal/infra/persistence/postgresql"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *PostgreSQLTestSuite) TestSearch() {
+	t := suite.T()
+
+	// The products are inserted through the event-sourced repository, so they're in both stores.
+	// The words of their names are not in the names of other products.
+	var (
+		es     = postgresql.NewEventSourcedProductsRepository(suite.db)
+		boots  = fixtures.NewProduct("Waterproof hiking boots", "90", 1)
+		hiking = fixtures.NewProduct("Hiking socks for hiking boots", "9", 1)
+		tent   = fixtures.NewProduct("Camping tent", "200", 1)
+		// The name is stored as it was given, so it can have HTML
+		lantern = fixtures.NewProduct(`<img src=x onerror=alert(1)> Lantern & "stove"`, "20", 1)
+	)
+	for _, p := range []domain.Product{boots, hiking, tent, lantern} {
+		require.NoError(t, es.Insert(context.Background(), p))
+	}
+
+	repositories := map[string]app.ProductsRepository{
+		"state":  postgresql.NewProductsRepository(suite.db),
+		"events": es,
+	}
+	for name, pr := range repositories {
+		// The words are stemmed, so "hike" matches "hiking"
+		hits, err := pr.Search(context.Background(), app.ProductsSearchRequest{Text: "hike boot", Limit: 10})
+		require.NoError(t, err, name)
+		require.Len(t, hits, 2, name)
+		require.ElementsMatch(t, []uuid.UUID{boots.ID(), hiking.ID()}, []uuid.UUID{hits[0].Product.ID(), hits[1].Product.ID()}, name)
+		require.True(t, hits[0].Rank >= hits[1].Rank, name)
+		for _, hit := range hits {
+			if hit.Product.ID() == boots.ID() {
+				require.Equal(t, "Waterproof <mark>hiking</mark> <mark>boots</mark>", hit.Snippet, name)
+			}
+		}
+
+		next, err := pr.Search(context.Background(), app.ProductsSearchRequest{Text: "hike boot", Offset: 1, Limit: 10})
+		require.NoError(t, err, name)
+		require.Len(t, next, 1, name)
+		require.Equal(t, hits[1].Product.ID(), next[0].Product.ID(), name)
+
+		hits, err = pr.Search(context.Background(), app.ProductsSearchRequest{Text: "boots -socks", Limit: 10})
+		require.NoError(t, err, name)
+		require.Len(t, hits, 1, name)
+		require.Equal(t, boots.ID(), hits[0].Product.ID(), name)
+
+		// The snippet is HTML-escaped, except for the highlights
+		hits, err = pr.Search(context.Background(), app.ProductsSearchRequest{Text: "lantern", Limit: 10})
+		require.NoError(t, err, name)
+		require.Len(t, hits, 1, name)
+		require.Equal(t, `&lt;img src=x onerror=alert(1)&gt; <mark>Lantern</mark> &amp; &quot;stove&quot;`, hits[0].Snippet, name)
+
+		hits, err = pr.Search(context.Background(), app.ProductsSearchRequest{Text: "unmatchable", Limit: 10})
+		require.NoError(t, err, name)
+		require.Empty(t, hits, name)
+	}
+}